@@ -3,43 +3,89 @@ package droneweather
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"log"
+	"net/http"
 	"os"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"agent-stack/internal/models"
 	"agent-stack/shared/config"
 	"agent-stack/shared/email"
+	"agent-stack/shared/httpclient"
+	"agent-stack/shared/monitoring"
 	"agent-stack/shared/scheduler"
+	"agent-stack/shared/storage"
 )
 
+// notifyStateFile holds the last flyable notification time for the cooldown
+const notifyStateFile = "drone_notify_state.json"
+
+// noFlyStreakFile holds the count of consecutive no-fly checks for the heads-up email
+const noFlyStreakFile = "drone_nofly_streak.json"
+
+// HeadsUpSubject is the subject of the email sent after no_fly_heads_up_runs no-fly checks
+const HeadsUpSubject = "Still No Good Drone Flying Days in %s"
+
+// httpDebugDir is where raw weather and TFR responses are saved when debug_http is enabled
+const httpDebugDir = "http_debug"
+
+// DefaultSubject is the subject template used for flight reports when none is configured
+const DefaultSubject = `{{if eq .Tier "caution"}}Marginal Day for Drone Flying in {{.LocationName}} - Fly with Caution{{else}}Good Day for Drone Flying in {{.LocationName}}{{end}}`
+
 // DroneMetrics represents the metrics collected during a drone weather check
 type DroneMetrics struct {
 	WeatherFetched bool `json:"weather_fetched"`
 	TFRsChecked    bool `json:"tfrs_checked"`
 	IsFlyable      bool `json:"is_flyable"`
 	EmailSent      bool `json:"email_sent"`
+	// CooldownActive means a flyable email was suppressed by notify_cooldown_hours
+	CooldownActive bool `json:"cooldown_active"`
+	// HeadsUpSent means this no-fly check reached no_fly_heads_up_runs and the
+	// informational heads-up email went out
+	HeadsUpSent bool `json:"heads_up_sent"`
 }
 
 // GetSummary implements the scheduler.Metrics interface
 func (m DroneMetrics) GetSummary() string {
 	if m.IsFlyable && m.EmailSent {
 		return "good weather conditions detected, email sent with TFR info"
+	} else if m.IsFlyable && m.CooldownActive {
+		return "good weather conditions detected, email suppressed by notify cooldown"
 	} else if m.IsFlyable {
 		return "good weather conditions detected, no email sent"
+	} else if m.HeadsUpSent {
+		return "poor weather conditions, no-fly streak heads-up email sent"
 	} else {
 		return "poor weather conditions, no email sent"
 	}
 }
 
+// weatherSource fetches and analyzes weather conditions; implemented by WeatherClient
+// and by failoverWeather when a fallback provider is configured
+type weatherSource interface {
+	WeatherProvider
+	AnalyzeWeatherConditions(data *models.WeatherData) *models.WeatherAnalysis
+}
+
+// tfrSource checks airspace restrictions; implemented by TFRClient
+type tfrSource interface {
+	CheckTFRs(ctx context.Context, lat, lon float64) (*models.TFRCheck, error)
+}
+
 // DroneWeatherAgent implements the scheduler.Agent interface
 type DroneWeatherAgent struct {
 	config        *config.Config
-	weatherClient *WeatherClient
-	tfrClient     *TFRClient
-	emailSender   *email.Sender
+	weatherClient weatherSource
+	tfrClient     tfrSource
+	emailSender   email.EmailSender
+	emailObserver email.SendObserver   // set once by the scheduler, before Initialize
+	notifyStore   *storage.NotifyStore // nil when notify_cooldown_hours is disabled
+	noFlyStreak   *storage.StreakStore // nil when no_fly_heads_up_runs is disabled
 }
 
 func NewDroneWeatherAgent(cfg *config.Config) *DroneWeatherAgent {
@@ -48,6 +94,14 @@ func NewDroneWeatherAgent(cfg *config.Config) *DroneWeatherAgent {
 	}
 }
 
+// ObserveEmail implements scheduler.EmailObserver for the SMTP sender
+func (d *DroneWeatherAgent) ObserveEmail(observer func(latency time.Duration, err error)) {
+	d.emailObserver = observer
+	if sender, ok := d.emailSender.(*email.Sender); ok {
+		sender.SetObserver(observer)
+	}
+}
+
 func (d *DroneWeatherAgent) Name() string {
 	return "Drone Weather Agent"
 }
@@ -60,20 +114,51 @@ func (d *DroneWeatherAgent) Initialize() error {
 	log.Printf("Initializing %s...", d.Name())
 
 	if d.weatherClient == nil {
-		d.weatherClient = NewWeatherClient(&d.config.DroneWeather)
-		log.Println("Weather client initialized")
+		weatherClient, err := d.newWeatherSource()
+		if err != nil {
+			return fmt.Errorf("failed to initialize weather client: %w", err)
+		}
+		d.weatherClient = weatherClient
+		if d.config.DroneWeather.FallbackWeatherProvider != "" {
+			log.Printf("Weather client initialized (%s, falling back to %s)",
+				d.config.DroneWeather.WeatherProvider, d.config.DroneWeather.FallbackWeatherProvider)
+		} else {
+			log.Println("Weather client initialized")
+		}
 	}
 
 	if d.tfrClient == nil {
-		d.tfrClient = NewTFRClient(&d.config.DroneWeather)
+		tfrClient := NewTFRClient(&d.config.DroneWeather)
+		tfrClient.client = d.debugHTTP(tfrClient.client)
+		d.tfrClient = tfrClient
 		log.Println("TFR client initialized")
 	}
 
 	if d.emailSender == nil {
-		d.emailSender = email.NewSender(&d.config.Email)
+		sender := email.NewSender(&d.config.Email)
+		sender.SetObserver(d.emailObserver)
+		d.emailSender = sender
 		log.Println("Email sender initialized")
 	}
 
+	if d.notifyStore == nil && d.config.DroneWeather.NotifyCooldownHours > 0 {
+		store, err := storage.NewNotifyStore(d.config.DataDir, notifyStateFile)
+		if err != nil {
+			return fmt.Errorf("failed to initialize notification store: %w", err)
+		}
+		d.notifyStore = store
+		log.Printf("Notification cooldown enabled (%d hours)", d.config.DroneWeather.NotifyCooldownHours)
+	}
+
+	if d.noFlyStreak == nil && d.config.DroneWeather.NoFlyHeadsUpRuns > 0 {
+		store, err := storage.NewStreakStore(d.config.DataDir, noFlyStreakFile)
+		if err != nil {
+			return fmt.Errorf("failed to initialize no-fly streak store: %w", err)
+		}
+		d.noFlyStreak = store
+		log.Printf("No-fly heads-up enabled after %d consecutive no-fly checks", d.config.DroneWeather.NoFlyHeadsUpRuns)
+	}
+
 	// Validate required configuration
 	if d.config.DroneWeather.HomeLatitude == 0 || d.config.DroneWeather.HomeLongitude == 0 {
 		return fmt.Errorf("home coordinates must be configured (home_latitude and home_longitude)")
@@ -95,69 +180,94 @@ func (d *DroneWeatherAgent) RunOnce(ctx context.Context, events *scheduler.Agent
 	startTime := time.Now()
 	metrics := DroneMetrics{}
 
-	// Fetch weather data
-	log.Println("Fetching weather data...")
-	weatherData, err := d.weatherClient.GetCurrentWeather(ctx,
-		d.config.DroneWeather.HomeLatitude,
-		d.config.DroneWeather.HomeLongitude)
-	if err != nil {
+	// Fetch weather data and check TFRs concurrently - they're independent
+	var (
+		wg          sync.WaitGroup
+		weatherData *models.WeatherData
+		weatherErr  error
+		tfrCheck    *models.TFRCheck
+		tfrErr      error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		log.Println("Fetching weather data...")
+		weatherData, weatherErr = d.weatherClient.GetCurrentWeather(ctx,
+			d.config.DroneWeather.HomeLatitude,
+			d.config.DroneWeather.HomeLongitude)
+	}()
+	go func() {
+		defer wg.Done()
+		log.Println("Checking TFRs...")
+		tfrCheck, tfrErr = d.tfrClient.CheckTFRs(ctx,
+			d.config.DroneWeather.HomeLatitude,
+			d.config.DroneWeather.HomeLongitude)
+	}()
+	wg.Wait()
+
+	if weatherErr != nil {
 		if events != nil && events.OnCriticalFailure != nil {
-			events.OnCriticalFailure(fmt.Errorf("failed to fetch weather data: %w", err), time.Since(startTime))
+			events.OnCriticalFailure(fmt.Errorf("failed to fetch weather data: %w", weatherErr), time.Since(startTime))
 		}
-		return fmt.Errorf("failed to fetch weather data: %w", err)
+		return fmt.Errorf("failed to fetch weather data: %w", weatherErr)
 	}
 	metrics.WeatherFetched = true
 
-	// Analyze weather conditions
-	weatherAnalysis := d.weatherClient.AnalyzeWeatherConditions(weatherData)
-	log.Printf("Weather analysis: flyable=%t, temp=%.1f°C, wind=%.1f km/h, visibility=%.1f km, time=%s",
-		weatherAnalysis.IsFlyable, weatherData.Temperature, weatherData.WindSpeed,
-		weatherData.Visibility, weatherData.Time.Format("15:04 MST"))
-
-	// Check TFRs
-	log.Println("Checking TFRs...")
-	tfrCheck, err := d.tfrClient.CheckTFRs(ctx,
-		d.config.DroneWeather.HomeLatitude,
-		d.config.DroneWeather.HomeLongitude)
-	if err != nil {
+	if tfrErr != nil {
 		// TFR check failure is not critical - we can still make decisions based on weather
 		if events != nil && events.OnPartialFailure != nil {
-			events.OnPartialFailure(fmt.Errorf("failed to check TFRs: %w", err), time.Since(startTime))
-		}
-		log.Printf("Warning: Failed to check TFRs: %v", err)
-
-		// Create a default TFR check when API fails
-		tfrCheck = &models.TFRCheck{
-			HasActiveTFRs: true, // Mark as having TFRs when check fails (informational warning)
-			ActiveTFRs:    []*models.TFR{},
-			CheckRadius:   d.config.DroneWeather.SearchRadiusMiles,
-			CheckTime:     time.Now(),
-			Summary:       "TFR check failed - verify airspace restrictions manually before flying",
+			events.OnPartialFailure(fmt.Errorf("failed to check TFRs: %w", tfrErr), time.Since(startTime))
 		}
+		log.Printf("Warning: Failed to check TFRs: %v", tfrErr)
+		tfrCheck = nil
 	} else {
 		metrics.TFRsChecked = true
+
+		// Keep the last successful check available at /tfrs between runs
+		if events != nil && events.OnSnapshot != nil {
+			events.OnSnapshot(monitoring.SnapshotTFRs, tfrCheck)
+		}
 	}
 
-	log.Printf("TFR check: %s", tfrCheck.Summary)
+	report := d.Evaluate(weatherData, tfrCheck)
+	report.Debug = d.debugReport(weatherData)
+	metrics.IsFlyable = report.IsFlyable
+	log.Printf("Weather analysis: flyable=%t, temp=%.1f°C (feels %.1f°C), wind=%.1f km/h, visibility=%.1f km, time=%s",
+		report.WeatherAnalysis.IsFlyable, weatherData.Temperature, weatherData.ApparentTemperature, weatherData.WindSpeed,
+		weatherData.Visibility, weatherData.Time.Format("15:04 MST"))
+	log.Printf("TFR check: %s", report.TFRCheck.Summary)
+
+	if events != nil && events.OnLastCheck != nil {
+		events.OnLastCheck(&models.DroneCheckSnapshot{
+			CheckedAt:       report.Date,
+			IsFlyable:       report.IsFlyable,
+			Tier:            report.Tier,
+			Reasons:         report.Reasons,
+			WeatherAnalysis: report.WeatherAnalysis,
+			TFRCheck:        report.TFRCheck,
+		})
+	}
 
-	// Determine if flying conditions are good based on weather only
-	// TFRs are informational - pilots can still fly outside restricted areas
-	isFlyable := weatherAnalysis.IsFlyable
-	metrics.IsFlyable = isFlyable
+	// A flyable check ends any no-fly streak
+	if report.IsFlyable && d.noFlyStreak != nil {
+		if err := d.noFlyStreak.Reset(); err != nil {
+			log.Printf("Warning: Failed to reset no-fly streak: %v", err)
+		}
+	}
+	if report.IsFlyable && d.notifyStore != nil {
+		if err := d.notifyStore.ClearUnsuitable(); err != nil {
+			log.Printf("Warning: Failed to clear no-fly spell: %v", err)
+		}
+	}
 
 	// Send email if weather conditions are good (TFRs are shown as informational)
-	if isFlyable {
+	if report.IsFlyable && d.inCooldown() {
+		metrics.CooldownActive = true
+		log.Printf("Conditions are good for flying but a notification was sent at %s - skipping email (notify_cooldown_hours=%d)",
+			d.notifyStore.LastNotified().Format("Jan 2 15:04"), d.config.DroneWeather.NotifyCooldownHours)
+	} else if report.IsFlyable {
 		log.Println("Conditions are good for flying - sending email notification...")
 
-		report := &models.DroneFlightReport{
-			Date:            time.Now(),
-			LocationName:    d.config.DroneWeather.HomeName,
-			WeatherAnalysis: weatherAnalysis,
-			TFRCheck:        tfrCheck,
-			IsFlyable:       true,
-			Summary:         "Excellent conditions for drone flying!",
-		}
-
 		body, err := d.generateEmailBody(report)
 		if err != nil {
 			if events != nil && events.OnCriticalFailure != nil {
@@ -166,7 +276,14 @@ func (d *DroneWeatherAgent) RunOnce(ctx context.Context, events *scheduler.Agent
 			return fmt.Errorf("failed to generate email body: %w", err)
 		}
 
-		subject := fmt.Sprintf("Good Day for Drone Flying in %s", report.LocationName)
+		subject, err := email.RenderSubject(d.config.DroneWeather.SubjectTemplate, DefaultSubject, report)
+		if err != nil {
+			if events != nil && events.OnCriticalFailure != nil {
+				events.OnCriticalFailure(fmt.Errorf("failed to render email subject: %w", err), time.Since(startTime))
+			}
+			return fmt.Errorf("failed to render email subject: %w", err)
+		}
+
 		if err := d.emailSender.SendHTML(subject, body); err != nil {
 			if events != nil && events.OnCriticalFailure != nil {
 				events.OnCriticalFailure(fmt.Errorf("failed to send email report: %w", err), time.Since(startTime))
@@ -174,12 +291,33 @@ func (d *DroneWeatherAgent) RunOnce(ctx context.Context, events *scheduler.Agent
 			return fmt.Errorf("failed to send email report: %w", err)
 		}
 		metrics.EmailSent = true
+
+		if d.notifyStore != nil {
+			if err := d.notifyStore.RecordNotified(time.Now()); err != nil {
+				log.Printf("Warning: Failed to record notification time: %v", err)
+			}
+		}
 	} else {
 		log.Println("Conditions not suitable for flying - no email sent")
 
-		// Log reasons why not flyable (weather only)
-		for _, reason := range weatherAnalysis.Reasons {
-			log.Printf("Weather issue: %s", reason)
+		// A no-fly check ends the cooldown so the next good spell is announced
+		if d.notifyStore != nil {
+			if err := d.endCooldownAfterNoFly(); err != nil {
+				log.Printf("Warning: Failed to reset notification cooldown: %v", err)
+			}
+		}
+
+		// Log reasons why not flyable
+		for _, reason := range report.Reasons {
+			log.Printf("Flying issue: %s", reason)
+		}
+
+		if err := d.trackNoFlyStreak(report, &metrics); err != nil {
+			// The heads-up is informational, so failing to send it is not critical
+			if events != nil && events.OnPartialFailure != nil {
+				events.OnPartialFailure(err, time.Since(startTime))
+			}
+			log.Printf("Warning: %v", err)
 		}
 	}
 
@@ -194,10 +332,174 @@ func (d *DroneWeatherAgent) RunOnce(ctx context.Context, events *scheduler.Agent
 	return nil
 }
 
+// debugHTTP wraps client to log requests and capture raw responses under the data
+// dir when debug_http is enabled
+func (d *DroneWeatherAgent) debugHTTP(client *http.Client) *http.Client {
+	if !d.config.DroneWeather.DebugHTTP {
+		return client
+	}
+	dir := filepath.Join(d.config.DataDir, httpDebugDir)
+	log.Printf("HTTP debug enabled, saving raw responses to %s", dir)
+	return httpclient.WithDebugCapture(client, dir)
+}
+
+// debugReport captures the raw and parsed weather data for the email footer when
+// debug_report is enabled, or returns nil
+func (d *DroneWeatherAgent) debugReport(weather *models.WeatherData) *models.DroneReportDebug {
+	if !d.config.DroneWeather.DebugReport {
+		return nil
+	}
+
+	debug := &models.DroneReportDebug{RawResponse: string(weather.RawResponse)}
+	var raw bytes.Buffer
+	if err := json.Indent(&raw, weather.RawResponse, "", "  "); err == nil {
+		debug.RawResponse = raw.String()
+	}
+	if parsed, err := json.MarshalIndent(weather, "", "  "); err != nil {
+		log.Printf("Warning: Failed to encode weather data for debug report: %v", err)
+	} else {
+		debug.ParsedWeather = string(parsed)
+	}
+	return debug
+}
+
+// trackNoFlyStreak counts a no-fly check and sends the heads-up email when the streak
+// reaches no_fly_heads_up_runs. It fires once per streak; longer streaks stay quiet
+// until a flyable check resets the count.
+func (d *DroneWeatherAgent) trackNoFlyStreak(report *models.DroneFlightReport, metrics *DroneMetrics) error {
+	if d.noFlyStreak == nil {
+		return nil
+	}
+
+	runs, err := d.noFlyStreak.Increment(report.Date)
+	if err != nil {
+		return fmt.Errorf("failed to record no-fly streak: %w", err)
+	}
+	if runs != d.config.DroneWeather.NoFlyHeadsUpRuns {
+		return nil
+	}
+
+	log.Printf("%d consecutive no-fly checks - sending heads-up email", runs)
+	body, err := renderTemplate(headsUpTemplatePath, &models.NoFlyStreak{
+		LocationName: report.LocationName,
+		Runs:         runs,
+		Since:        d.noFlyStreak.Since(),
+		Date:         report.Date,
+		Reasons:      report.Reasons,
+
+		NextFlyableDays: report.WeatherAnalysis.NextFlyableDays,
+		OutlookDays:     report.WeatherAnalysis.OutlookDays,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate heads-up email body: %w", err)
+	}
+	if err := d.emailSender.SendHTML(fmt.Sprintf(HeadsUpSubject, report.LocationName), body); err != nil {
+		return fmt.Errorf("failed to send heads-up email: %w", err)
+	}
+	metrics.HeadsUpSent = true
+	return nil
+}
+
+// inCooldown reports whether a flyable notification was sent within notify_cooldown_hours
+func (d *DroneWeatherAgent) inCooldown() bool {
+	if d.notifyStore == nil {
+		return false
+	}
+	lastNotified := d.notifyStore.LastNotified()
+	cooldown := time.Duration(d.config.DroneWeather.NotifyCooldownHours) * time.Hour
+	return !lastNotified.IsZero() && time.Since(lastNotified) < cooldown
+}
+
+// endCooldownAfterNoFly records a no-fly check and resets the cooldown once
+// notify_reset_no_fly_runs checks in a row have been not flyable
+func (d *DroneWeatherAgent) endCooldownAfterNoFly() error {
+	runs, err := d.notifyStore.MarkUnsuitable()
+	if err != nil {
+		return err
+	}
+	if runs < d.config.DroneWeather.NotifyResetNoFlyRuns {
+		return nil
+	}
+	return d.notifyStore.Reset()
+}
+
+// Evaluate decides whether conditions are flyable from already-fetched weather and
+// TFR data, without sending email or logging. A nil tfr means the TFR check failed.
+func (d *DroneWeatherAgent) Evaluate(weather *models.WeatherData, tfr *models.TFRCheck) *models.DroneFlightReport {
+	analyzer := d.weatherClient
+	if analyzer == nil {
+		analyzer = NewWeatherClient(&d.config.DroneWeather)
+	}
+	weatherAnalysis := analyzer.AnalyzeWeatherConditions(weather)
+
+	// Flyability is based on weather only by default; TFRs are informational
+	// since pilots can still fly outside restricted areas
+	isFlyable := weatherAnalysis.IsFlyable
+	reasons := append([]string{}, weatherAnalysis.Reasons...)
+
+	if tfr == nil {
+		radius, unit := searchRadius(&d.config.DroneWeather)
+		tfr = &models.TFRCheck{
+			HasActiveTFRs: true, // Mark as having TFRs when check fails (informational warning)
+			ActiveTFRs:    []*models.TFR{},
+			CheckRadius:   radius,
+			RadiusUnit:    unit,
+			CheckTime:     time.Now(),
+			Summary:       "TFR check failed - verify airspace restrictions manually before flying",
+		}
+
+		// Unless configured to refuse flying when airspace status is unknown
+		if d.config.DroneWeather.RequireTFRSuccess {
+			isFlyable = false
+			reasons = append(reasons, "TFR check failed and require_tfr_success is enabled - airspace status unknown")
+		}
+	} else if d.config.DroneWeather.TFRBlocksFlight && tfr.HasActiveTFRs {
+		// Users in dense airspace can opt into treating any nearby TFR as a no-go
+		isFlyable = false
+		_, unit := searchRadius(&d.config.DroneWeather)
+		reasons = append(reasons, fmt.Sprintf("%d active TFR(s) within %d %s and tfr_blocks_flight is enabled",
+			len(tfr.ActiveTFRs), tfr.CheckRadius, unit))
+	}
+
+	tier := weatherAnalysis.Tier
+	if !isFlyable {
+		tier = models.TierNoGo
+	}
+
+	summary := "Excellent conditions for drone flying!"
+	switch tier {
+	case models.TierCaution:
+		summary = "Flyable with caution - some conditions are marginal"
+	case models.TierNoGo:
+		summary = "Conditions not suitable for drone flying"
+	}
+
+	return &models.DroneFlightReport{
+		Date:            time.Now(),
+		LocationName:    d.config.DroneWeather.HomeName,
+		WeatherAnalysis: weatherAnalysis,
+		TFRCheck:        tfr,
+		IsFlyable:       isFlyable,
+		Tier:            tier,
+		Summary:         summary,
+		Reasons:         reasons,
+	}
+}
+
+// Email templates, resolved relative to the repository root
+const (
+	emailTemplatePath   = "agents/drone-weather/email_template.html"
+	headsUpTemplatePath = "agents/drone-weather/heads_up_template.html"
+)
+
 // generateEmailBody creates HTML email content for drone weather report
 func (d *DroneWeatherAgent) generateEmailBody(report *models.DroneFlightReport) (string, error) {
+	return renderTemplate(emailTemplatePath, report)
+}
+
+// renderTemplate executes the HTML template at templatePath against data
+func renderTemplate(templatePath string, data interface{}) (string, error) {
 	// Read template from external file
-	templatePath := "agents/drone-weather/email_template.html"
 	tmplBytes, err := os.ReadFile(templatePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read email template: %w", err)
@@ -209,7 +511,7 @@ func (d *DroneWeatherAgent) generateEmailBody(report *models.DroneFlightReport)
 	}
 
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, report); err != nil {
+	if err := tmpl.Execute(&buf, data); err != nil {
 		return "", fmt.Errorf("failed to execute email template: %w", err)
 	}
 
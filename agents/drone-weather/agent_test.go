@@ -1,11 +1,23 @@
 package droneweather
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"agent-stack/internal/models"
 	"agent-stack/shared/config"
+	"agent-stack/shared/email"
+	"agent-stack/shared/monitoring"
+	"agent-stack/shared/scheduler"
 )
 
 func TestDroneMetricsGetSummary(t *testing.T) {
@@ -200,3 +212,851 @@ func TestGenerateEmailBodyMethod(t *testing.T) {
 		t.Logf("Expected error due to template file: %v", err)
 	}
 }
+
+// newTestServers starts fake Open-Meteo and FAA TFR servers returning the given current wind speed
+func newTestServers(t *testing.T, windSpeedKmh float64) (weatherURL, tfrURL string) {
+	t.Helper()
+
+	weather := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{
+			"latitude": 40.0,
+			"longitude": -74.0,
+			"timezone": "America/New_York",
+			"current": {"time": "2025-06-01T09:00", "temperature_2m": 20.0, "wind_speed_10m": %.1f, "wind_direction_10m": 180, "visibility": 10000, "precipitation": 0},
+			"hourly": {"time": ["2025-06-01T09:00", "2025-06-01T10:00"], "wind_speed_10m": [10, 12], "wind_gusts_10m": [15, 18]}
+		}`, windSpeedKmh)
+	}))
+	t.Cleanup(weather.Close)
+
+	tfr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"type": "FeatureCollection", "features": []}`))
+	}))
+	t.Cleanup(tfr.Close)
+
+	return weather.URL, tfr.URL
+}
+
+func TestLastCheckEndpointAfterRun(t *testing.T) {
+	weatherURL, tfrURL := newTestServers(t, 40.0) // too windy, so no email is attempted
+
+	cfg := &config.Config{
+		DroneWeather: config.DroneWeatherConfig{
+			HomeLatitude:      40.0,
+			HomeLongitude:     -74.0,
+			HomeName:          "Test Location",
+			SearchRadiusMiles: 25,
+			MaxWindSpeedKmh:   25,
+			MinVisibilityKm:   5,
+			MinTempC:          4.4,
+			MaxTempC:          35.0,
+			WeatherURL:        weatherURL,
+			TFRURL:            tfrURL,
+		},
+	}
+	agent := NewDroneWeatherAgent(cfg)
+	if err := agent.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	monitor := monitoring.NewMonitor()
+	events := &scheduler.AgentEvents{OnLastCheck: monitor.RecordLastCheck}
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+
+	server := monitoring.NewHealthServer(monitor, "127.0.0.1", "0")
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start health server: %v", err)
+	}
+	defer server.Shutdown(context.Background())
+
+	resp, err := http.Get("http://" + server.Addr() + "/last-check")
+	if err != nil {
+		t.Fatalf("Failed to query /last-check: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 from /last-check, got %d", resp.StatusCode)
+	}
+
+	var snapshot models.DroneCheckSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+
+	if snapshot.IsFlyable {
+		t.Error("Expected snapshot to record not-flyable decision")
+	}
+	if len(snapshot.Reasons) != 1 || !strings.Contains(snapshot.Reasons[0], "Wind speed too high") {
+		t.Errorf("Expected wind reason in snapshot, got %v", snapshot.Reasons)
+	}
+	if snapshot.WeatherAnalysis == nil || snapshot.WeatherAnalysis.Data.WindSpeed != 40.0 {
+		t.Errorf("Expected weather data in snapshot, got %+v", snapshot.WeatherAnalysis)
+	}
+	if snapshot.TFRCheck == nil || snapshot.TFRCheck.HasActiveTFRs {
+		t.Errorf("Expected clear TFR check in snapshot, got %+v", snapshot.TFRCheck)
+	}
+}
+
+// fakeSMTPServer accepts SMTP connections on localhost and captures received message bodies
+type fakeSMTPServer struct {
+	listener net.Listener
+	messages chan string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start fake SMTP server: %v", err)
+	}
+	s := &fakeSMTPServer{listener: listener, messages: make(chan string, 10)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "AUTH"):
+			reply("235 Authentication successful")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.messages <- data.String()
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestDroneAgentSendsThroughSharedSender(t *testing.T) {
+	// The email template is resolved relative to the repository root
+	t.Chdir("../..")
+
+	weatherURL, tfrURL := newTestServers(t, 10.0) // calm, so an email is sent
+	smtpServer := newFakeSMTPServer(t)
+
+	cfg := &config.Config{
+		DroneWeather: config.DroneWeatherConfig{
+			HomeLatitude:      40.0,
+			HomeLongitude:     -74.0,
+			HomeName:          "Test Location",
+			SearchRadiusMiles: 25,
+			MaxWindSpeedKmh:   25,
+			MinVisibilityKm:   5,
+			MinTempC:          4.4,
+			MaxTempC:          35.0,
+			WeatherURL:        weatherURL,
+			TFRURL:            tfrURL,
+		},
+		Email: config.EmailConfig{
+			SMTPServer: "127.0.0.1",
+			SMTPPort:   smtpServer.port(),
+			Username:   "user",
+			Password:   "pass",
+			FromEmail:  "from@test.com",
+			ToEmail:    "to@test.com",
+		},
+	}
+	agent := NewDroneWeatherAgent(cfg)
+	if err := agent.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	var metrics DroneMetrics
+	events := &scheduler.AgentEvents{
+		OnSuccess: func(m scheduler.Metrics, duration time.Duration) {
+			metrics = m.(DroneMetrics)
+		},
+	}
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+
+	if !metrics.EmailSent {
+		t.Error("Expected metrics to report email sent")
+	}
+
+	select {
+	case msg := <-smtpServer.messages:
+		if !strings.Contains(msg, "Subject: Good Day for Drone Flying in Test Location") {
+			t.Errorf("Expected drone subject in message, got:\n%s", msg)
+		}
+		if !strings.Contains(msg, "To: to@test.com") {
+			t.Errorf("Expected recipient header in message, got:\n%s", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for email")
+	}
+}
+
+func TestDroneSubjectTemplate(t *testing.T) {
+	report := &models.DroneFlightReport{
+		Date:         time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC),
+		LocationName: "Test Field",
+	}
+
+	tests := []struct {
+		name     string
+		tmpl     string
+		tier     models.FlightTier
+		expected string
+	}{
+		{"Default subject", "", models.TierGood, "Good Day for Drone Flying in Test Field"},
+		{"Default subject on a caution day", "", models.TierCaution, "Marginal Day for Drone Flying in Test Field - Fly with Caution"},
+		{"Custom subject", `Fly {{.LocationName}} on {{.Date.Format "Mon Jan 2"}}`, models.TierGood, "Fly Test Field on Sun Jun 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report.Tier = tt.tier
+			subject, err := email.RenderSubject(tt.tmpl, DefaultSubject, report)
+			if err != nil {
+				t.Fatalf("RenderSubject() error: %v", err)
+			}
+			if subject != tt.expected {
+				t.Errorf("Subject = %q, want %q", subject, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRequireTFRSuccess(t *testing.T) {
+	// The email template is resolved relative to the repository root
+	t.Chdir("../..")
+
+	weatherURL, _ := newTestServers(t, 10.0) // calm weather
+	failingTFR := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failingTFR.Close()
+
+	tests := []struct {
+		name          string
+		requireTFR    bool
+		expectFlyable bool
+		expectEmail   bool
+	}{
+		{"TFR failure is informational by default", false, true, true},
+		{"TFR failure blocks flying when required", true, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			smtpServer := newFakeSMTPServer(t)
+			cfg := &config.Config{
+				DroneWeather: config.DroneWeatherConfig{
+					HomeLatitude:      40.0,
+					HomeLongitude:     -74.0,
+					HomeName:          "Test Location",
+					SearchRadiusMiles: 25,
+					MaxWindSpeedKmh:   25,
+					MinVisibilityKm:   5,
+					MinTempC:          4.4,
+					MaxTempC:          35.0,
+					WeatherURL:        weatherURL,
+					TFRURL:            failingTFR.URL,
+					RequireTFRSuccess: tt.requireTFR,
+				},
+				Email: config.EmailConfig{
+					SMTPServer: "127.0.0.1",
+					SMTPPort:   smtpServer.port(),
+					Username:   "user",
+					Password:   "pass",
+					FromEmail:  "from@test.com",
+					ToEmail:    "to@test.com",
+				},
+			}
+			agent := NewDroneWeatherAgent(cfg)
+			if err := agent.Initialize(); err != nil {
+				t.Fatalf("Initialize() error: %v", err)
+			}
+
+			var metrics DroneMetrics
+			var snapshot *models.DroneCheckSnapshot
+			var partialFailure bool
+			events := &scheduler.AgentEvents{
+				OnSuccess:        func(m scheduler.Metrics, d time.Duration) { metrics = m.(DroneMetrics) },
+				OnPartialFailure: func(err error, d time.Duration) { partialFailure = true },
+				OnLastCheck:      func(s interface{}) { snapshot = s.(*models.DroneCheckSnapshot) },
+			}
+			if err := agent.RunOnce(context.Background(), events); err != nil {
+				t.Fatalf("RunOnce() error: %v", err)
+			}
+
+			if !partialFailure {
+				t.Error("Expected TFR failure to be reported as partial failure")
+			}
+			if metrics.IsFlyable != tt.expectFlyable {
+				t.Errorf("Expected IsFlyable=%v, got %v", tt.expectFlyable, metrics.IsFlyable)
+			}
+			if metrics.EmailSent != tt.expectEmail {
+				t.Errorf("Expected EmailSent=%v, got %v", tt.expectEmail, metrics.EmailSent)
+			}
+
+			hasTFRReason := false
+			for _, reason := range snapshot.Reasons {
+				if strings.Contains(reason, "TFR check failed") {
+					hasTFRReason = true
+				}
+			}
+			if hasTFRReason != tt.requireTFR {
+				t.Errorf("Expected TFR failure reason recorded=%v, got reasons %v", tt.requireTFR, snapshot.Reasons)
+			}
+		})
+	}
+}
+
+// fakeWeatherSource returns canned weather data and records invocation
+type fakeWeatherSource struct {
+	*WeatherClient
+	data    *models.WeatherData
+	started chan struct{}
+	wait    <-chan struct{}
+}
+
+func (f *fakeWeatherSource) GetCurrentWeather(ctx context.Context, lat, lon float64) (*models.WeatherData, error) {
+	close(f.started)
+	select {
+	case <-f.wait:
+	case <-time.After(2 * time.Second):
+		return nil, fmt.Errorf("TFR check never started concurrently")
+	}
+	return f.data, nil
+}
+
+// fakeTFRSource returns a canned TFR check and records invocation
+type fakeTFRSource struct {
+	check   *models.TFRCheck
+	started chan struct{}
+	wait    <-chan struct{}
+}
+
+func (f *fakeTFRSource) CheckTFRs(ctx context.Context, lat, lon float64) (*models.TFRCheck, error) {
+	close(f.started)
+	select {
+	case <-f.wait:
+	case <-time.After(2 * time.Second):
+		return nil, fmt.Errorf("weather fetch never started concurrently")
+	}
+	return f.check, nil
+}
+
+func TestRunOnceFetchesConcurrently(t *testing.T) {
+	weatherStarted := make(chan struct{})
+	tfrStarted := make(chan struct{})
+
+	cfg := &config.Config{
+		DroneWeather: config.DroneWeatherConfig{
+			HomeLatitude:    40.0,
+			HomeLongitude:   -74.0,
+			HomeName:        "Test Location",
+			MaxWindSpeedKmh: 25,
+			MinVisibilityKm: 5,
+			MinTempC:        4.4,
+			MaxTempC:        35.0,
+		},
+	}
+	agent := NewDroneWeatherAgent(cfg)
+
+	// Each fake blocks until the other has started, so a sequential RunOnce would time out
+	agent.weatherClient = &fakeWeatherSource{
+		WeatherClient: NewWeatherClient(&cfg.DroneWeather),
+		data: &models.WeatherData{
+			Temperature: 20.0,
+			WindSpeed:   40.0, // too windy, so no email is attempted
+			Visibility:  10.0,
+			Time:        time.Now(),
+		},
+		started: weatherStarted,
+		wait:    tfrStarted,
+	}
+	tfrCheck := &models.TFRCheck{Summary: "No restrictions found within 25 miles - clear to fly"}
+	agent.tfrClient = &fakeTFRSource{check: tfrCheck, started: tfrStarted, wait: weatherStarted}
+
+	if err := agent.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	var metrics DroneMetrics
+	var snapshot *models.DroneCheckSnapshot
+	events := &scheduler.AgentEvents{
+		OnSuccess:   func(m scheduler.Metrics, d time.Duration) { metrics = m.(DroneMetrics) },
+		OnLastCheck: func(s interface{}) { snapshot = s.(*models.DroneCheckSnapshot) },
+	}
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+
+	if !metrics.WeatherFetched || !metrics.TFRsChecked {
+		t.Errorf("Expected both weather and TFRs fetched, got %+v", metrics)
+	}
+	if metrics.IsFlyable {
+		t.Error("Expected windy conditions to be not flyable")
+	}
+	if snapshot.TFRCheck != tfrCheck {
+		t.Error("Expected TFR check result combined into snapshot")
+	}
+	if snapshot.WeatherAnalysis.Data.WindSpeed != 40.0 {
+		t.Error("Expected weather result combined into snapshot")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	calm := &models.WeatherData{Temperature: 20, WindSpeed: 10, Visibility: 10}
+	windy := &models.WeatherData{Temperature: 20, WindSpeed: 40, Visibility: 10}
+	clearTFRs := &models.TFRCheck{Summary: "No active TFRs within 10 miles"}
+	activeTFRs := &models.TFRCheck{
+		HasActiveTFRs: true,
+		ActiveTFRs:    []*models.TFR{{Name: "Stadium", Type: "Security"}},
+		CheckRadius:   10,
+		Summary:       "1 active TFR within 10 miles",
+	}
+
+	tests := []struct {
+		name          string
+		weather       *models.WeatherData
+		tfr           *models.TFRCheck
+		requireTFR    bool
+		tfrBlocks     bool
+		expectFlyable bool
+		expectReasons int
+	}{
+		{"Good weather and TFRs checked", calm, clearTFRs, false, false, true, 0},
+		{"High wind", windy, clearTFRs, false, false, false, 1},
+		{"TFR check failed is informational by default", calm, nil, false, false, true, 0},
+		{"TFR check failed blocks when required", calm, nil, true, false, false, 1},
+		{"Active TFRs are informational by default", calm, activeTFRs, false, false, true, 0},
+		{"Active TFRs block when configured", calm, activeTFRs, false, true, false, 1},
+		{"No active TFRs with blocking configured", calm, clearTFRs, false, true, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{DroneWeather: config.DroneWeatherConfig{
+				HomeName:           "Test Field",
+				MaxWindSpeedKmh:    25,
+				MinVisibilityKm:    5,
+				MaxPrecipitationMm: 0.1,
+				MinTempC:           0,
+				MaxTempC:           40,
+				SearchRadiusMiles:  10,
+				RequireTFRSuccess:  tt.requireTFR,
+				TFRBlocksFlight:    tt.tfrBlocks,
+			}}
+			// No Initialize: Evaluate must work without network clients or SMTP
+			agent := NewDroneWeatherAgent(cfg)
+
+			report := agent.Evaluate(tt.weather, tt.tfr)
+
+			if report.IsFlyable != tt.expectFlyable {
+				t.Errorf("Expected flyable=%t, got %t (reasons: %v)", tt.expectFlyable, report.IsFlyable, report.Reasons)
+			}
+			if len(report.Reasons) != tt.expectReasons {
+				t.Errorf("Expected %d reasons, got %v", tt.expectReasons, report.Reasons)
+			}
+			if report.LocationName != "Test Field" {
+				t.Errorf("Expected location Test Field, got %s", report.LocationName)
+			}
+			if report.TFRCheck == nil {
+				t.Fatal("Expected a TFR check in the report")
+			}
+			if tt.tfr == nil && !report.TFRCheck.HasActiveTFRs {
+				t.Error("Failed TFR check should be reported as possible restrictions")
+			}
+		})
+	}
+}
+
+func TestHighlightsForGoodDay(t *testing.T) {
+	// The email template is resolved relative to the repository root
+	t.Chdir("../..")
+
+	cfg := &config.Config{
+		DroneWeather: config.DroneWeatherConfig{
+			HomeName:        "Test Location",
+			MaxWindSpeedKmh: 25,
+			MinVisibilityKm: 5,
+			MinTempC:        4.4,
+			MaxTempC:        35.0,
+		},
+	}
+	agent := NewDroneWeatherAgent(cfg)
+
+	weather := &models.WeatherData{
+		Temperature:         20.0,
+		ApparentTemperature: 20.0,
+		WindSpeed:           12.0,
+		Visibility:          10.0,
+		Time:                time.Now(),
+	}
+	report := agent.Evaluate(weather, &models.TFRCheck{Summary: "No active TFRs"})
+
+	highlights := report.WeatherAnalysis.Highlights
+	if len(highlights) != 4 {
+		t.Fatalf("Expected a highlight for each of the 4 checks, got %v", highlights)
+	}
+	if highlights[0] != "Wind 12.0 km/h, well under 25 km/h limit" {
+		t.Errorf("Unexpected wind highlight: %q", highlights[0])
+	}
+
+	body, err := agent.generateEmailBody(report)
+	if err != nil {
+		t.Fatalf("generateEmailBody() error: %v", err)
+	}
+	for _, highlight := range highlights {
+		if !strings.Contains(body, highlight) {
+			t.Errorf("Expected email to contain highlight %q", highlight)
+		}
+	}
+}
+
+func TestDebugReportSection(t *testing.T) {
+	// The email template is resolved relative to the repository root
+	t.Chdir("../..")
+
+	weather := &models.WeatherData{
+		Temperature:         20.0,
+		ApparentTemperature: 20.0,
+		WindSpeed:           12.0,
+		Visibility:          10.0,
+		Time:                time.Now(),
+		RawResponse:         []byte(`{"current":{"temperature_2m":20}}`),
+	}
+
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("debug_report=%t", enabled), func(t *testing.T) {
+			cfg := &config.Config{
+				DroneWeather: config.DroneWeatherConfig{
+					HomeName:        "Test Location",
+					MaxWindSpeedKmh: 25,
+					MinVisibilityKm: 5,
+					MinTempC:        4.4,
+					MaxTempC:        35.0,
+					DebugReport:     enabled,
+				},
+			}
+			agent := NewDroneWeatherAgent(cfg)
+			report := agent.Evaluate(weather, &models.TFRCheck{Summary: "No active TFRs"})
+			report.Debug = agent.debugReport(weather)
+
+			body, err := agent.generateEmailBody(report)
+			if err != nil {
+				t.Fatalf("generateEmailBody() error: %v", err)
+			}
+
+			for _, want := range []string{"Raw Open-Meteo Response", "&#34;temperature_2m&#34;: 20", "&#34;wind_speed&#34;: 12"} {
+				if strings.Contains(body, want) != enabled {
+					t.Errorf("Expected body to contain %q: %t", want, enabled)
+				}
+			}
+		})
+	}
+}
+
+// countingSender counts emails instead of sending them over SMTP
+type countingSender struct {
+	sent int
+}
+
+func (c *countingSender) SendReport(report *models.EmailReport, subjectTemplate string) error {
+	c.sent++
+	return nil
+}
+
+func (c *countingSender) SendHTML(subject, htmlBody string) error {
+	c.sent++
+	return nil
+}
+
+func TestNotifyCooldown(t *testing.T) {
+	// The email template is resolved relative to the repository root
+	t.Chdir("../..")
+
+	calmURL, tfrURL := newTestServers(t, 10.0)
+	windyURL, _ := newTestServers(t, 40.0)
+
+	cfg := &config.Config{
+		DataDir: t.TempDir(),
+		DroneWeather: config.DroneWeatherConfig{
+			HomeLatitude:        40.0,
+			HomeLongitude:       -74.0,
+			HomeName:            "Test Location",
+			SearchRadiusMiles:   25,
+			MaxWindSpeedKmh:     25,
+			MinVisibilityKm:     5,
+			MinTempC:            4.4,
+			MaxTempC:            35.0,
+			WeatherURL:          calmURL,
+			TFRURL:              tfrURL,
+			NotifyCooldownHours: 6,
+		},
+	}
+	sender := &countingSender{}
+	agent := NewDroneWeatherAgent(cfg)
+	agent.emailSender = sender
+	if err := agent.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	run := func() DroneMetrics {
+		t.Helper()
+		var metrics DroneMetrics
+		events := &scheduler.AgentEvents{
+			OnSuccess: func(m scheduler.Metrics, d time.Duration) { metrics = m.(DroneMetrics) },
+		}
+		if err := agent.RunOnce(context.Background(), events); err != nil {
+			t.Fatalf("RunOnce() error: %v", err)
+		}
+		return metrics
+	}
+
+	if metrics := run(); !metrics.EmailSent || sender.sent != 1 {
+		t.Fatalf("Expected first flyable run to send an email, got metrics %+v and %d emails", metrics, sender.sent)
+	}
+
+	metrics := run()
+	if metrics.EmailSent || !metrics.CooldownActive || sender.sent != 1 {
+		t.Errorf("Expected second flyable run within the cooldown to send no email, got metrics %+v and %d emails", metrics, sender.sent)
+	}
+	if !metrics.IsFlyable {
+		t.Error("Expected checks to still run and report flyable during the cooldown")
+	}
+
+	// The cooldown survives a restart
+	restarted := NewDroneWeatherAgent(cfg)
+	restarted.emailSender = sender
+	if err := restarted.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+	if !restarted.inCooldown() {
+		t.Error("Expected cooldown to be restored after a restart")
+	}
+
+	windy := NewWeatherClient(&config.DroneWeatherConfig{WeatherURL: windyURL, ForecastHours: 24, MaxWindSpeedKmh: 25, MinVisibilityKm: 5, MinTempC: 4.4, MaxTempC: 35.0})
+	calm := NewWeatherClient(&cfg.DroneWeather)
+
+	// A single no-fly check between good ones ends the cooldown
+	agent.weatherClient = windy
+	if metrics := run(); metrics.IsFlyable {
+		t.Fatal("Expected windy run to be not flyable")
+	}
+	agent.weatherClient = calm
+	if metrics := run(); !metrics.EmailSent || sender.sent != 2 {
+		t.Errorf("Expected flyable run after a no-fly check to send again, got metrics %+v and %d emails", metrics, sender.sent)
+	}
+
+	// With notify_reset_no_fly_runs raised, a brief bad spell keeps the cooldown
+	agent.config.DroneWeather.NotifyResetNoFlyRuns = 2
+	agent.weatherClient = windy
+	run()
+	agent.weatherClient = calm
+	if metrics := run(); metrics.EmailSent || !metrics.CooldownActive || sender.sent != 2 {
+		t.Errorf("Expected flyable run after one of two required no-fly checks to stay in cooldown, got metrics %+v and %d emails", metrics, sender.sent)
+	}
+	agent.weatherClient = windy
+	run()
+	run()
+	agent.weatherClient = calm
+	if metrics := run(); !metrics.EmailSent || sender.sent != 3 {
+		t.Errorf("Expected flyable run after two no-fly checks to send again, got metrics %+v and %d emails", metrics, sender.sent)
+	}
+}
+
+func TestNoFlyHeadsUp(t *testing.T) {
+	// The email templates are resolved relative to the repository root
+	t.Chdir("../..")
+
+	windyURL, tfrURL := newTestServers(t, 40.0)
+	calmURL, _ := newTestServers(t, 10.0)
+
+	cfg := &config.Config{
+		DataDir: t.TempDir(),
+		DroneWeather: config.DroneWeatherConfig{
+			HomeLatitude:      40.0,
+			HomeLongitude:     -74.0,
+			HomeName:          "Test Location",
+			SearchRadiusMiles: 25,
+			MaxWindSpeedKmh:   25,
+			MinVisibilityKm:   5,
+			MinTempC:          4.4,
+			MaxTempC:          35.0,
+			WeatherURL:        windyURL,
+			TFRURL:            tfrURL,
+			NoFlyHeadsUpRuns:  3,
+		},
+	}
+	sender := &countingSender{}
+	agent := NewDroneWeatherAgent(cfg)
+	agent.emailSender = sender
+	if err := agent.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	run := func() DroneMetrics {
+		t.Helper()
+		var metrics DroneMetrics
+		events := &scheduler.AgentEvents{
+			OnSuccess: func(m scheduler.Metrics, d time.Duration) { metrics = m.(DroneMetrics) },
+		}
+		if err := agent.RunOnce(context.Background(), events); err != nil {
+			t.Fatalf("RunOnce() error: %v", err)
+		}
+		return metrics
+	}
+
+	for i := 1; i <= 5; i++ {
+		metrics := run()
+		if metrics.HeadsUpSent != (i == 3) {
+			t.Errorf("Run %d: expected heads-up sent %t, got %t", i, i == 3, metrics.HeadsUpSent)
+		}
+	}
+	if sender.sent != 1 {
+		t.Errorf("Expected exactly one heads-up email over 5 no-fly runs, got %d", sender.sent)
+	}
+
+	// The streak survives a restart
+	restarted := NewDroneWeatherAgent(cfg)
+	if err := restarted.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+	if restarted.noFlyStreak.Count() != 5 {
+		t.Errorf("Expected streak of 5 after restart, got %d", restarted.noFlyStreak.Count())
+	}
+
+	// A flyable check resets the streak so the next one can fire again
+	agent.weatherClient = NewWeatherClient(&config.DroneWeatherConfig{WeatherURL: calmURL, ForecastHours: 24, MaxWindSpeedKmh: 25, MinVisibilityKm: 5, MinTempC: 4.4, MaxTempC: 35.0})
+	if metrics := run(); !metrics.IsFlyable {
+		t.Fatal("Expected calm run to be flyable")
+	}
+	if agent.noFlyStreak.Count() != 0 {
+		t.Errorf("Expected flyable check to reset the streak, got %d", agent.noFlyStreak.Count())
+	}
+}
+
+func TestDebugHTTPCapturesResponses(t *testing.T) {
+	weatherURL, tfrURL := newTestServers(t, 40.0) // too windy, so no email is attempted
+
+	cfg := &config.Config{
+		DataDir: t.TempDir(),
+		DroneWeather: config.DroneWeatherConfig{
+			HomeLatitude:      40.0,
+			HomeLongitude:     -74.0,
+			HomeName:          "Test Location",
+			SearchRadiusMiles: 25,
+			MaxWindSpeedKmh:   25,
+			MinVisibilityKm:   5,
+			WeatherURL:        weatherURL,
+			TFRURL:            tfrURL,
+			DebugHTTP:         true,
+		},
+	}
+	agent := NewDroneWeatherAgent(cfg)
+	if err := agent.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+	if err := agent.RunOnce(context.Background(), nil); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(cfg.DataDir, httpDebugDir, "*.body"))
+	if err != nil || len(files) != 2 {
+		t.Errorf("Expected weather and TFR responses to be captured, got %v (err %v)", files, err)
+	}
+}
+
+func TestTFRsEndpointAfterRun(t *testing.T) {
+	weatherURL, tfrURL := newTestServers(t, 40.0) // too windy, so no email is attempted
+
+	cfg := &config.Config{
+		DroneWeather: config.DroneWeatherConfig{
+			HomeLatitude:      40.0,
+			HomeLongitude:     -74.0,
+			HomeName:          "Test Location",
+			SearchRadiusMiles: 25,
+			MaxWindSpeedKmh:   25,
+			MinVisibilityKm:   5,
+			WeatherURL:        weatherURL,
+			TFRURL:            tfrURL,
+		},
+	}
+	agent := NewDroneWeatherAgent(cfg)
+	if err := agent.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	monitor := monitoring.NewMonitor()
+	events := &scheduler.AgentEvents{OnSnapshot: monitor.RecordSnapshot}
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+
+	server := monitoring.NewHealthServer(monitor, "127.0.0.1", "0")
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start health server: %v", err)
+	}
+	defer server.Shutdown(context.Background())
+
+	resp, err := http.Get("http://" + server.Addr() + "/tfrs")
+	if err != nil {
+		t.Fatalf("Failed to query /tfrs: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 from /tfrs, got %d", resp.StatusCode)
+	}
+
+	var check models.TFRCheck
+	if err := json.NewDecoder(resp.Body).Decode(&check); err != nil {
+		t.Fatalf("Failed to decode TFR check: %v", err)
+	}
+	if check.CheckRadius != 25 || check.CheckTime.IsZero() || check.HasActiveTFRs || check.Summary == "" {
+		t.Errorf("Expected stored TFR check, got %+v", check)
+	}
+}
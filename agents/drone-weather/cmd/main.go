@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -10,20 +11,40 @@ import (
 
 	droneweather "agent-stack/agents/drone-weather"
 	"agent-stack/shared/config"
+	"agent-stack/shared/httpclient"
 	"agent-stack/shared/scheduler"
+	"agent-stack/shared/version"
 )
 
 func main() {
+	once := flag.Bool("once", false, "run a single weather check and exit")
+	serveAfterOnce := flag.Bool("serve-after-once", false, "with --once, keep serving health endpoints reflecting the run's outcome until stopped")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
+
+	log.Printf("%s %s", "drone-weather", version.Get())
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	if *printConfig {
+		if err := cfg.WriteEffective(os.Stdout); err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
+	}
+
 	// Validate Drone Weather specific configuration
 	if err := cfg.ValidateDroneWeather(); err != nil {
 		log.Fatalf("Failed to validate Drone Weather configuration: %v", err)
 	}
 
+	if err := httpclient.SetProxy(cfg.ProxyURL); err != nil {
+		log.Fatalf("Failed to configure proxy: %v", err)
+	}
+
 	// Create context that responds to signals
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
@@ -32,14 +53,24 @@ func main() {
 	agent := droneweather.NewDroneWeatherAgent(cfg)
 	s := scheduler.New(cfg, agent)
 
-	if len(os.Args) > 1 && os.Args[1] == "--once" {
+	if *once {
 		fmt.Println("Running once...")
 		if err := agent.Initialize(); err != nil {
 			log.Fatalf("Failed to initialize agent: %v", err)
 		}
 
-		if err := s.RunOnce(ctx); err != nil {
-			log.Fatalf("Failed to run: %v", err)
+		runErr := s.RunOnce(ctx)
+		if *serveAfterOnce {
+			if runErr != nil {
+				log.Printf("Run failed: %v", runErr)
+			}
+			if err := s.ServeHealth(ctx); err != nil && ctx.Err() == nil {
+				log.Fatalf("Health server failed: %v", err)
+			}
+			return
+		}
+		if runErr != nil {
+			log.Fatalf("Failed to run: %v", runErr)
 		}
 		return
 	}
@@ -0,0 +1,157 @@
+package droneweather
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"agent-stack/internal/models"
+)
+
+// OpenWeatherMapClient fetches weather from the OpenWeatherMap One Call 3.0 API.
+// Requests, retries and the forecast horizon are shared with the Open-Meteo client.
+type OpenWeatherMapClient struct {
+	weather *WeatherClient
+	apiKey  string
+}
+
+// OpenWeatherMapResponse represents the parts of a One Call 3.0 response used here,
+// requested in metric units (m/s and Celsius)
+type OpenWeatherMapResponse struct {
+	Lat            float64 `json:"lat"`
+	Lon            float64 `json:"lon"`
+	Timezone       string  `json:"timezone"`
+	TimezoneOffset int     `json:"timezone_offset"` // seconds from UTC
+	Current        struct {
+		Dt         int64                `json:"dt"`
+		Temp       float64              `json:"temp"`
+		FeelsLike  float64              `json:"feels_like"`
+		Visibility float64              `json:"visibility"` // meters, capped at 10 km
+		WindSpeed  float64              `json:"wind_speed"`
+		WindDeg    int                  `json:"wind_deg"`
+		Rain       openWeatherMapVolume `json:"rain"`
+		Snow       openWeatherMapVolume `json:"snow"`
+	} `json:"current"`
+	Hourly []struct {
+		Dt        int64   `json:"dt"`
+		WindSpeed float64 `json:"wind_speed"`
+		WindGust  float64 `json:"wind_gust"`
+		Pop       float64 `json:"pop"` // probability of precipitation, 0-1
+	} `json:"hourly"`
+	Daily []struct {
+		Dt   int64 `json:"dt"`
+		Temp struct {
+			Min float64 `json:"min"`
+			Max float64 `json:"max"`
+		} `json:"temp"`
+		WindSpeed float64 `json:"wind_speed"`
+		WindGust  float64 `json:"wind_gust"`
+		Pop       float64 `json:"pop"`
+		Rain      float64 `json:"rain"` // mm
+		Snow      float64 `json:"snow"` // mm
+	} `json:"daily"`
+}
+
+// openWeatherMapVolume is precipitation over the last hour in mm
+type openWeatherMapVolume struct {
+	OneHour float64 `json:"1h"`
+}
+
+// GetCurrentWeather fetches current conditions and the hourly and daily forecast from
+// OpenWeatherMap, converted to km/h and Celsius
+func (o *OpenWeatherMapClient) GetCurrentWeather(ctx context.Context, lat, lon float64) (*models.WeatherData, error) {
+	query := fmt.Sprintf("%s?lat=%.4f&lon=%.4f&units=metric&exclude=minutely,alerts", o.weather.config.WeatherURL, lat, lon)
+	// The API key is only accepted as a query parameter, so keep it out of the log
+	log.Printf("Fetching weather data from: %s", query)
+
+	body, err := o.weather.fetchWithRetry(ctx, query+"&appid="+url.QueryEscape(o.apiKey))
+	if err != nil {
+		return nil, err
+	}
+	var apiResp OpenWeatherMapResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode weather response: %w", err)
+	}
+	if apiResp.Current.Dt == 0 {
+		return nil, fmt.Errorf("weather response has no current conditions")
+	}
+
+	location, err := time.LoadLocation(apiResp.Timezone)
+	if err != nil {
+		log.Printf("Warning: Failed to load timezone %s, using its fixed offset: %v", apiResp.Timezone, err)
+		location = time.FixedZone(apiResp.Timezone, apiResp.TimezoneOffset)
+	}
+
+	current := apiResp.Current
+	return &models.WeatherData{
+		Latitude:            apiResp.Lat,
+		Longitude:           apiResp.Lon,
+		Temperature:         current.Temp,
+		ApparentTemperature: current.FeelsLike,
+		WindSpeed:           msToKmh(current.WindSpeed),
+		WindDir:             current.WindDeg,
+		Visibility:          current.Visibility / 1000, // Convert m to km
+		Precipitation:       current.Rain.OneHour + current.Snow.OneHour,
+		Time:                time.Unix(current.Dt, 0).In(location),
+		Timezone:            apiResp.Timezone,
+		HourlyData:          o.hourlyForecast(&apiResp, location),
+		DailyData:           o.dailyForecast(&apiResp, location),
+		RawResponse:         body,
+	}, nil
+}
+
+// hourlyForecast converts the hourly forecast, limited to forecast_hours
+func (o *OpenWeatherMapClient) hourlyForecast(apiResp *OpenWeatherMapResponse, location *time.Location) *models.HourlyForecast {
+	hours := apiResp.Hourly
+	if limit := o.weather.forecastHours(); len(hours) > limit {
+		hours = hours[:limit]
+	}
+	if len(hours) == 0 {
+		return nil
+	}
+
+	forecast := &models.HourlyForecast{}
+	for _, hour := range hours {
+		forecast.Times = append(forecast.Times, time.Unix(hour.Dt, 0).In(location))
+		forecast.WindSpeeds = append(forecast.WindSpeeds, msToKmh(hour.WindSpeed))
+		forecast.WindGusts = append(forecast.WindGusts, msToKmh(hour.WindGust))
+		forecast.PrecipProbabilities = append(forecast.PrecipProbabilities, hour.Pop*100)
+	}
+	return forecast
+}
+
+// dailyForecast converts today plus forecast_days following days, or nothing when
+// the outlook is disabled. The daily forecast covers 8 days including today.
+func (o *OpenWeatherMapClient) dailyForecast(apiResp *OpenWeatherMapResponse, location *time.Location) []models.DailyForecast {
+	days := o.weather.config.ForecastDays
+	if days <= 0 {
+		return nil
+	}
+
+	var forecast []models.DailyForecast
+	for i, day := range apiResp.Daily {
+		// forecast_days counts today, which the outlook skips
+		if i > days {
+			break
+		}
+		date := time.Unix(day.Dt, 0).In(location)
+		forecast = append(forecast, models.DailyForecast{
+			Date:                 time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, location),
+			MaxWindKmh:           msToKmh(day.WindSpeed),
+			MaxGustsKmh:          msToKmh(day.WindGust),
+			MinTempC:             day.Temp.Min,
+			MaxTempC:             day.Temp.Max,
+			PrecipitationMm:      day.Rain + day.Snow,
+			PrecipProbabilityPct: day.Pop * 100,
+		})
+	}
+	return forecast
+}
+
+// msToKmh converts meters per second to km/h
+func msToKmh(ms float64) float64 {
+	return ms * 3.6
+}
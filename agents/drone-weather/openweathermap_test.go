@@ -0,0 +1,124 @@
+package droneweather
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"agent-stack/shared/config"
+)
+
+const openWeatherMapFixture = `{
+	"lat": 40.0,
+	"lon": -74.0,
+	"timezone": "America/New_York",
+	"timezone_offset": -14400,
+	"current": {"dt": 1748782800, "temp": 20.5, "feels_like": 19.8, "visibility": 10000, "wind_speed": 5, "wind_deg": 200, "rain": {"1h": 0.4}},
+	"hourly": [
+		{"dt": 1748782800, "wind_speed": 5, "wind_gust": 8, "pop": 0.1},
+		{"dt": 1748786400, "wind_speed": 6, "wind_gust": 9, "pop": 0.35},
+		{"dt": 1748790000, "wind_speed": 7, "wind_gust": 10, "pop": 0}
+	],
+	"daily": [
+		{"dt": 1748797200, "temp": {"min": 14, "max": 24}, "wind_speed": 7, "wind_gust": 10, "pop": 0.35, "rain": 1.2},
+		{"dt": 1748883600, "temp": {"min": 12, "max": 22}, "wind_speed": 4, "wind_gust": 6, "pop": 0},
+		{"dt": 1748970000, "temp": {"min": 11, "max": 21}, "wind_speed": 3, "wind_gust": 5, "pop": 0.8, "rain": 6, "snow": 1}
+	]
+}`
+
+func TestOpenWeatherMapProvider(t *testing.T) {
+	var query map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(openWeatherMapFixture))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		DroneWeather: config.DroneWeatherConfig{
+			WeatherProvider:      config.WeatherProviderOpenWeatherMap,
+			WeatherURL:           server.URL,
+			OpenWeatherMapAPIKey: "owm-key",
+			ForecastHours:        2,
+			ForecastDays:         1,
+		},
+	}
+	source, err := NewDroneWeatherAgent(cfg).newWeatherSource()
+	if err != nil {
+		t.Fatalf("newWeatherSource() error: %v", err)
+	}
+
+	data, err := source.GetCurrentWeather(context.Background(), 40.0, -74.0)
+	if err != nil {
+		t.Fatalf("GetCurrentWeather() error: %v", err)
+	}
+
+	if got := query["appid"]; len(got) != 1 || got[0] != "owm-key" {
+		t.Errorf("Expected the API key as appid, got %v", got)
+	}
+	if got := query["units"]; len(got) != 1 || got[0] != "metric" {
+		t.Errorf("Expected metric units to be requested, got %v", got)
+	}
+
+	approx := func(name string, got, want float64) {
+		t.Helper()
+		if math.Abs(got-want) > 0.01 {
+			t.Errorf("Expected %s %.2f, got %.2f", name, want, got)
+		}
+	}
+	approx("wind speed (km/h)", data.WindSpeed, 18)
+	approx("temperature", data.Temperature, 20.5)
+	approx("apparent temperature", data.ApparentTemperature, 19.8)
+	approx("visibility (km)", data.Visibility, 10)
+	approx("precipitation", data.Precipitation, 0.4)
+	if data.WindDir != 200 {
+		t.Errorf("Expected wind direction 200, got %d", data.WindDir)
+	}
+	if data.Time.Location().String() != "America/New_York" || data.Time.Hour() != 9 {
+		t.Errorf("Expected 09:00 New York time, got %s", data.Time)
+	}
+
+	hourly := data.HourlyData
+	if hourly == nil || len(hourly.Times) != 2 {
+		t.Fatalf("Expected the hourly forecast limited to forecast_hours, got %+v", hourly)
+	}
+	approx("hourly gusts (km/h)", hourly.WindGusts[1], 32.4)
+	approx("hourly precipitation probability", hourly.PrecipProbabilities[1], 35)
+
+	if len(data.DailyData) != 2 {
+		t.Fatalf("Expected today plus forecast_days daily entries, got %d", len(data.DailyData))
+	}
+	tomorrow := data.DailyData[1]
+	if want := time.Date(2025, 6, 2, 0, 0, 0, 0, data.Time.Location()); !tomorrow.Date.Equal(want) {
+		t.Errorf("Expected tomorrow's date %s, got %s", want, tomorrow.Date)
+	}
+	approx("daily max wind (km/h)", tomorrow.MaxWindKmh, 14.4)
+	approx("daily min temp", tomorrow.MinTempC, 12)
+}
+
+func TestOpenWeatherMapFallbackUsesItsDefaultURL(t *testing.T) {
+	cfg := &config.Config{
+		DroneWeather: config.DroneWeatherConfig{
+			WeatherProvider:         config.WeatherProviderOpenMeteo,
+			WeatherURL:              "https://open-meteo.example.com/v1/forecast",
+			FallbackWeatherProvider: config.WeatherProviderOpenWeatherMap,
+			OpenWeatherMapAPIKey:    "owm-key",
+		},
+	}
+	source, err := NewDroneWeatherAgent(cfg).newWeatherSource()
+	if err != nil {
+		t.Fatalf("newWeatherSource() error: %v", err)
+	}
+
+	fallback, ok := source.fallback.(*OpenWeatherMapClient)
+	if !ok {
+		t.Fatalf("Expected an OpenWeatherMap fallback, got %T", source.fallback)
+	}
+	if got := fallback.weather.config.WeatherURL; got != config.DefaultWeatherURL(config.WeatherProviderOpenWeatherMap) {
+		t.Errorf("Expected the fallback to query the OpenWeatherMap endpoint, got %s", got)
+	}
+}
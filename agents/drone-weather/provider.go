@@ -0,0 +1,81 @@
+package droneweather
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"agent-stack/internal/models"
+	"agent-stack/shared/config"
+)
+
+// WeatherProvider fetches current conditions and the hourly forecast for a
+// location. Implementations return km/h and Celsius regardless of the API's units.
+type WeatherProvider interface {
+	GetCurrentWeather(ctx context.Context, lat, lon float64) (*models.WeatherData, error)
+}
+
+// failoverWeather fetches from the primary provider and, when that fails, from the
+// fallback (if any) before giving up. Analysis is provider independent and comes
+// from the embedded WeatherClient.
+type failoverWeather struct {
+	*WeatherClient
+	primary  WeatherProvider
+	fallback WeatherProvider // nil when no fallback is configured
+}
+
+func (f *failoverWeather) GetCurrentWeather(ctx context.Context, lat, lon float64) (*models.WeatherData, error) {
+	data, err := f.primary.GetCurrentWeather(ctx, lat, lon)
+	if err == nil || f.fallback == nil || ctx.Err() != nil {
+		return data, err
+	}
+
+	log.Printf("Warning: Primary weather provider failed, trying fallback: %v", err)
+	data, fallbackErr := f.fallback.GetCurrentWeather(ctx, lat, lon)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("%w (fallback provider also failed: %v)", err, fallbackErr)
+	}
+	return data, nil
+}
+
+// newWeatherSource builds the configured primary and fallback weather providers
+func (d *DroneWeatherAgent) newWeatherSource() (*failoverWeather, error) {
+	cfg := &d.config.DroneWeather
+
+	primary, err := d.newWeatherProvider(cfg.WeatherProvider, cfg.WeatherURL)
+	if err != nil {
+		return nil, err
+	}
+	source := &failoverWeather{WeatherClient: NewWeatherClient(cfg), primary: primary}
+
+	if cfg.FallbackWeatherProvider != "" {
+		url := cfg.FallbackWeatherURL
+		if url == "" && cfg.FallbackWeatherProvider == cfg.WeatherProvider {
+			url = cfg.WeatherURL
+		} else if url == "" {
+			url = config.DefaultWeatherURL(cfg.FallbackWeatherProvider)
+		}
+		source.fallback, err = d.newWeatherProvider(cfg.FallbackWeatherProvider, url)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return source, nil
+}
+
+// newWeatherProvider creates the named provider querying url
+func (d *DroneWeatherAgent) newWeatherProvider(name, url string) (WeatherProvider, error) {
+	providerCfg := d.config.DroneWeather
+	providerCfg.WeatherURL = url
+	client := NewWeatherClient(&providerCfg)
+	client.client = d.debugHTTP(client.client)
+
+	switch name {
+	case config.WeatherProviderOpenMeteo, "":
+		return client, nil
+	case config.WeatherProviderOpenWeatherMap:
+		return &OpenWeatherMapClient{weather: client, apiKey: providerCfg.OpenWeatherMapAPIKey}, nil
+	default:
+		return nil, fmt.Errorf("unknown weather provider %q", name)
+	}
+}
@@ -0,0 +1,116 @@
+package droneweather
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"agent-stack/internal/models"
+	"agent-stack/shared/config"
+	"agent-stack/shared/scheduler"
+)
+
+// fakeProvider returns canned weather or an error and counts calls
+type fakeProvider struct {
+	data  *models.WeatherData
+	err   error
+	calls int
+}
+
+func (f *fakeProvider) GetCurrentWeather(ctx context.Context, lat, lon float64) (*models.WeatherData, error) {
+	f.calls++
+	return f.data, f.err
+}
+
+func TestFallbackWeatherProvider(t *testing.T) {
+	cfg := &config.Config{
+		DroneWeather: config.DroneWeatherConfig{
+			HomeLatitude:    40.0,
+			HomeLongitude:   -74.0,
+			HomeName:        "Test Location",
+			MaxWindSpeedKmh: 25,
+			MinVisibilityKm: 5,
+			MinTempC:        4.4,
+			MaxTempC:        35.0,
+		},
+	}
+	primary := &fakeProvider{err: errors.New("weather API returned status 503")}
+	fallback := &fakeProvider{data: &models.WeatherData{
+		Temperature: 20.0,
+		WindSpeed:   40.0, // too windy, so no email is attempted
+		Visibility:  10.0,
+		Time:        time.Now(),
+	}}
+
+	agent := NewDroneWeatherAgent(cfg)
+	agent.weatherClient = &failoverWeather{WeatherClient: NewWeatherClient(&cfg.DroneWeather), primary: primary, fallback: fallback}
+	agent.tfrClient = &fakeTFRSource{check: &models.TFRCheck{Summary: "No active TFRs"}, started: make(chan struct{}), wait: closedChan()}
+
+	var metrics DroneMetrics
+	criticalFailed := false
+	events := &scheduler.AgentEvents{
+		OnSuccess:         func(m scheduler.Metrics, d time.Duration) { metrics = m.(DroneMetrics) },
+		OnCriticalFailure: func(err error, d time.Duration) { criticalFailed = true },
+	}
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+
+	if primary.calls != 1 || fallback.calls != 1 {
+		t.Errorf("Expected one call to each provider, got primary=%d fallback=%d", primary.calls, fallback.calls)
+	}
+	if criticalFailed || !metrics.WeatherFetched {
+		t.Errorf("Expected the fallback to rescue the run, got metrics %+v", metrics)
+	}
+
+	// Without a fallback the primary error is critical
+	agent.weatherClient = &failoverWeather{WeatherClient: NewWeatherClient(&cfg.DroneWeather), primary: primary}
+	agent.tfrClient = &fakeTFRSource{check: &models.TFRCheck{}, started: make(chan struct{}), wait: closedChan()}
+	if err := agent.RunOnce(context.Background(), events); err == nil || !criticalFailed {
+		t.Errorf("Expected a critical failure without a fallback, got err=%v", err)
+	}
+}
+
+func TestNewWeatherSourceUsesFallbackURL(t *testing.T) {
+	primaryServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer primaryServer.Close()
+	fallbackURL, _ := newTestServers(t, 12.0)
+
+	cfg := &config.Config{
+		DroneWeather: config.DroneWeatherConfig{
+			WeatherProvider:         config.WeatherProviderOpenMeteo,
+			WeatherURL:              primaryServer.URL,
+			FallbackWeatherProvider: config.WeatherProviderOpenMeteo,
+			FallbackWeatherURL:      fallbackURL,
+			ForecastHours:           24,
+		},
+	}
+	source, err := NewDroneWeatherAgent(cfg).newWeatherSource()
+	if err != nil {
+		t.Fatalf("newWeatherSource() error: %v", err)
+	}
+
+	data, err := source.GetCurrentWeather(context.Background(), 40.0, -74.0)
+	if err != nil {
+		t.Fatalf("Expected fallback provider to succeed, got %v", err)
+	}
+	if data.WindSpeed != 12.0 {
+		t.Errorf("Expected wind speed 12.0 from the fallback, got %.1f", data.WindSpeed)
+	}
+
+	cfg.DroneWeather.FallbackWeatherProvider = "darksky"
+	if _, err := NewDroneWeatherAgent(cfg).newWeatherSource(); err == nil {
+		t.Error("Expected an error for an unknown provider")
+	}
+}
+
+func closedChan() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
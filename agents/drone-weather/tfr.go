@@ -3,17 +3,21 @@ package droneweather
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"math"
 	"net/http"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"agent-stack/internal/models"
 	"agent-stack/shared/config"
+	"agent-stack/shared/httpclient"
 )
 
 // TFRClient handles interactions with the FAA TFR API
@@ -25,9 +29,7 @@ type TFRClient struct {
 func NewTFRClient(cfg *config.DroneWeatherConfig) *TFRClient {
 	return &TFRClient{
 		config: cfg,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client: httpclient.New(httpTimeout(cfg), cfg.UserAgent),
 	}
 }
 
@@ -48,6 +50,52 @@ type GeoJSONProperties struct {
 	LegalClass string `json:"LEGAL"`
 	Title      string `json:"TITLE"`
 	State      string `json:"STATE"`
+	// Vertical limits use the FAA's AIXM names. Values are numbers or strings such
+	// as "SFC" or "UNL"; units are "FT" or "FL" (flight level, hundreds of feet).
+	LowerValue geoJSONScalar `json:"VAL_DIST_VER_LOWER"`
+	LowerUnit  string        `json:"UOM_DIST_VER_LOWER"`
+	UpperValue geoJSONScalar `json:"VAL_DIST_VER_UPPER"`
+	UpperUnit  string        `json:"UOM_DIST_VER_UPPER"`
+}
+
+// geoJSONScalar is a property that may be encoded as a JSON string or number
+type geoJSONScalar string
+
+func (s *geoJSONScalar) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		*s = geoJSONScalar(str)
+		return nil
+	}
+	if string(data) == "null" {
+		*s = ""
+		return nil
+	}
+	*s = geoJSONScalar(data)
+	return nil
+}
+
+// parseAltitudeFt converts a vertical limit to feet. ok is false when the limit is
+// missing, unlimited or unparseable; the surface is 0 ft.
+func parseAltitudeFt(value geoJSONScalar, unit string) (feet int, ok bool) {
+	v := strings.ToUpper(strings.TrimSpace(string(value)))
+	switch v {
+	case "", "UNL", "UNLTD", "UNLIMITED":
+		return 0, false
+	case "SFC", "GND":
+		return 0, true
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	if strings.EqualFold(strings.TrimSpace(unit), "FL") {
+		n *= 100
+	}
+	return int(math.Round(n)), true
 }
 
 type GeoJSONGeometry struct {
@@ -57,21 +105,47 @@ type GeoJSONGeometry struct {
 
 // TFR fetching and parsing functions
 
-// fetchActiveTFRs fetches the list of active TFRs from FAA GeoJSON API
+// fetchActiveTFRs fetches the list of active TFRs from FAA GeoJSON API,
+// trying each configured endpoint in order until one succeeds
 func (t *TFRClient) fetchActiveTFRs(ctx context.Context) ([]*models.TFR, error) {
 	log.Printf("Fetching fresh TFR data")
 
-	// Use the FAA GeoServer WFS endpoint for TFR data
-	endpoint := "https://tfr.faa.gov/geoserver/TFR/ows?service=WFS&version=1.1.0&request=GetFeature&typeName=TFR:V_TFR_LOC&maxFeatures=300&outputFormat=application/json&srsname=EPSG:3857"
-	log.Printf("Fetching TFRs from: %s", endpoint)
+	var errs []error
+	for _, endpoint := range t.endpoints() {
+		log.Printf("Fetching TFRs from: %s", endpoint)
 
-	tfrs, err := t.fetchFromEndpoint(ctx, endpoint)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch TFRs from %s: %w", endpoint, err)
+		tfrs, err := t.fetchFromEndpoint(ctx, endpoint)
+		if err != nil {
+			log.Printf("TFR endpoint %s failed: %v", endpoint, err)
+			errs = append(errs, fmt.Errorf("failed to fetch TFRs from %s: %w", endpoint, err))
+
+			// Don't fall through to other endpoints once the caller has given up
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		log.Printf("Successfully fetched %d TFRs from %s", len(tfrs), endpoint)
+		return tfrs, nil
 	}
 
-	log.Printf("Successfully fetched %d TFRs", len(tfrs))
-	return tfrs, nil
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no TFR endpoints configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// endpoints returns the candidate TFR endpoints in priority order.
+// The fallback list takes precedence over the single URL when set.
+func (t *TFRClient) endpoints() []string {
+	if len(t.config.TFRURLs) > 0 {
+		return t.config.TFRURLs
+	}
+	if t.config.TFRURL != "" {
+		return []string{t.config.TFRURL}
+	}
+	return nil
 }
 
 // fetchFromEndpoint attempts to fetch TFR data from a specific endpoint
@@ -81,8 +155,7 @@ func (t *TFRClient) fetchFromEndpoint(ctx context.Context, endpoint string) ([]*
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	// Set headers to mimic browser request
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; DroneWeatherBot/1.0)")
+	// The User-Agent is set by the shared HTTP client
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 
 	resp, err := t.client.Do(req)
@@ -116,13 +189,27 @@ func (t *TFRClient) parseGeoJSONTFRs(body io.Reader) ([]*models.TFR, error) {
 		tfr.Type = feature.Properties.LegalClass
 		tfr.Name = feature.Properties.State
 
+		// Unknown limits leave the full band restricted: AltMin 0, AltMax 0 (unlimited)
+		if feet, ok := parseAltitudeFt(feature.Properties.LowerValue, feature.Properties.LowerUnit); ok {
+			tfr.AltMin = feet
+		}
+		if feet, ok := parseAltitudeFt(feature.Properties.UpperValue, feature.Properties.UpperUnit); ok {
+			tfr.AltMax = feet
+		}
+
 		// Parse dates from title
 		startTime, endTime, err := t.parseTFRDatesFromTitle(feature.Properties.Title)
 		if err != nil {
+			if t.config.UnparseableTFRPolicy == config.UnparseableTFRSkip {
+				log.Printf("Skipping TFR %s with unparseable dates: %v", tfr.ID, err)
+				continue
+			}
+
 			// For TFRs without clear date patterns (permanent restrictions), assume they're active
 			log.Printf("Using default dates for TFR %s (likely permanent): %v", tfr.ID, err)
 			tfr.StartTime = time.Now().Add(-24 * time.Hour)    // Started yesterday
 			tfr.EndTime = time.Now().Add(365 * 24 * time.Hour) // Valid for a year
+			tfr.DatesUnknown = t.config.UnparseableTFRPolicy == config.UnparseableTFRFlag
 		} else {
 			tfr.StartTime = startTime
 			tfr.EndTime = endTime
@@ -205,56 +292,78 @@ func (t *TFRClient) calculatePolygonCenter(coordinates [][]float64) (lat, lon, r
 		return 0, 0, 0
 	}
 
-	// Convert Web Mercator coordinates to lat/lon and calculate centroid
-	var latSum, lonSum float64
-	var validPoints int
-
-	for _, coord := range coordinates {
-		if len(coord) >= 2 {
-			mercatorLat, mercatorLon := coord[1], coord[0]
-			lat, lon := t.webMercatorToWGS84(mercatorLat, mercatorLon)
-			latSum += lat
-			lonSum += lon
-			validPoints++
+	// Convert Web Mercator coordinates to lat/lon, dropping degenerate points
+	var points [][2]float64
+	for i, coord := range coordinates {
+		if len(coord) < 2 {
+			log.Printf("Warning: Skipping polygon vertex %d with %d coordinate(s)", i, len(coord))
+			continue
+		}
+		lat, lon, ok := t.webMercatorToWGS84(coord[1], coord[0])
+		if !ok {
+			log.Printf("Warning: Skipping polygon vertex %d with invalid coordinates %v", i, coord)
+			continue
 		}
+		points = append(points, [2]float64{lat, lon})
 	}
 
-	if validPoints == 0 {
+	if len(points) == 0 {
 		return 0, 0, 0
 	}
 
-	// Calculate centroid
-	centerLat := latSum / float64(validPoints)
-	centerLon := lonSum / float64(validPoints)
+	// Calculate centroid, unwrapping longitudes relative to the first vertex so
+	// polygons spanning the dateline don't average out to the wrong hemisphere
+	var latSum, lonSum float64
+	refLon := points[0][1]
+	for _, p := range points {
+		latSum += p[0]
+		lonSum += refLon + normalizeLongitude(p[1]-refLon)
+	}
+
+	centerLat := latSum / float64(len(points))
+	centerLon := normalizeLongitude(lonSum / float64(len(points)))
 
 	// Calculate approximate radius as max distance from center to any vertex
 	var maxDistance float64
-	for _, coord := range coordinates {
-		if len(coord) >= 2 {
-			mercatorLat, mercatorLon := coord[1], coord[0]
-			lat, lon := t.webMercatorToWGS84(mercatorLat, mercatorLon)
-			distance := t.calculateDistance(centerLat, centerLon, lat, lon)
-			if distance > maxDistance {
-				maxDistance = distance
-			}
+	for _, p := range points {
+		distance := t.calculateDistance(centerLat, centerLon, p[0], p[1])
+		if distance > maxDistance {
+			maxDistance = distance
 		}
 	}
 
 	return centerLat, centerLon, maxDistance
 }
 
-// webMercatorToWGS84 converts Web Mercator (EPSG:3857) coordinates to WGS84 lat/lon
-func (t *TFRClient) webMercatorToWGS84(mercatorY, mercatorX float64) (lat, lon float64) {
-	// Convert from Web Mercator to WGS84
-	lon = mercatorX / 20037508.34 * 180
-	lat = mercatorY / 20037508.34 * 180
+// webMercatorMaxExtent is the half-width of the EPSG:3857 projected plane in meters
+const webMercatorMaxExtent = 20037508.34
+
+// webMercatorToWGS84 converts Web Mercator (EPSG:3857) coordinates to WGS84 lat/lon.
+// Out-of-range values are clamped (latitude) or wrapped (longitude); ok is false
+// for NaN or infinite input.
+func (t *TFRClient) webMercatorToWGS84(mercatorY, mercatorX float64) (lat, lon float64, ok bool) {
+	if math.IsNaN(mercatorX) || math.IsNaN(mercatorY) || math.IsInf(mercatorX, 0) || math.IsInf(mercatorY, 0) {
+		return 0, 0, false
+	}
+
+	// Clamp to the projection's valid square (about ±85.05° latitude)
+	mercatorY = math.Max(-webMercatorMaxExtent, math.Min(webMercatorMaxExtent, mercatorY))
+
+	lon = normalizeLongitude(mercatorX / webMercatorMaxExtent * 180)
+	lat = mercatorY / webMercatorMaxExtent * 180
 	lat = 180 / math.Pi * (2*math.Atan(math.Exp(lat*math.Pi/180)) - math.Pi/2)
-	return lat, lon
+	return lat, lon, true
+}
+
+// normalizeLongitude wraps a longitude into the [-180, 180] range
+func normalizeLongitude(lon float64) float64 {
+	return math.Remainder(lon, 360)
 }
 
 // CheckTFRs checks for active TFRs in the area around the given coordinates
 func (t *TFRClient) CheckTFRs(ctx context.Context, lat, lon float64) (*models.TFRCheck, error) {
-	log.Printf("Checking TFRs around %.4f, %.4f within %d miles", lat, lon, t.config.SearchRadiusMiles)
+	radius, unit := searchRadius(t.config)
+	log.Printf("Checking TFRs around %.4f, %.4f within %d %s", lat, lon, radius, unit)
 
 	// Fetch active TFRs from FAA API
 	allTFRs, err := t.fetchActiveTFRs(ctx)
@@ -264,10 +373,13 @@ func (t *TFRClient) CheckTFRs(ctx context.Context, lat, lon float64) (*models.TF
 		return t.buildTFRCheck([]*models.TFR{}), err
 	}
 
-	// Filter TFRs that are currently active and within search area
-	var activeTFRs []*models.TFR
-	now := time.Now()
+	return t.buildTFRCheck(t.activeNearby(lat, lon, allTFRs, time.Now())), nil
+}
 
+// activeNearby filters TFRs to those active at now and within the search area,
+// recording each one's distance from home and sorting them nearest first
+func (t *TFRClient) activeNearby(lat, lon float64, allTFRs []*models.TFR, now time.Time) []*models.TFR {
+	var activeTFRs []*models.TFR
 	for _, tfr := range allTFRs {
 		// Check if TFR is currently active
 		// Skip if TFR hasn't started yet OR if TFR has already ended
@@ -277,34 +389,74 @@ func (t *TFRClient) CheckTFRs(ctx context.Context, lat, lon float64) (*models.TF
 
 		// Check if TFR intersects with search area
 		if t.isWithinSearchArea(lat, lon, tfr) {
+			tfr.DistanceMiles = t.calculateDistance(lat, lon, tfr.Latitude, tfr.Longitude)
 			activeTFRs = append(activeTFRs, tfr)
 		}
 	}
 
-	return t.buildTFRCheck(activeTFRs), nil
+	sort.SliceStable(activeTFRs, func(i, j int) bool {
+		return activeTFRs[i].DistanceMiles < activeTFRs[j].DistanceMiles
+	})
+	return activeTFRs
 }
 
 // buildTFRCheck creates a TFRCheck result from a list of active TFRs
 func (t *TFRClient) buildTFRCheck(activeTFRs []*models.TFR) *models.TFRCheck {
+	radius, unit := searchRadius(t.config)
 	check := &models.TFRCheck{
 		HasActiveTFRs: len(activeTFRs) > 0,
 		ActiveTFRs:    activeTFRs,
-		CheckRadius:   t.config.SearchRadiusMiles,
+		CheckRadius:   radius,
+		RadiusUnit:    unit,
 		CheckTime:     time.Now(),
 	}
 
 	if len(activeTFRs) == 0 {
-		check.Summary = fmt.Sprintf("No restrictions found within %d miles - clear to fly", t.config.SearchRadiusMiles)
+		check.Summary = fmt.Sprintf("No restrictions found within %d %s - clear to fly", radius, unit)
 	} else {
-		check.Summary = fmt.Sprintf("%d restriction(s) found within %d miles - check locations before flying", len(activeTFRs), t.config.SearchRadiusMiles)
+		check.Summary = fmt.Sprintf("%d restriction(s) found within %d %s - check locations before flying", len(activeTFRs), radius, unit)
+	}
+
+	var unknownDates int
+	for _, tfr := range activeTFRs {
+		if tfr.DatesUnknown {
+			unknownDates++
+		}
+	}
+	if unknownDates > 0 {
+		check.Summary += fmt.Sprintf(" (%d with unverified dates)", unknownDates)
 	}
 
 	return check
 }
 
-// isWithinSearchArea checks if a TFR intersects with the search area around the given coordinates
+// searchRadius returns the TFR search radius and its unit as configured for display;
+// search_radius_km takes precedence over search_radius_miles
+func searchRadius(cfg *config.DroneWeatherConfig) (int, string) {
+	if cfg.SearchRadiusKm > 0 {
+		return cfg.SearchRadiusKm, "km"
+	}
+	return cfg.SearchRadiusMiles, "miles"
+}
+
+// searchRadiusInMiles returns the configured search radius converted to miles,
+// the unit used by all TFR distance calculations
+func searchRadiusInMiles(cfg *config.DroneWeatherConfig) float64 {
+	if cfg.SearchRadiusKm > 0 {
+		return float64(cfg.SearchRadiusKm) / 1.609344 // 1 mile = 1.609344 km
+	}
+	return float64(cfg.SearchRadiusMiles)
+}
+
+// isWithinSearchArea checks if a TFR intersects with the search volume around the given coordinates.
+// Both the horizontal area and the configured flight altitude band must overlap.
 func (t *TFRClient) isWithinSearchArea(homeLat, homeLon float64, tfr *models.TFR) bool {
-	searchRadiusMiles := float64(t.config.SearchRadiusMiles)
+	return t.overlapsHorizontally(homeLat, homeLon, tfr) && t.overlapsVertically(tfr)
+}
+
+// overlapsHorizontally checks if a TFR's circle intersects the search radius around home
+func (t *TFRClient) overlapsHorizontally(homeLat, homeLon float64, tfr *models.TFR) bool {
+	searchRadiusMiles := searchRadiusInMiles(t.config)
 
 	// Simple distance-based check
 	if tfr.Latitude == 0 && tfr.Longitude == 0 {
@@ -321,6 +473,18 @@ func (t *TFRClient) isWithinSearchArea(homeLat, homeLon float64, tfr *models.TFR
 	return distanceToCenter <= (searchRadiusMiles + tfrRadiusMiles)
 }
 
+// overlapsVertically checks if a TFR's altitude band overlaps the configured flight floor/ceiling.
+// TFRs without a known upper bound are assumed to extend indefinitely upward.
+func (t *TFRClient) overlapsVertically(tfr *models.TFR) bool {
+	if tfr.AltMin > t.config.FlightCeilingFt {
+		return false // Restriction starts above our highest flight altitude
+	}
+	if tfr.AltMax > 0 && tfr.AltMax < t.config.FlightFloorFt {
+		return false // Restriction ends below our lowest flight altitude
+	}
+	return true
+}
+
 // calculateDistance calculates the distance between two coordinates in miles
 func (t *TFRClient) calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
 	const earthRadiusMiles = 3959.0
@@ -1,7 +1,13 @@
 package droneweather
 
 import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"agent-stack/internal/models"
 	"agent-stack/shared/config"
@@ -48,7 +54,7 @@ func TestCalculateDistance(t *testing.T) {
 }
 
 func TestIsWithinSearchArea(t *testing.T) {
-	client := &TFRClient{config: &config.DroneWeatherConfig{SearchRadiusMiles: 25}}
+	client := &TFRClient{config: &config.DroneWeatherConfig{SearchRadiusMiles: 25, FlightCeilingFt: 400}}
 
 	tests := []struct {
 		name     string
@@ -75,6 +81,24 @@ func TestIsWithinSearchArea(t *testing.T) {
 			tfr:      &models.TFR{Latitude: 0, Longitude: 0, Radius: 10},
 			expected: false,
 		},
+		{
+			name:    "Overlaps horizontally and vertically",
+			homeLat: 40.0, homeLon: -74.0,
+			tfr:      &models.TFR{Latitude: 40.1, Longitude: -74.1, Radius: 10, AltMin: 0, AltMax: 3000},
+			expected: true,
+		},
+		{
+			name:    "Overlaps horizontally but starts above flight ceiling",
+			homeLat: 40.0, homeLon: -74.0,
+			tfr:      &models.TFR{Latitude: 40.1, Longitude: -74.1, Radius: 10, AltMin: 1000, AltMax: 18000},
+			expected: false,
+		},
+		{
+			name:    "Overlaps vertically but outside horizontal area",
+			homeLat: 40.0, homeLon: -74.0,
+			tfr:      &models.TFR{Latitude: 42.0, Longitude: -76.0, Radius: 5, AltMin: 0, AltMax: 3000},
+			expected: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -151,3 +175,345 @@ func abs(x float64) float64 {
 	}
 	return x
 }
+
+func TestSearchRadiusKm(t *testing.T) {
+	miles := &TFRClient{config: &config.DroneWeatherConfig{SearchRadiusMiles: 40, FlightCeilingFt: 400}}
+	// 64 km is about 39.8 miles; km also takes precedence over the miles setting
+	km := &TFRClient{config: &config.DroneWeatherConfig{SearchRadiusMiles: 5, SearchRadiusKm: 64, FlightCeilingFt: 400}}
+
+	for _, tfr := range []*models.TFR{
+		{Latitude: 40.3, Longitude: -74.0}, // about 21 miles north
+		{Latitude: 40.9, Longitude: -74.0}, // about 62 miles north
+		{Latitude: 40.0, Longitude: -74.5, Radius: 10},
+		{Latitude: 41.0, Longitude: -75.0, Radius: 2},
+	} {
+		inMiles := miles.isWithinSearchArea(40.0, -74.0, tfr)
+		inKm := km.isWithinSearchArea(40.0, -74.0, tfr)
+		if inMiles != inKm {
+			t.Errorf("Expected equivalent radii to agree for TFR at %.1f, %.1f: miles=%v km=%v", tfr.Latitude, tfr.Longitude, inMiles, inKm)
+		}
+	}
+
+	check := km.buildTFRCheck(nil)
+	if check.CheckRadius != 64 || check.RadiusUnit != "km" {
+		t.Errorf("Expected radius 64 km, got %d %s", check.CheckRadius, check.RadiusUnit)
+	}
+	if expected := "No restrictions found within 64 km - clear to fly"; check.Summary != expected {
+		t.Errorf("Expected summary %q, got %q", expected, check.Summary)
+	}
+	if check := miles.buildTFRCheck(nil); check.RadiusUnit != "miles" {
+		t.Errorf("Expected miles unit, got %q", check.RadiusUnit)
+	}
+}
+
+func TestActiveNearbySortsByDistance(t *testing.T) {
+	client := &TFRClient{config: &config.DroneWeatherConfig{SearchRadiusMiles: 25, FlightCeilingFt: 400}}
+	now := time.Now()
+	active := func(name string, lat float64) *models.TFR {
+		return &models.TFR{Name: name, Latitude: lat, Longitude: -74.0, StartTime: now.Add(-time.Hour)}
+	}
+
+	nearby := client.activeNearby(40.0, -74.0, []*models.TFR{
+		active("FAR", 40.3),   // about 21 miles north
+		active("NEAR", 40.05), // about 3.5 miles north
+		active("OUT OF RANGE", 41.0),
+		{Name: "EXPIRED", Latitude: 40.0, Longitude: -74.0, StartTime: now.Add(-2 * time.Hour), EndTime: now.Add(-time.Hour)},
+		active("MID", 40.15),
+	}, now)
+
+	var names []string
+	for _, tfr := range nearby {
+		names = append(names, tfr.Name)
+		if tfr.DistanceMiles <= 0 {
+			t.Errorf("Expected distance populated for %s, got %.2f", tfr.Name, tfr.DistanceMiles)
+		}
+	}
+	if got := strings.Join(names, ","); got != "NEAR,MID,FAR" {
+		t.Fatalf("Expected nearest-first NEAR,MID,FAR, got %s", got)
+	}
+
+	check := client.buildTFRCheck(nearby)
+	if got := check.Distance(nearby[0]); got != "3.5 mi" {
+		t.Errorf("Expected \"3.5 mi\", got %q", got)
+	}
+	check.RadiusUnit = "km"
+	if got := check.Distance(nearby[0]); got != "5.6 km" {
+		t.Errorf("Expected \"5.6 km\", got %q", got)
+	}
+}
+
+func TestUnparseableTFRPolicy(t *testing.T) {
+	geoJSON := `{
+		"type": "FeatureCollection",
+		"features": [{
+			"type": "Feature",
+			"properties": {"NOTAM_KEY": "1/2345", "LEGAL": "SECURITY", "TITLE": "WASHINGTON, DC, Special Security Area", "STATE": "DC"},
+			"geometry": {"type": "Polygon", "coordinates": [[[-8575000, 4705000], [-8574000, 4705000], [-8574000, 4706000]]]}
+		}]
+	}`
+
+	tests := []struct {
+		name          string
+		policy        string
+		expectCount   int
+		expectUnknown bool
+		expectSuffix  bool
+	}{
+		{"Active policy keeps TFR", config.UnparseableTFRActive, 1, false, false},
+		{"Skip policy drops TFR", config.UnparseableTFRSkip, 0, false, false},
+		{"Flag policy marks TFR", config.UnparseableTFRFlag, 1, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &TFRClient{config: &config.DroneWeatherConfig{SearchRadiusMiles: 25, UnparseableTFRPolicy: tt.policy}}
+
+			tfrs, err := client.parseGeoJSONTFRs(strings.NewReader(geoJSON))
+			if err != nil {
+				t.Fatalf("parseGeoJSONTFRs() error: %v", err)
+			}
+			if len(tfrs) != tt.expectCount {
+				t.Fatalf("Expected %d TFRs, got %d", tt.expectCount, len(tfrs))
+			}
+			if tt.expectCount == 0 {
+				return
+			}
+
+			if tfrs[0].DatesUnknown != tt.expectUnknown {
+				t.Errorf("Expected DatesUnknown=%v, got %v", tt.expectUnknown, tfrs[0].DatesUnknown)
+			}
+			if !tfrs[0].EndTime.After(time.Now()) {
+				t.Error("Expected TFR with unparseable dates to be treated as active")
+			}
+
+			check := client.buildTFRCheck(tfrs)
+			hasSuffix := strings.HasSuffix(check.Summary, "(1 with unverified dates)")
+			if hasSuffix != tt.expectSuffix {
+				t.Errorf("Unexpected summary %q", check.Summary)
+			}
+		})
+	}
+}
+
+func TestParseGeoJSONTFRAltitudes(t *testing.T) {
+	// Property layout as served by the FAA GeoServer, with AIXM vertical limits
+	geoJSON := `{
+		"type": "FeatureCollection",
+		"features": [
+			{"type": "Feature", "id": "V_TFR_LOC.1",
+			 "properties": {"GID": 1, "NOTAM_KEY": "4/1111-1-FDC-F", "LEGAL": "SPACE OPERATIONS", "TITLE": "CAPE CANAVERAL, FL, Space Operations", "STATE": "FL", "CNS_LOCATION_ID": "ZJX",
+			   "VAL_DIST_VER_LOWER": "SFC", "UOM_DIST_VER_LOWER": "FT", "VAL_DIST_VER_UPPER": "UNL", "UOM_DIST_VER_UPPER": "FT"},
+			 "geometry": {"type": "Polygon", "coordinates": [[[-8975000, 3315000], [-8974000, 3315000], [-8974000, 3316000]]]}},
+			{"type": "Feature", "id": "V_TFR_LOC.2",
+			 "properties": {"GID": 2, "NOTAM_KEY": "4/2222-1-FDC-F", "LEGAL": "VIP", "TITLE": "NEW YORK, NY, VIP Movement", "STATE": "NY", "CNS_LOCATION_ID": "ZNY",
+			   "VAL_DIST_VER_LOWER": 3000, "UOM_DIST_VER_LOWER": "FT", "VAL_DIST_VER_UPPER": 180, "UOM_DIST_VER_UPPER": "FL"},
+			 "geometry": {"type": "Polygon", "coordinates": [[[-8235000, 4975000], [-8234000, 4975000], [-8234000, 4976000]]]}},
+			{"type": "Feature", "id": "V_TFR_LOC.3",
+			 "properties": {"GID": 3, "NOTAM_KEY": "4/3333-1-FDC-F", "LEGAL": "HAZARDS", "TITLE": "DENVER, CO, Fire Fighting", "STATE": "CO", "CNS_LOCATION_ID": "ZDV",
+			   "VAL_DIST_VER_LOWER": null, "VAL_DIST_VER_UPPER": "8000", "UOM_DIST_VER_UPPER": "FT"},
+			 "geometry": {"type": "Polygon", "coordinates": [[[-11680000, 4825000], [-11679000, 4825000], [-11679000, 4826000]]]}}
+		]
+	}`
+	client := &TFRClient{config: &config.DroneWeatherConfig{FlightCeilingFt: 400}}
+
+	tfrs, err := client.parseGeoJSONTFRs(strings.NewReader(geoJSON))
+	if err != nil {
+		t.Fatalf("parseGeoJSONTFRs() error: %v", err)
+	}
+	if len(tfrs) != 3 {
+		t.Fatalf("Expected 3 TFRs, got %d", len(tfrs))
+	}
+
+	expected := []struct {
+		altMin, altMax int
+		overlaps       bool
+	}{
+		{0, 0, true},         // surface to unlimited
+		{3000, 18000, false}, // starts far above the flight ceiling
+		{0, 8000, true},      // missing lower limit assumed to be the surface
+	}
+	for i, want := range expected {
+		tfr := tfrs[i]
+		if tfr.AltMin != want.altMin || tfr.AltMax != want.altMax {
+			t.Errorf("%s: expected altitudes %d-%d ft, got %d-%d", tfr.ID, want.altMin, want.altMax, tfr.AltMin, tfr.AltMax)
+		}
+		if got := client.overlapsVertically(tfr); got != want.overlaps {
+			t.Errorf("%s: expected overlapsVertically=%v, got %v", tfr.ID, want.overlaps, got)
+		}
+	}
+}
+
+func TestFetchActiveTFRsUsesConfiguredURL(t *testing.T) {
+	var requestedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.RequestURI()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"type": "FeatureCollection", "features": []}`))
+	}))
+	defer server.Close()
+
+	client := NewTFRClient(&config.DroneWeatherConfig{
+		SearchRadiusMiles: 25,
+		TFRURL:            server.URL + "/mirror/tfrs?format=json",
+	})
+
+	if _, err := client.fetchActiveTFRs(context.Background()); err != nil {
+		t.Fatalf("fetchActiveTFRs() error: %v", err)
+	}
+
+	if requestedPath != "/mirror/tfrs?format=json" {
+		t.Errorf("Expected request to configured URL, got %q", requestedPath)
+	}
+}
+
+func TestFetchActiveTFRsFallsBackToNextEndpoint(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"type": "FeatureCollection",
+			"features": [{
+				"type": "Feature",
+				"properties": {"NOTAM_KEY": "5/6789", "LEGAL": "HAZARDS", "TITLE": "SAN JOSE, CA, Monday, January 13, 2025 through Friday, December 19, 2025 UTC", "STATE": "CA"},
+				"geometry": {"type": "Polygon", "coordinates": [[[-13580000, 4500000], [-13579000, 4500000], [-13579000, 4501000]]]}
+			}]
+		}`))
+	}))
+	defer working.Close()
+
+	client := NewTFRClient(&config.DroneWeatherConfig{
+		SearchRadiusMiles: 25,
+		TFRURLs:           []string{failing.URL, working.URL},
+	})
+
+	tfrs, err := client.fetchActiveTFRs(context.Background())
+	if err != nil {
+		t.Fatalf("fetchActiveTFRs() error: %v", err)
+	}
+	if len(tfrs) != 1 || tfrs[0].ID != "5/6789" {
+		t.Errorf("Expected TFR from fallback endpoint, got %+v", tfrs)
+	}
+}
+
+func TestFetchActiveTFRsAllEndpointsFail(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	client := NewTFRClient(&config.DroneWeatherConfig{
+		TFRURLs: []string{failing.URL + "/a", failing.URL + "/b"},
+	})
+
+	_, err := client.fetchActiveTFRs(context.Background())
+	if err == nil {
+		t.Fatal("Expected error when all endpoints fail")
+	}
+	if !strings.Contains(err.Error(), "/a") || !strings.Contains(err.Error(), "/b") {
+		t.Errorf("Expected aggregated error mentioning both endpoints, got: %v", err)
+	}
+}
+
+func TestWebMercatorToWGS84(t *testing.T) {
+	client := &TFRClient{}
+
+	tests := []struct {
+		name      string
+		mercatorX float64
+		mercatorY float64
+		expectLat float64
+		expectLon float64
+	}{
+		{"Origin", 0, 0, 0, 0},
+		{"New York City", -8238310.2345, 4970071.5785, 40.7128, -74.0060},
+		{"London", -14226.6309, 6711542.4747, 51.5074, -0.1278},
+		{"Sydney", 16832542.2769, -4011198.6467, -33.8688, 151.2093},
+		{"Beyond dateline wraps", 16832542.2769 - 2*webMercatorMaxExtent, 0, 0, 151.2093},
+		{"Beyond north bound clamps", 0, 3 * webMercatorMaxExtent, 85.0511, 0},
+		{"Beyond south bound clamps", 0, -3 * webMercatorMaxExtent, -85.0511, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lat, lon, ok := client.webMercatorToWGS84(tt.mercatorY, tt.mercatorX)
+			if !ok {
+				t.Fatal("Expected conversion to succeed")
+			}
+			if abs(lat-tt.expectLat) > 1e-4 || abs(lon-tt.expectLon) > 1e-4 {
+				t.Errorf("webMercatorToWGS84() = (%.4f, %.4f), want (%.4f, %.4f)", lat, lon, tt.expectLat, tt.expectLon)
+			}
+		})
+	}
+}
+
+func TestWebMercatorToWGS84DegenerateInput(t *testing.T) {
+	client := &TFRClient{}
+
+	inputs := [][2]float64{
+		{math.NaN(), 0},
+		{0, math.NaN()},
+		{math.Inf(1), 0},
+		{0, math.Inf(-1)},
+	}
+
+	for _, in := range inputs {
+		lat, lon, ok := client.webMercatorToWGS84(in[0], in[1])
+		if ok {
+			t.Errorf("Expected failure for input %v, got (%v, %v)", in, lat, lon)
+		}
+		if math.IsNaN(lat) || math.IsNaN(lon) {
+			t.Errorf("Conversion produced NaN for input %v", in)
+		}
+	}
+}
+
+func TestCalculatePolygonCenterAcrossDateline(t *testing.T) {
+	client := &TFRClient{}
+
+	// Square straddling the antimeridian at the equator (179°E to 179°W)
+	x := 179.0 / 180 * webMercatorMaxExtent
+	coords := [][]float64{
+		{x, -100000}, {-x, -100000}, {-x, 100000}, {x, 100000},
+		{1}, // degenerate vertex should be skipped
+	}
+
+	lat, lon, radius := client.calculatePolygonCenter(coords)
+	if abs(lat) > 1e-6 {
+		t.Errorf("Expected centroid latitude 0, got %v", lat)
+	}
+	if abs(abs(lon)-180) > 1e-6 {
+		t.Errorf("Expected centroid on the dateline, got longitude %v", lon)
+	}
+	if radius <= 0 || radius > 200 {
+		t.Errorf("Expected small radius around dateline, got %v miles", radius)
+	}
+}
+
+func TestOverlapsVertically(t *testing.T) {
+	client := &TFRClient{config: &config.DroneWeatherConfig{FlightFloorFt: 200, FlightCeilingFt: 400}}
+
+	tests := []struct {
+		name     string
+		tfr      *models.TFR
+		expected bool
+	}{
+		{"Unknown altitude band", &models.TFR{}, true},
+		{"Surface to 3000 ft", &models.TFR{AltMin: 0, AltMax: 3000}, true},
+		{"Band within flight range", &models.TFR{AltMin: 250, AltMax: 300}, true},
+		{"Band entirely above ceiling", &models.TFR{AltMin: 500, AltMax: 3000}, false},
+		{"Band entirely below floor", &models.TFR{AltMin: 0, AltMax: 100}, false},
+		{"Band touching ceiling", &models.TFR{AltMin: 400, AltMax: 1000}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := client.overlapsVertically(tt.tfr); result != tt.expected {
+				t.Errorf("overlapsVertically() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -4,81 +4,104 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"net/http"
 	"time"
 
 	"agent-stack/internal/models"
 	"agent-stack/shared/config"
+	"agent-stack/shared/httpclient"
 )
 
 // WeatherClient handles interactions with the Open-Meteo API
 type WeatherClient struct {
-	config *config.DroneWeatherConfig
-	client *http.Client
+	config       *config.DroneWeatherConfig
+	client       *http.Client
+	retryBackoff time.Duration // pause before the first retry, doubled after each
 }
 
+// weatherRetryBackoff is the initial pause between weather fetch attempts
+const weatherRetryBackoff = 2 * time.Second
+
 // OpenMeteoResponse represents the response from Open-Meteo API
 type OpenMeteoResponse struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
 	Timezone  string  `json:"timezone"`
 	Current   struct {
-		Time          string  `json:"time"`
-		Temperature   float64 `json:"temperature_2m"`
-		WindSpeed     float64 `json:"wind_speed_10m"`
-		WindDirection int     `json:"wind_direction_10m"`
-		Visibility    float64 `json:"visibility"`
-		Precipitation float64 `json:"precipitation"`
+		Time        string  `json:"time"`
+		Temperature float64 `json:"temperature_2m"`
+		// Pointer so a missing value falls back to a computed wind chill
+		ApparentTemperature *float64 `json:"apparent_temperature"`
+		WindSpeed           float64  `json:"wind_speed_10m"`
+		WindDirection       int      `json:"wind_direction_10m"`
+		Visibility          float64  `json:"visibility"`
+		Precipitation       float64  `json:"precipitation"`
 	} `json:"current"`
 	Hourly struct {
 		Time      []string  `json:"time"`
 		WindSpeed []float64 `json:"wind_speed_10m"`
 		WindGusts []float64 `json:"wind_gusts_10m"`
+		// Percent; optional, so older or partial responses still parse
+		PrecipProbability []float64 `json:"precipitation_probability"`
 	} `json:"hourly"`
+	// Daily is only requested when forecast_days is set
+	Daily struct {
+		Time                 []string  `json:"time"`
+		WindSpeedMax         []float64 `json:"wind_speed_10m_max"`
+		WindGustsMax         []float64 `json:"wind_gusts_10m_max"`
+		TemperatureMin       []float64 `json:"temperature_2m_min"`
+		TemperatureMax       []float64 `json:"temperature_2m_max"`
+		PrecipitationSum     []float64 `json:"precipitation_sum"`
+		PrecipProbabilityMax []float64 `json:"precipitation_probability_max"`
+	} `json:"daily"`
+	// Used when Timezone is not in the local tz database; a pointer so a
+	// missing offset can be told apart from UTC
+	UTCOffsetSeconds     *int   `json:"utc_offset_seconds"`
+	TimezoneAbbreviation string `json:"timezone_abbreviation"`
 }
 
 func NewWeatherClient(cfg *config.DroneWeatherConfig) *WeatherClient {
 	return &WeatherClient{
-		config: cfg,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		config:       cfg,
+		client:       httpclient.New(httpTimeout(cfg), cfg.UserAgent),
+		retryBackoff: weatherRetryBackoff,
 	}
 }
 
-// GetCurrentWeather fetches current weather data from Open-Meteo API
+// GetCurrentWeather fetches current weather data from Open-Meteo API. Values
+// are always returned in km/h and Celsius regardless of the requested units.
 func (w *WeatherClient) GetCurrentWeather(ctx context.Context, lat, lon float64) (*models.WeatherData, error) {
-	url := fmt.Sprintf("%s?latitude=%.4f&longitude=%.4f&current=temperature_2m,wind_speed_10m,wind_direction_10m,visibility,precipitation&hourly=wind_speed_10m,wind_gusts_10m&wind_speed_unit=kmh&temperature_unit=celsius&timezone=auto&forecast_hours=24",
-		w.config.WeatherURL, lat, lon)
+	imperial := w.config.Units == config.UnitsImperial
+	windUnit, tempUnit := "kmh", "celsius"
+	if imperial {
+		windUnit, tempUnit = "mph", "fahrenheit"
+	}
+	url := fmt.Sprintf("%s?latitude=%.4f&longitude=%.4f&current=temperature_2m,apparent_temperature,wind_speed_10m,wind_direction_10m,visibility,precipitation&hourly=wind_speed_10m,wind_gusts_10m,precipitation_probability&wind_speed_unit=%s&temperature_unit=%s&timezone=auto&forecast_hours=%d",
+		w.config.WeatherURL, lat, lon, windUnit, tempUnit, w.forecastHours())
+	if days := w.config.ForecastDays; days > 0 {
+		// forecast_days counts today, which the outlook skips
+		url += fmt.Sprintf("&daily=wind_speed_10m_max,wind_gusts_10m_max,temperature_2m_min,temperature_2m_max,precipitation_sum,precipitation_probability_max&forecast_days=%d", days+1)
+	}
 
 	log.Printf("Fetching weather data from: %s", url)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	body, err := w.fetchWithRetry(ctx, url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create weather request: %w", err)
+		return nil, err
 	}
-
-	resp, err := w.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch weather data: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("weather API returned status %d", resp.StatusCode)
-	}
-
 	var apiResp OpenMeteoResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+	if err := json.Unmarshal(body, &apiResp); err != nil {
 		return nil, fmt.Errorf("failed to decode weather response: %w", err)
 	}
 
 	// Parse time with timezone
 	location, err := time.LoadLocation(apiResp.Timezone)
 	if err != nil {
-		log.Printf("Warning: Failed to load timezone %s, using UTC: %v", apiResp.Timezone, err)
-		location = time.UTC
+		location = fallbackLocation(&apiResp, lon)
+		log.Printf("Warning: Failed to load timezone %s, using fixed offset %s: %v", apiResp.Timezone, location, err)
 	}
 
 	parsedTime, err := time.ParseInLocation("2006-01-02T15:04", apiResp.Current.Time, location)
@@ -86,40 +109,222 @@ func (w *WeatherClient) GetCurrentWeather(ctx context.Context, lat, lon float64)
 		return nil, fmt.Errorf("failed to parse weather time: %w", err)
 	}
 
-	// Parse hourly data
-	var hourlyData *models.HourlyForecast
-	if len(apiResp.Hourly.Time) > 0 && len(apiResp.Hourly.WindSpeed) > 0 && len(apiResp.Hourly.WindGusts) > 0 {
-		hourlyData = &models.HourlyForecast{
-			Times:      make([]time.Time, len(apiResp.Hourly.Time)),
-			WindSpeeds: apiResp.Hourly.WindSpeed,
-			WindGusts:  apiResp.Hourly.WindGusts,
-		}
-
-		// Parse hourly timestamps
-		for i, timeStr := range apiResp.Hourly.Time {
-			parsedHourlyTime, err := time.ParseInLocation("2006-01-02T15:04", timeStr, location)
-			if err != nil {
-				log.Printf("Warning: Failed to parse hourly time %s: %v", timeStr, err)
-				continue
+	// Convert imperial responses back to the canonical units used by the analysis
+	if imperial {
+		apiResp.Current.Temperature = fahrenheitToCelsius(apiResp.Current.Temperature)
+		if apiResp.Current.ApparentTemperature != nil {
+			c := fahrenheitToCelsius(*apiResp.Current.ApparentTemperature)
+			apiResp.Current.ApparentTemperature = &c
+		}
+		apiResp.Current.WindSpeed = mphToKmh(apiResp.Current.WindSpeed)
+		for i := range apiResp.Hourly.WindSpeed {
+			apiResp.Hourly.WindSpeed[i] = mphToKmh(apiResp.Hourly.WindSpeed[i])
+		}
+		for i := range apiResp.Hourly.WindGusts {
+			apiResp.Hourly.WindGusts[i] = mphToKmh(apiResp.Hourly.WindGusts[i])
+		}
+		for _, speeds := range [][]float64{apiResp.Daily.WindSpeedMax, apiResp.Daily.WindGustsMax} {
+			for i := range speeds {
+				speeds[i] = mphToKmh(speeds[i])
 			}
-			hourlyData.Times[i] = parsedHourlyTime
 		}
+		for _, temps := range [][]float64{apiResp.Daily.TemperatureMin, apiResp.Daily.TemperatureMax} {
+			for i := range temps {
+				temps[i] = fahrenheitToCelsius(temps[i])
+			}
+		}
+	}
+
+	// Parse hourly data
+	hourlyData := parseHourlyForecast(apiResp.Hourly.Time, apiResp.Hourly.WindSpeed, apiResp.Hourly.WindGusts, apiResp.Hourly.PrecipProbability, location)
+
+	apparent := windChillC(apiResp.Current.Temperature, apiResp.Current.WindSpeed)
+	if apiResp.Current.ApparentTemperature != nil {
+		apparent = *apiResp.Current.ApparentTemperature
 	}
 
 	return &models.WeatherData{
-		Latitude:      apiResp.Latitude,
-		Longitude:     apiResp.Longitude,
-		Temperature:   apiResp.Current.Temperature,
-		WindSpeed:     apiResp.Current.WindSpeed, // Now in km/h from API
-		WindDir:       apiResp.Current.WindDirection,
-		Visibility:    apiResp.Current.Visibility / 1000, // Convert m to km
-		Precipitation: apiResp.Current.Precipitation,
-		Time:          parsedTime,
-		Timezone:      apiResp.Timezone,
-		HourlyData:    hourlyData,
+		Latitude:            apiResp.Latitude,
+		Longitude:           apiResp.Longitude,
+		Temperature:         apiResp.Current.Temperature,
+		ApparentTemperature: apparent,
+		WindSpeed:           apiResp.Current.WindSpeed, // km/h
+		WindDir:             apiResp.Current.WindDirection,
+		Visibility:          apiResp.Current.Visibility / 1000, // Convert m to km
+		Precipitation:       apiResp.Current.Precipitation,
+		Time:                parsedTime,
+		Timezone:            apiResp.Timezone,
+		HourlyData:          hourlyData,
+		DailyData:           parseDailyForecast(&apiResp, location),
+		RawResponse:         body,
 	}, nil
 }
 
+// fetchWithRetry fetches the forecast, retrying 5xx responses and network errors
+// such as timeouts with exponential backoff. 4xx responses are not retried.
+func (w *WeatherClient) fetchWithRetry(ctx context.Context, url string) ([]byte, error) {
+	backoff := w.retryBackoff
+	attempts := w.fetchAttempts()
+
+	for attempt := 1; ; attempt++ {
+		body, retryable, err := w.fetch(ctx, url)
+		if err == nil || !retryable || attempt >= attempts || ctx.Err() != nil {
+			return body, err
+		}
+
+		log.Printf("Weather fetch attempt %d/%d failed, retrying in %v: %v", attempt, attempts, backoff, err)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to fetch weather data: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// fetch performs a single forecast request, returning the response body, and reports
+// whether a failure is worth retrying
+func (w *WeatherClient) fetch(ctx context.Context, url string) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create weather request: %w", err)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to fetch weather data: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode >= 500, fmt.Errorf("weather API returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to read weather response: %w", err)
+	}
+	return body, false, nil
+}
+
+// fetchAttempts returns the configured number of weather fetch attempts, defaulting to 3
+func (w *WeatherClient) fetchAttempts() int {
+	if w.config.WeatherFetchAttempts > 0 {
+		return w.config.WeatherFetchAttempts
+	}
+	return 3
+}
+
+// httpTimeout returns the configured per-request timeout for weather and TFR calls
+func httpTimeout(cfg *config.DroneWeatherConfig) time.Duration {
+	if cfg.HTTPTimeoutSeconds > 0 {
+		return time.Duration(cfg.HTTPTimeoutSeconds) * time.Second
+	}
+	return 30 * time.Second
+}
+
+// forecastHours returns the configured forecast horizon, defaulting to 24 hours
+func (w *WeatherClient) forecastHours() int {
+	if w.config.ForecastHours > 0 {
+		return w.config.ForecastHours
+	}
+	return 24
+}
+
+// fallbackLocation approximates local time when the API's timezone is unknown to the
+// local tz database: the response's UTC offset when present, otherwise the nominal
+// offset of the longitude (15° per hour). Both are fixed, so DST changes within the
+// forecast horizon are not reflected.
+func fallbackLocation(apiResp *OpenMeteoResponse, lon float64) *time.Location {
+	name := apiResp.TimezoneAbbreviation
+	if apiResp.UTCOffsetSeconds != nil {
+		if name == "" {
+			name = apiResp.Timezone
+		}
+		return time.FixedZone(name, *apiResp.UTCOffsetSeconds)
+	}
+	hours := int(math.Round(normalizeLongitude(lon) / 15))
+	return time.FixedZone(fmt.Sprintf("UTC%+d", hours), hours*3600)
+}
+
+// parseHourlyForecast builds an hourly forecast from Open-Meteo's parallel arrays.
+// Mismatched lengths are truncated to the shortest array and entries with
+// unparseable times are dropped, so every index refers to a complete hour.
+// Precipitation probabilities are optional and omitted unless they cover every hour.
+func parseHourlyForecast(times []string, windSpeeds, windGusts, precipProbabilities []float64, location *time.Location) *models.HourlyForecast {
+	n := min(len(times), len(windSpeeds), len(windGusts))
+	if n != len(times) || n != len(windSpeeds) || n != len(windGusts) {
+		log.Printf("Warning: Hourly forecast arrays have mismatched lengths (time=%d, wind_speed=%d, wind_gusts=%d), truncating to %d",
+			len(times), len(windSpeeds), len(windGusts), n)
+	}
+	if n == 0 {
+		return nil
+	}
+	hasPrecip := len(precipProbabilities) >= n
+	if len(precipProbabilities) > 0 && !hasPrecip {
+		log.Printf("Warning: Hourly precipitation probability covers %d of %d hours, ignoring it", len(precipProbabilities), n)
+	}
+
+	forecast := &models.HourlyForecast{
+		Times:      make([]time.Time, 0, n),
+		WindSpeeds: make([]float64, 0, n),
+		WindGusts:  make([]float64, 0, n),
+	}
+	for i := 0; i < n; i++ {
+		parsedHourlyTime, err := time.ParseInLocation("2006-01-02T15:04", times[i], location)
+		if err != nil {
+			log.Printf("Warning: Failed to parse hourly time %s: %v", times[i], err)
+			continue
+		}
+		forecast.Times = append(forecast.Times, parsedHourlyTime)
+		forecast.WindSpeeds = append(forecast.WindSpeeds, windSpeeds[i])
+		forecast.WindGusts = append(forecast.WindGusts, windGusts[i])
+		if hasPrecip {
+			forecast.PrecipProbabilities = append(forecast.PrecipProbabilities, precipProbabilities[i])
+		}
+	}
+
+	if len(forecast.Times) == 0 {
+		return nil
+	}
+	return forecast
+}
+
+// parseDailyForecast builds the daily outlook from Open-Meteo's parallel daily arrays,
+// truncated to the shortest required array. Gusts and precipitation probability are
+// optional and left at zero when missing.
+func parseDailyForecast(apiResp *OpenMeteoResponse, location *time.Location) []models.DailyForecast {
+	daily := apiResp.Daily
+	n := min(len(daily.Time), len(daily.WindSpeedMax), len(daily.TemperatureMin), len(daily.TemperatureMax), len(daily.PrecipitationSum))
+	if n != len(daily.Time) {
+		log.Printf("Warning: Daily forecast arrays have mismatched lengths, truncating to %d days", n)
+	}
+
+	var days []models.DailyForecast
+	for i := 0; i < n; i++ {
+		date, err := time.ParseInLocation("2006-01-02", daily.Time[i], location)
+		if err != nil {
+			log.Printf("Warning: Failed to parse daily time %s: %v", daily.Time[i], err)
+			continue
+		}
+		day := models.DailyForecast{
+			Date:            date,
+			MaxWindKmh:      daily.WindSpeedMax[i],
+			MinTempC:        daily.TemperatureMin[i],
+			MaxTempC:        daily.TemperatureMax[i],
+			PrecipitationMm: daily.PrecipitationSum[i],
+		}
+		if i < len(daily.WindGustsMax) {
+			day.MaxGustsKmh = daily.WindGustsMax[i]
+		}
+		if i < len(daily.PrecipProbabilityMax) {
+			day.PrecipProbabilityPct = daily.PrecipProbabilityMax[i]
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
 // AnalyzeWeatherConditions analyzes weather data against flying thresholds
 func (w *WeatherClient) AnalyzeWeatherConditions(data *models.WeatherData) *models.WeatherAnalysis {
 	analysis := &models.WeatherAnalysis{
@@ -129,53 +334,118 @@ func (w *WeatherClient) AnalyzeWeatherConditions(data *models.WeatherData) *mode
 		WindForecast: "Light and stable through afternoon", // Simplified forecast
 	}
 
-	// Calculate average wind values from hourly data
+	// Calculate average wind values from hourly data, limited to the
+	// configured horizon in case the API returned more hours than requested
 	if data.HourlyData != nil && len(data.HourlyData.WindSpeeds) > 0 {
+		hours := min(len(data.HourlyData.WindSpeeds), w.forecastHours())
+
 		// Calculate average wind speed
 		var totalWindSpeed float64
-		for _, speed := range data.HourlyData.WindSpeeds {
+		for _, speed := range data.HourlyData.WindSpeeds[:hours] {
 			totalWindSpeed += speed
 		}
-		analysis.AvgWindSpeedKmh = totalWindSpeed / float64(len(data.HourlyData.WindSpeeds))
+		analysis.AvgWindSpeedKmh = totalWindSpeed / float64(hours)
+		analysis.AvgWindHours = hours
 
 		// Calculate average wind gusts
-		if len(data.HourlyData.WindGusts) > 0 {
+		if gusts := data.HourlyData.WindGusts[:min(len(data.HourlyData.WindGusts), hours)]; len(gusts) > 0 {
 			var totalGusts float64
-			for _, gust := range data.HourlyData.WindGusts {
+			for _, gust := range gusts {
 				totalGusts += gust
 			}
-			analysis.AvgWindGustsKmh = totalGusts / float64(len(data.HourlyData.WindGusts))
+			analysis.AvgWindGustsKmh = totalGusts / float64(len(gusts))
 		}
 	}
 
+	analysis.BestWindow = w.bestWindow(data.HourlyData)
+
+	// Passing values within this fraction of a limit put the day in the caution tier
+	margin := w.cautionMargin()
+
 	// Check wind speed
-	if data.WindSpeed > float64(w.config.MaxWindSpeedKmh) {
+	maxWind := float64(w.config.MaxWindSpeedKmh)
+	if data.WindSpeed > maxWind {
 		analysis.IsFlyable = false
 		analysis.Reasons = append(analysis.Reasons, fmt.Sprintf("Wind speed too high: %.1f km/h (max: %d km/h)", data.WindSpeed, w.config.MaxWindSpeedKmh))
+	} else if margin > 0 && data.WindSpeed > maxWind*(1-margin) {
+		analysis.Cautions = append(analysis.Cautions, fmt.Sprintf("Wind %.1f km/h, close to %d km/h limit", data.WindSpeed, w.config.MaxWindSpeedKmh))
+	} else if data.WindSpeed <= maxWind*0.6 {
+		analysis.Highlights = append(analysis.Highlights, fmt.Sprintf("Wind %.1f km/h, well under %d km/h limit", data.WindSpeed, w.config.MaxWindSpeedKmh))
+	} else {
+		analysis.Highlights = append(analysis.Highlights, fmt.Sprintf("Wind %.1f km/h, under %d km/h limit", data.WindSpeed, w.config.MaxWindSpeedKmh))
 	}
 
 	// Check visibility
 	if data.Visibility < float64(w.config.MinVisibilityKm) {
 		analysis.IsFlyable = false
 		analysis.Reasons = append(analysis.Reasons, fmt.Sprintf("Visibility too low: %.1f km (min: %d km)", data.Visibility, w.config.MinVisibilityKm))
+	} else if margin > 0 && data.Visibility < float64(w.config.MinVisibilityKm)*(1+margin) {
+		analysis.Cautions = append(analysis.Cautions, fmt.Sprintf("Visibility %.1f km, close to %d km minimum", data.Visibility, w.config.MinVisibilityKm))
+	} else {
+		analysis.Highlights = append(analysis.Highlights, fmt.Sprintf("Visibility %.1f km, above %d km minimum", data.Visibility, w.config.MinVisibilityKm))
 	}
 
 	// Check precipitation
 	if data.Precipitation > w.config.MaxPrecipitationMm {
 		analysis.IsFlyable = false
 		analysis.Reasons = append(analysis.Reasons, fmt.Sprintf("Precipitation present: %.1f mm (max: %.1f mm)", data.Precipitation, w.config.MaxPrecipitationMm))
+	} else if margin > 0 && data.Precipitation > 0 && data.Precipitation > w.config.MaxPrecipitationMm*(1-margin) {
+		analysis.Cautions = append(analysis.Cautions, fmt.Sprintf("Precipitation %.1f mm, close to %.1f mm limit", data.Precipitation, w.config.MaxPrecipitationMm))
+	} else if data.Precipitation == 0 {
+		analysis.Highlights = append(analysis.Highlights, "No precipitation")
+	} else {
+		analysis.Highlights = append(analysis.Highlights, fmt.Sprintf("Precipitation %.1f mm, within %.1f mm limit", data.Precipitation, w.config.MaxPrecipitationMm))
+	}
+
+	// Check the chance of precipitation over the forecast horizon, since dry
+	// conditions now don't help if rain is likely before the flight ends
+	if data.HourlyData != nil && len(data.HourlyData.PrecipProbabilities) > 0 {
+		hours := min(len(data.HourlyData.PrecipProbabilities), w.forecastHours())
+		for _, probability := range data.HourlyData.PrecipProbabilities[:hours] {
+			analysis.MaxPrecipProbabilityPct = math.Max(analysis.MaxPrecipProbabilityPct, probability)
+		}
+		analysis.PrecipProbabilityHours = hours
+
+		if limit := w.config.MaxPrecipProbabilityPct; limit > 0 {
+			if analysis.MaxPrecipProbabilityPct > float64(limit) {
+				analysis.IsFlyable = false
+				analysis.Reasons = append(analysis.Reasons, fmt.Sprintf("Precipitation likely: up to %.0f%% chance in the next %dh (max: %d%%)", analysis.MaxPrecipProbabilityPct, hours, limit))
+			} else if margin > 0 && analysis.MaxPrecipProbabilityPct > float64(limit)*(1-margin) {
+				analysis.Cautions = append(analysis.Cautions, fmt.Sprintf("Chance of precipitation up to %.0f%%, close to %d%% limit", analysis.MaxPrecipProbabilityPct, limit))
+			} else {
+				analysis.Highlights = append(analysis.Highlights, fmt.Sprintf("Chance of precipitation up to %.0f%%, under %d%% limit", analysis.MaxPrecipProbabilityPct, limit))
+			}
+		}
 	}
 
-	// Check temperature (use Celsius for comparisons)
-	if data.Temperature < w.config.MinTempC {
+	// Check temperature (use Celsius for comparisons). The minimum can be checked
+	// against the feels-like temperature since wind chill drains batteries faster.
+	minTemp, minTempLabel := data.Temperature, "Temperature"
+	if w.config.TempComparison == config.TempComparisonApparent {
+		minTemp, minTempLabel = data.ApparentTemperature, "Feels-like temperature"
+	}
+	tempOK := true
+	if minTemp < w.config.MinTempC {
+		tempOK = false
 		analysis.IsFlyable = false
-		analysis.Reasons = append(analysis.Reasons, fmt.Sprintf("Temperature too low: %.1f°C (min: %.1f°C)", data.Temperature, w.config.MinTempC))
+		analysis.Reasons = append(analysis.Reasons, fmt.Sprintf("%s too low: %.1f°C (min: %.1f°C)", minTempLabel, minTemp, w.config.MinTempC))
 	}
 
 	if data.Temperature > w.config.MaxTempC {
+		tempOK = false
 		analysis.IsFlyable = false
 		analysis.Reasons = append(analysis.Reasons, fmt.Sprintf("Temperature too high: %.1f°C (max: %.1f°C)", data.Temperature, w.config.MaxTempC))
 	}
+	// The temperature caution band is a fraction of the allowed range, since a
+	// percentage of a Celsius value near zero is meaningless
+	tempBand := (w.config.MaxTempC - w.config.MinTempC) * margin
+	if tempOK && margin > 0 && minTemp < w.config.MinTempC+tempBand {
+		analysis.Cautions = append(analysis.Cautions, fmt.Sprintf("%s %.1f°C, close to %.1f°C minimum", minTempLabel, minTemp, w.config.MinTempC))
+	} else if tempOK && margin > 0 && data.Temperature > w.config.MaxTempC-tempBand {
+		analysis.Cautions = append(analysis.Cautions, fmt.Sprintf("Temperature %.1f°C, close to %.1f°C maximum", data.Temperature, w.config.MaxTempC))
+	} else if tempOK {
+		analysis.Highlights = append(analysis.Highlights, fmt.Sprintf("%s %.1f°C, within %.1f-%.1f°C range", minTempLabel, minTemp, w.config.MinTempC, w.config.MaxTempC))
+	}
 
 	// Update wind forecast based on conditions (using km/h)
 	if data.WindSpeed < 8 { // ~5 mph
@@ -188,5 +458,117 @@ func (w *WeatherClient) AnalyzeWeatherConditions(data *models.WeatherData) *mode
 		analysis.WindForecast = "Strong winds, challenging conditions"
 	}
 
+	if w.config.ForecastDays > 0 {
+		analysis.NextFlyableDays, analysis.OutlookDays = w.nextFlyableDays(data)
+	}
+
+	switch {
+	case !analysis.IsFlyable:
+		analysis.Tier = models.TierNoGo
+	case len(analysis.Cautions) > 0:
+		analysis.Tier = models.TierCaution
+	default:
+		analysis.Tier = models.TierGood
+	}
+
 	return analysis
 }
+
+// nextFlyableDays evaluates each forecast day after today against the thresholds
+// using daily aggregates, returning the flyable days and how many were evaluated.
+// Wind is judged by the day's maximum and temperature by its high, since flights
+// happen during the day; visibility has no daily aggregate and is not checked.
+func (w *WeatherClient) nextFlyableDays(data *models.WeatherData) ([]models.DailyForecast, int) {
+	today := data.Time.Format("2006-01-02")
+	var flyable []models.DailyForecast
+	evaluated := 0
+	for _, day := range data.DailyData {
+		if day.Date.Format("2006-01-02") <= today {
+			continue
+		}
+		if evaluated == w.config.ForecastDays {
+			break
+		}
+		evaluated++
+
+		if day.MaxWindKmh > float64(w.config.MaxWindSpeedKmh) ||
+			day.PrecipitationMm > w.config.MaxPrecipitationMm ||
+			day.MaxTempC < w.config.MinTempC || day.MaxTempC > w.config.MaxTempC {
+			continue
+		}
+		if limit := w.config.MaxPrecipProbabilityPct; limit > 0 && day.PrecipProbabilityPct > float64(limit) {
+			continue
+		}
+		flyable = append(flyable, day)
+	}
+	return flyable, evaluated
+}
+
+// cautionMargin returns the configured caution band as a fraction of each limit;
+// zero disables the caution tier
+func (w *WeatherClient) cautionMargin() float64 {
+	if w.config.CautionMarginPct <= 0 {
+		return 0
+	}
+	return float64(w.config.CautionMarginPct) / 100
+}
+
+// bestWindow finds the longest contiguous run of forecast hours with wind at or under
+// the limit, within the forecast horizon. Runs shorter than MinWindowMinutes are
+// ignored; since data is hourly each good hour counts as 60 minutes.
+func (w *WeatherClient) bestWindow(hourly *models.HourlyForecast) *models.FlightWindow {
+	if hourly == nil {
+		return nil
+	}
+
+	maxWind := float64(w.config.MaxWindSpeedKmh)
+	hours := min(len(hourly.Times), len(hourly.WindSpeeds), w.forecastHours())
+	bestStart, bestLen := -1, 0
+	for i := 0; i < hours; {
+		if hourly.WindSpeeds[i] > maxWind {
+			i++
+			continue
+		}
+		start := i
+		for i < hours && hourly.WindSpeeds[i] <= maxWind {
+			i++
+		}
+		if i-start > bestLen {
+			bestStart, bestLen = start, i-start
+		}
+	}
+
+	if bestStart < 0 || time.Duration(bestLen)*time.Hour < time.Duration(w.config.MinWindowMinutes)*time.Minute {
+		return nil
+	}
+
+	window := &models.FlightWindow{
+		Start: hourly.Times[bestStart],
+		End:   hourly.Times[bestStart+bestLen-1].Add(time.Hour),
+	}
+	for _, speed := range hourly.WindSpeeds[bestStart : bestStart+bestLen] {
+		window.MaxWindKmh = math.Max(window.MaxWindKmh, speed)
+	}
+	return window
+}
+
+// fahrenheitToCelsius converts a temperature from Fahrenheit to Celsius
+func fahrenheitToCelsius(f float64) float64 {
+	return (f - 32) * 5 / 9
+}
+
+// mphToKmh converts a speed from miles per hour to kilometres per hour
+func mphToKmh(mph float64) float64 {
+	return mph * 1.609344
+}
+
+// windChillC computes the wind chill temperature in Celsius using the
+// North American (Environment Canada / NWS) formula. Outside its valid range
+// (above 10°C or winds of 4.8 km/h or less) the air temperature is returned.
+func windChillC(tempC, windKmh float64) float64 {
+	if tempC > 10 || windKmh <= 4.8 {
+		return tempC
+	}
+	v := math.Pow(windKmh, 0.16)
+	return 13.12 + 0.6215*tempC - 11.37*v + 0.3965*tempC*v
+}
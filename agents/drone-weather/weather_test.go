@@ -1,6 +1,13 @@
 package droneweather
 
 import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -202,3 +209,740 @@ func TestWindForecastGeneration(t *testing.T) {
 		})
 	}
 }
+
+// newSlowServer returns a server that only responds once the client goes away
+func newSlowServer(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func TestGetCurrentWeatherRespectsContextDeadline(t *testing.T) {
+	server, _ := newSlowServer(t)
+	client := NewWeatherClient(&config.DroneWeatherConfig{WeatherURL: server.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetCurrentWeather(ctx, 40.0, -74.0)
+	if err == nil {
+		t.Fatal("Expected error when context deadline is exceeded")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Request was not aborted promptly (took %v)", elapsed)
+	}
+}
+
+func TestCheckTFRsRespectsContextCancellation(t *testing.T) {
+	server, hits := newSlowServer(t)
+	client := NewTFRClient(&config.DroneWeatherConfig{
+		SearchRadiusMiles: 25,
+		TFRURLs:           []string{server.URL + "/first", server.URL + "/second"},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.CheckTFRs(ctx, 40.0, -74.0)
+	if err == nil {
+		t.Fatal("Expected error when context is cancelled")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Request was not aborted promptly (took %v)", elapsed)
+	}
+	if n := atomic.LoadInt32(hits); n != 1 {
+		t.Errorf("Expected fallback endpoints to be skipped after cancellation, got %d requests", n)
+	}
+}
+
+func TestWindChillC(t *testing.T) {
+	tests := []struct {
+		name     string
+		tempC    float64
+		windKmh  float64
+		expected float64
+	}{
+		{"Cold and windy", 5, 30, 0.1},
+		{"Freezing with strong wind", -10, 40, -20.8},
+		{"Warm air has no wind chill", 15, 30, 15},
+		{"Calm air has no wind chill", 5, 3, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := windChillC(tt.tempC, tt.windKmh)
+			if math.Abs(got-tt.expected) > 0.1 {
+				t.Errorf("windChillC(%.1f, %.1f) = %.2f, want %.1f", tt.tempC, tt.windKmh, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTempComparisonMode(t *testing.T) {
+	// 6°C actual but 1°C feels-like, against a 4.4°C minimum
+	weather := &models.WeatherData{
+		Temperature:         6,
+		ApparentTemperature: 1,
+		WindSpeed:           20,
+		Visibility:          10,
+		Time:                time.Now(),
+	}
+
+	tests := []struct {
+		mode          string
+		expectFlyable bool
+	}{
+		{config.TempComparisonActual, true},
+		{config.TempComparisonApparent, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			client := &WeatherClient{config: &config.DroneWeatherConfig{
+				MaxWindSpeedKmh: 25,
+				MinVisibilityKm: 5,
+				MinTempC:        4.4,
+				MaxTempC:        35,
+				TempComparison:  tt.mode,
+			}}
+
+			analysis := client.AnalyzeWeatherConditions(weather)
+			if analysis.IsFlyable != tt.expectFlyable {
+				t.Errorf("Expected flyable=%t with %s comparison, got %t (reasons: %v)", tt.expectFlyable, tt.mode, analysis.IsFlyable, analysis.Reasons)
+			}
+		})
+	}
+}
+
+func TestGetCurrentWeatherApparentTemperature(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected float64
+	}{
+		{
+			name:     "Uses API apparent temperature",
+			body:     `{"timezone":"UTC","current":{"time":"2025-01-01T12:00","temperature_2m":5,"apparent_temperature":-1.5,"wind_speed_10m":30}}`,
+			expected: -1.5,
+		},
+		{
+			name:     "Computes wind chill when missing",
+			body:     `{"timezone":"UTC","current":{"time":"2025-01-01T12:00","temperature_2m":5,"wind_speed_10m":30}}`,
+			expected: windChillC(5, 30),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := NewWeatherClient(&config.DroneWeatherConfig{WeatherURL: server.URL})
+			data, err := client.GetCurrentWeather(context.Background(), 40, -74)
+			if err != nil {
+				t.Fatalf("GetCurrentWeather() error: %v", err)
+			}
+			if math.Abs(data.ApparentTemperature-tt.expected) > 0.001 {
+				t.Errorf("ApparentTemperature = %.2f, want %.2f", data.ApparentTemperature, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetCurrentWeatherMismatchedHourlyArrays(t *testing.T) {
+	tests := []struct {
+		name        string
+		hourly      string
+		expectHours int
+	}{
+		{
+			name:        "Fewer gusts than times",
+			hourly:      `{"time":["2025-01-01T12:00","2025-01-01T13:00","2025-01-01T14:00"],"wind_speed_10m":[10,12,14],"wind_gusts_10m":[20]}`,
+			expectHours: 1,
+		},
+		{
+			name:        "Fewer times than speeds",
+			hourly:      `{"time":["2025-01-01T12:00","2025-01-01T13:00"],"wind_speed_10m":[10,12,14],"wind_gusts_10m":[20,22,24]}`,
+			expectHours: 2,
+		},
+		{
+			name:        "Unparseable time is dropped",
+			hourly:      `{"time":["2025-01-01T12:00","garbage"],"wind_speed_10m":[10,12],"wind_gusts_10m":[20,22]}`,
+			expectHours: 1,
+		},
+		{
+			name:        "Empty arrays",
+			hourly:      `{"time":[],"wind_speed_10m":[],"wind_gusts_10m":[]}`,
+			expectHours: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"timezone":"UTC","current":{"time":"2025-01-01T12:00","temperature_2m":15,"wind_speed_10m":10},"hourly":` + tt.hourly + `}`
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(body))
+			}))
+			defer server.Close()
+
+			client := NewWeatherClient(&config.DroneWeatherConfig{WeatherURL: server.URL})
+			data, err := client.GetCurrentWeather(context.Background(), 40, -74)
+			if err != nil {
+				t.Fatalf("GetCurrentWeather() error: %v", err)
+			}
+
+			if tt.expectHours == 0 {
+				if data.HourlyData != nil {
+					t.Errorf("Expected no hourly data, got %d hours", len(data.HourlyData.Times))
+				}
+				return
+			}
+			if data.HourlyData == nil {
+				t.Fatal("Expected hourly data")
+			}
+			h := data.HourlyData
+			if len(h.Times) != tt.expectHours || len(h.WindSpeeds) != tt.expectHours || len(h.WindGusts) != tt.expectHours {
+				t.Errorf("Expected %d aligned hours, got times=%d speeds=%d gusts=%d",
+					tt.expectHours, len(h.Times), len(h.WindSpeeds), len(h.WindGusts))
+			}
+			for i, ts := range h.Times {
+				if ts.IsZero() {
+					t.Errorf("Hour %d has a zero time", i)
+				}
+			}
+		})
+	}
+}
+
+func TestGetCurrentWeatherForecastHours(t *testing.T) {
+	tests := []struct {
+		name          string
+		forecastHours int
+		expected      string
+	}{
+		{"Default horizon", 0, "24"},
+		{"Short horizon", 6, "6"},
+		{"Long horizon", 72, "72"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requested string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requested = r.URL.Query().Get("forecast_hours")
+				w.Write([]byte(`{"timezone":"UTC","current":{"time":"2025-01-01T12:00"}}`))
+			}))
+			defer server.Close()
+
+			client := NewWeatherClient(&config.DroneWeatherConfig{WeatherURL: server.URL, ForecastHours: tt.forecastHours})
+			if _, err := client.GetCurrentWeather(context.Background(), 40, -74); err != nil {
+				t.Fatalf("GetCurrentWeather() error: %v", err)
+			}
+			if requested != tt.expected {
+				t.Errorf("Expected forecast_hours=%s, got %q", tt.expected, requested)
+			}
+		})
+	}
+}
+
+func TestAverageWindLimitedToForecastHours(t *testing.T) {
+	client := NewWeatherClient(&config.DroneWeatherConfig{
+		MaxWindSpeedKmh: 25,
+		MinVisibilityKm: 5,
+		MinTempC:        4.4,
+		MaxTempC:        35,
+		ForecastHours:   2,
+	})
+
+	analysis := client.AnalyzeWeatherConditions(&models.WeatherData{
+		Temperature: 20,
+		Visibility:  10,
+		HourlyData: &models.HourlyForecast{
+			Times:      []time.Time{{}, {}, {}},
+			WindSpeeds: []float64{10, 20, 90},
+			WindGusts:  []float64{20, 30, 90},
+		},
+	})
+
+	if analysis.AvgWindHours != 2 {
+		t.Errorf("Expected 2 averaged hours, got %d", analysis.AvgWindHours)
+	}
+	if analysis.AvgWindSpeedKmh != 15 {
+		t.Errorf("Expected average wind 15 km/h, got %.1f", analysis.AvgWindSpeedKmh)
+	}
+	if analysis.AvgWindGustsKmh != 25 {
+		t.Errorf("Expected average gusts 25 km/h, got %.1f", analysis.AvgWindGustsKmh)
+	}
+}
+
+func TestGetCurrentWeatherUnits(t *testing.T) {
+	// The same conditions expressed in each unit system: 20°C / 68°F air,
+	// 30 km/h / 18.64 mph wind, which is over the 25 km/h limit
+	responses := map[string]string{
+		"kmh": `{"timezone":"UTC","current":{"time":"2025-01-01T12:00","temperature_2m":20,"apparent_temperature":20,"wind_speed_10m":30,"visibility":10000},
+			"hourly":{"time":["2025-01-01T12:00"],"wind_speed_10m":[30],"wind_gusts_10m":[40]}}`,
+		"mph": `{"timezone":"UTC","current":{"time":"2025-01-01T12:00","temperature_2m":68,"apparent_temperature":68,"wind_speed_10m":18.641,"visibility":10000},
+			"hourly":{"time":["2025-01-01T12:00"],"wind_speed_10m":[18.641],"wind_gusts_10m":[24.855]}}`,
+	}
+
+	tests := []struct {
+		units      string
+		expectWind string
+		expectTemp string
+	}{
+		{config.UnitsMetric, "kmh", "celsius"},
+		{config.UnitsImperial, "mph", "fahrenheit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.units, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query := r.URL.Query()
+				if query.Get("wind_speed_unit") != tt.expectWind || query.Get("temperature_unit") != tt.expectTemp {
+					t.Errorf("Expected %s/%s units, got %s/%s", tt.expectWind, tt.expectTemp,
+						query.Get("wind_speed_unit"), query.Get("temperature_unit"))
+				}
+				w.Write([]byte(responses[query.Get("wind_speed_unit")]))
+			}))
+			defer server.Close()
+
+			client := NewWeatherClient(&config.DroneWeatherConfig{
+				WeatherURL:      server.URL,
+				Units:           tt.units,
+				MaxWindSpeedKmh: 25,
+				MinVisibilityKm: 5,
+				MinTempC:        4.4,
+				MaxTempC:        35,
+			})
+			data, err := client.GetCurrentWeather(context.Background(), 40, -74)
+			if err != nil {
+				t.Fatalf("GetCurrentWeather() error: %v", err)
+			}
+
+			if math.Abs(data.Temperature-20) > 0.1 || math.Abs(data.ApparentTemperature-20) > 0.1 {
+				t.Errorf("Expected 20°C, got %.2f (apparent %.2f)", data.Temperature, data.ApparentTemperature)
+			}
+			if math.Abs(data.WindSpeed-30) > 0.1 {
+				t.Errorf("Expected 30 km/h wind, got %.2f", data.WindSpeed)
+			}
+			if math.Abs(data.HourlyData.WindGusts[0]-40) > 0.1 {
+				t.Errorf("Expected 40 km/h gusts, got %.2f", data.HourlyData.WindGusts[0])
+			}
+
+			analysis := client.AnalyzeWeatherConditions(data)
+			if analysis.IsFlyable {
+				t.Error("Expected wind over the limit to be not flyable in both unit systems")
+			}
+			if len(analysis.Reasons) != 1 {
+				t.Errorf("Expected only the wind reason, got %v", analysis.Reasons)
+			}
+		})
+	}
+}
+
+func TestOutgoingRequestsUseConfiguredUserAgent(t *testing.T) {
+	var weatherUA, tfrUA string
+	weatherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		weatherUA = r.Header.Get("User-Agent")
+		w.Write([]byte(`{"timezone":"UTC","current":{"time":"2025-01-01T12:00"}}`))
+	}))
+	defer weatherServer.Close()
+	tfrServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tfrUA = r.Header.Get("User-Agent")
+		w.Write([]byte(`{"type":"FeatureCollection","features":[]}`))
+	}))
+	defer tfrServer.Close()
+
+	cfg := &config.DroneWeatherConfig{
+		WeatherURL: weatherServer.URL,
+		TFRURL:     tfrServer.URL,
+		UserAgent:  "drone-check/1.0 (pilot@example.com)",
+	}
+	if _, err := NewWeatherClient(cfg).GetCurrentWeather(context.Background(), 40, -74); err != nil {
+		t.Fatalf("GetCurrentWeather() error: %v", err)
+	}
+	if _, err := NewTFRClient(cfg).CheckTFRs(context.Background(), 40, -74); err != nil {
+		t.Fatalf("CheckTFRs() error: %v", err)
+	}
+
+	if weatherUA != cfg.UserAgent {
+		t.Errorf("Expected weather User-Agent %q, got %q", cfg.UserAgent, weatherUA)
+	}
+	if tfrUA != cfg.UserAgent {
+		t.Errorf("Expected TFR User-Agent %q, got %q", cfg.UserAgent, tfrUA)
+	}
+}
+
+func TestBestWindowMinimumDuration(t *testing.T) {
+	start := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	hourly := func(speeds ...float64) *models.HourlyForecast {
+		forecast := &models.HourlyForecast{WindSpeeds: speeds, WindGusts: make([]float64, len(speeds))}
+		for i := range speeds {
+			forecast.Times = append(forecast.Times, start.Add(time.Duration(i)*time.Hour))
+		}
+		return forecast
+	}
+
+	tests := []struct {
+		name        string
+		minMinutes  int
+		forecast    *models.HourlyForecast
+		expectStart int // hour offset, -1 for no window
+		expectHours int
+	}{
+		{"Single good hour below a two hour minimum", 120, hourly(40, 10, 40, 40), -1, 0},
+		{"Three good hours meet a two hour minimum", 120, hourly(40, 10, 12, 15, 40), 1, 3},
+		{"Single good hour meets a 30 minute minimum", 30, hourly(40, 10, 40), 1, 1},
+		{"Longest run wins", 60, hourly(10, 40, 10, 12, 14, 40, 10, 10), 2, 3},
+		{"Whole horizon is good", 120, hourly(5, 6, 7), 0, 3},
+		{"No good hours", 60, hourly(40, 50), -1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewWeatherClient(&config.DroneWeatherConfig{
+				MaxWindSpeedKmh:  25,
+				MinWindowMinutes: tt.minMinutes,
+			})
+
+			window := client.bestWindow(tt.forecast)
+			if tt.expectStart < 0 {
+				if window != nil {
+					t.Errorf("Expected no window, got %v - %v", window.Start, window.End)
+				}
+				return
+			}
+			if window == nil {
+				t.Fatal("Expected a best window")
+			}
+			expectedStart := start.Add(time.Duration(tt.expectStart) * time.Hour)
+			if !window.Start.Equal(expectedStart) {
+				t.Errorf("Expected window to start at %v, got %v", expectedStart, window.Start)
+			}
+			if window.Duration() != time.Duration(tt.expectHours)*time.Hour {
+				t.Errorf("Expected %dh window, got %v", tt.expectHours, window.Duration())
+			}
+		})
+	}
+}
+
+func TestPrecipitationProbability(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         int
+		probabilities []float64
+		expectFlyable bool
+		expectMax     float64
+	}{
+		{"Rain likely later despite dry now", 50, []float64{0, 10, 80, 90}, false, 90},
+		{"Low chance all day", 50, []float64{0, 10, 20, 30}, true, 30},
+		{"Check disabled still reports the chance", 0, []float64{0, 80}, true, 80},
+		{"Probability beyond the horizon is ignored", 50, []float64{0, 10, 20, 30, 95}, true, 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewWeatherClient(&config.DroneWeatherConfig{
+				MaxWindSpeedKmh:         25,
+				MinVisibilityKm:         5,
+				MinTempC:                4.4,
+				MaxTempC:                35,
+				ForecastHours:           4,
+				MaxPrecipProbabilityPct: tt.limit,
+			})
+
+			n := len(tt.probabilities)
+			analysis := client.AnalyzeWeatherConditions(&models.WeatherData{
+				Temperature:   20,
+				Visibility:    10,
+				Precipitation: 0, // dry right now
+				HourlyData: &models.HourlyForecast{
+					Times:               make([]time.Time, n),
+					WindSpeeds:          make([]float64, n),
+					WindGusts:           make([]float64, n),
+					PrecipProbabilities: tt.probabilities,
+				},
+			})
+
+			if analysis.IsFlyable != tt.expectFlyable {
+				t.Errorf("Expected flyable=%t, got %t (reasons: %v)", tt.expectFlyable, analysis.IsFlyable, analysis.Reasons)
+			}
+			if analysis.MaxPrecipProbabilityPct != tt.expectMax {
+				t.Errorf("Expected max probability %.0f%%, got %.0f%%", tt.expectMax, analysis.MaxPrecipProbabilityPct)
+			}
+		})
+	}
+}
+
+func TestGetCurrentWeatherPrecipitationProbability(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hourly := r.URL.Query().Get("hourly"); !strings.Contains(hourly, "precipitation_probability") {
+			t.Errorf("Expected precipitation_probability in hourly params, got %q", hourly)
+		}
+		w.Write([]byte(`{"timezone":"UTC","current":{"time":"2025-01-01T12:00","precipitation":0},
+			"hourly":{"time":["2025-01-01T12:00","2025-01-01T13:00"],"wind_speed_10m":[5,5],"wind_gusts_10m":[8,8],"precipitation_probability":[20,85]}}`))
+	}))
+	defer server.Close()
+
+	client := NewWeatherClient(&config.DroneWeatherConfig{WeatherURL: server.URL})
+	data, err := client.GetCurrentWeather(context.Background(), 40, -74)
+	if err != nil {
+		t.Fatalf("GetCurrentWeather() error: %v", err)
+	}
+	if got := data.HourlyData.PrecipProbabilities; len(got) != 2 || got[1] != 85 {
+		t.Errorf("Expected hourly probabilities [20 85], got %v", got)
+	}
+}
+
+func TestCautionTier(t *testing.T) {
+	good := func() *models.WeatherData {
+		return &models.WeatherData{Temperature: 20, ApparentTemperature: 20, WindSpeed: 10, Visibility: 10}
+	}
+
+	tests := []struct {
+		name          string
+		margin        int
+		modify        func(d *models.WeatherData)
+		expectTier    models.FlightTier
+		expectFlyable bool
+	}{
+		{"Comfortable margins", 10, func(d *models.WeatherData) {}, models.TierGood, true},
+		{"Wind close to limit", 10, func(d *models.WeatherData) { d.WindSpeed = 23.5 }, models.TierCaution, true},
+		{"Visibility close to minimum", 10, func(d *models.WeatherData) { d.Visibility = 5.3 }, models.TierCaution, true},
+		{"Temperature close to minimum", 10, func(d *models.WeatherData) { d.Temperature = 6 }, models.TierCaution, true},
+		{"Temperature close to maximum", 10, func(d *models.WeatherData) { d.Temperature = 33 }, models.TierCaution, true},
+		{"Precipitation close to limit", 10, func(d *models.WeatherData) { d.Precipitation = 0.95 }, models.TierCaution, true},
+		{"Wind over limit", 10, func(d *models.WeatherData) { d.WindSpeed = 30 }, models.TierNoGo, false},
+		{"Caution disabled", -1, func(d *models.WeatherData) { d.WindSpeed = 24.9 }, models.TierGood, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewWeatherClient(&config.DroneWeatherConfig{
+				MaxWindSpeedKmh:    25,
+				MinVisibilityKm:    5,
+				MaxPrecipitationMm: 1,
+				MinTempC:           4.4,
+				MaxTempC:           35,
+				CautionMarginPct:   tt.margin,
+			})
+
+			data := good()
+			tt.modify(data)
+			analysis := client.AnalyzeWeatherConditions(data)
+
+			if analysis.Tier != tt.expectTier {
+				t.Errorf("Expected tier %s, got %s (cautions: %v, reasons: %v)", tt.expectTier, analysis.Tier, analysis.Cautions, analysis.Reasons)
+			}
+			if analysis.IsFlyable != tt.expectFlyable {
+				t.Errorf("Expected flyable=%t, got %t", tt.expectFlyable, analysis.IsFlyable)
+			}
+			if tt.expectTier == models.TierCaution && len(analysis.Cautions) != 1 {
+				t.Errorf("Expected exactly one caution, got %v", analysis.Cautions)
+			}
+		})
+	}
+}
+
+func TestGetCurrentWeatherRetries(t *testing.T) {
+	tests := []struct {
+		name           string
+		failures       int32
+		failStatus     int
+		expectRequests int32
+		expectErr      bool
+	}{
+		{"Succeeds after two 5xx responses", 2, http.StatusServiceUnavailable, 3, false},
+		{"Gives up after the configured attempts", 5, http.StatusBadGateway, 3, true},
+		{"Does not retry 4xx responses", 5, http.StatusBadRequest, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&requests, 1) <= tt.failures {
+					w.WriteHeader(tt.failStatus)
+					return
+				}
+				w.Write([]byte(`{"timezone":"UTC","current":{"time":"2025-01-01T12:00","temperature_2m":5,"wind_speed_10m":10}}`))
+			}))
+			defer server.Close()
+
+			client := NewWeatherClient(&config.DroneWeatherConfig{WeatherURL: server.URL, WeatherFetchAttempts: 3})
+			client.retryBackoff = time.Millisecond
+
+			_, err := client.GetCurrentWeather(context.Background(), 40, -74)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error=%v, got %v", tt.expectErr, err)
+			}
+			if got := atomic.LoadInt32(&requests); got != tt.expectRequests {
+				t.Errorf("Expected %d requests, got %d", tt.expectRequests, got)
+			}
+		})
+	}
+}
+
+func TestHTTPTimeoutIsConfigurable(t *testing.T) {
+	tests := []struct {
+		name     string
+		seconds  int
+		expected time.Duration
+	}{
+		{"Default", 0, 30 * time.Second},
+		{"Custom", 75, 75 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.DroneWeatherConfig{HTTPTimeoutSeconds: tt.seconds}
+			if got := NewWeatherClient(cfg).client.Timeout; got != tt.expected {
+				t.Errorf("Expected weather client timeout %v, got %v", tt.expected, got)
+			}
+			if got := NewTFRClient(cfg).client.Timeout; got != tt.expected {
+				t.Errorf("Expected TFR client timeout %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNextFlyableDays(t *testing.T) {
+	now := time.Date(2025, 6, 2, 9, 0, 0, 0, time.UTC) // Monday
+	day := func(offset int, wind, high, precip, chance float64) models.DailyForecast {
+		return models.DailyForecast{
+			Date:                 time.Date(2025, 6, 2+offset, 0, 0, 0, 0, time.UTC),
+			MaxWindKmh:           wind,
+			MinTempC:             high - 10,
+			MaxTempC:             high,
+			PrecipitationMm:      precip,
+			PrecipProbabilityPct: chance,
+		}
+	}
+	daily := []models.DailyForecast{
+		day(0, 10, 20, 0, 0),  // today is covered by current conditions
+		day(1, 40, 20, 0, 0),  // too windy
+		day(2, 15, 22, 0, 10), // flyable
+		day(3, 15, 22, 5, 90), // rain
+		day(4, 15, 2, 0, 0),   // too cold all day
+		day(5, 20, 25, 0, 60), // chance of rain over the limit
+		day(6, 12, 24, 0, 0),  // flyable, but beyond the outlook
+	}
+
+	client := NewWeatherClient(&config.DroneWeatherConfig{
+		MaxWindSpeedKmh:         25,
+		MinVisibilityKm:         5,
+		MaxPrecipitationMm:      0.5,
+		MinTempC:                4.4,
+		MaxTempC:                35,
+		MaxPrecipProbabilityPct: 50,
+		ForecastDays:            5,
+	})
+	analysis := client.AnalyzeWeatherConditions(&models.WeatherData{
+		Temperature: 20,
+		WindSpeed:   40, // not flyable right now
+		Visibility:  10,
+		Time:        now,
+		DailyData:   daily,
+	})
+
+	if analysis.IsFlyable {
+		t.Error("Expected current conditions to stay not flyable")
+	}
+	if analysis.OutlookDays != 5 {
+		t.Errorf("Expected 5 outlook days, got %d", analysis.OutlookDays)
+	}
+	if len(analysis.NextFlyableDays) != 1 || analysis.NextFlyableDays[0].Date.Weekday() != time.Wednesday {
+		t.Errorf("Expected only Wednesday to be flyable, got %+v", analysis.NextFlyableDays)
+	}
+
+	client.config.ForecastDays = 0
+	if analysis := client.AnalyzeWeatherConditions(&models.WeatherData{Time: now, DailyData: daily}); analysis.NextFlyableDays != nil || analysis.OutlookDays != 0 {
+		t.Errorf("Expected no outlook when forecast_days is disabled, got %d days", analysis.OutlookDays)
+	}
+}
+
+func TestGetCurrentWeatherDailyForecast(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("forecast_days"); got != "3" {
+			t.Errorf("Expected forecast_days=3 (today plus two), got %q", got)
+		}
+		if daily := r.URL.Query().Get("daily"); !strings.Contains(daily, "wind_speed_10m_max") {
+			t.Errorf("Expected daily wind aggregates to be requested, got %q", daily)
+		}
+		w.Write([]byte(`{"timezone":"UTC","current":{"time":"2025-06-02T09:00"},
+			"daily":{"time":["2025-06-02","2025-06-03","2025-06-04"],"wind_speed_10m_max":[10,12,14],
+			"temperature_2m_min":[50,50,50],"temperature_2m_max":[68,70,72],"precipitation_sum":[0,0,1.5]}}`))
+	}))
+	defer server.Close()
+
+	client := NewWeatherClient(&config.DroneWeatherConfig{WeatherURL: server.URL, ForecastDays: 2, Units: config.UnitsImperial})
+	data, err := client.GetCurrentWeather(context.Background(), 40, -74)
+	if err != nil {
+		t.Fatalf("GetCurrentWeather() error: %v", err)
+	}
+	if len(data.DailyData) != 3 {
+		t.Fatalf("Expected 3 days, got %d", len(data.DailyData))
+	}
+	last := data.DailyData[2]
+	if last.Date.Format("2006-01-02") != "2025-06-04" || math.Abs(last.MaxWindKmh-mphToKmh(14)) > 0.001 ||
+		math.Abs(last.MaxTempC-fahrenheitToCelsius(72)) > 0.001 || last.PrecipitationMm != 1.5 {
+		t.Errorf("Unexpected daily forecast %+v", last)
+	}
+}
+
+func TestGetCurrentWeatherUnknownTimezone(t *testing.T) {
+	tests := []struct {
+		name         string
+		offsetFields string
+		lon          float64
+		expectOffset int
+	}{
+		{"API offset", `"utc_offset_seconds":-25200,"timezone_abbreviation":"PDT",`, -122.4, -7 * 3600},
+		{"Offset from longitude", ``, -74.0, -5 * 3600},
+		{"Offset from longitude east of Greenwich", ``, 139.7, 9 * 3600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"timezone":"Mars/Olympus_Mons",` + tt.offsetFields + `"current":{"time":"2025-06-02T09:00"},
+					"hourly":{"time":["2025-06-02T09:00"],"wind_speed_10m":[5],"wind_gusts_10m":[8]}}`))
+			}))
+			defer server.Close()
+
+			client := NewWeatherClient(&config.DroneWeatherConfig{WeatherURL: server.URL})
+			data, err := client.GetCurrentWeather(context.Background(), 40, tt.lon)
+			if err != nil {
+				t.Fatalf("GetCurrentWeather() error: %v", err)
+			}
+
+			// Times stay local wall-clock times, just with an approximate offset
+			for _, parsed := range []time.Time{data.Time, data.HourlyData.Times[0]} {
+				if _, offset := parsed.Zone(); offset != tt.expectOffset {
+					t.Errorf("Expected offset %ds, got %ds", tt.expectOffset, offset)
+				}
+				if parsed.Hour() != 9 {
+					t.Errorf("Expected local hour 9, got %d", parsed.Hour())
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"agent-stack/agents/youtube-curator/youtube"
@@ -18,11 +21,21 @@ import (
 
 // YouTubeMetrics represents the metrics collected during a YouTube curation run
 type YouTubeMetrics struct {
+	// VideosFound counts videos returned by the crawl, before any filtering
 	VideosFound    int `json:"videos_found"`
 	Analyzed       int `json:"analyzed"`
 	Relevant       int `json:"relevant"`
 	Skipped        int `json:"skipped"`
 	AnalysisErrors int `json:"analysis_errors"`
+	Deferred       int `json:"deferred"` // over max_analysis_per_run, left for the next run
+	// Filtered counts videos dropped by the language and category allowlists before analysis
+	Filtered int `json:"filtered"`
+	// SkippedByKeyword counts videos dropped by exclude_keywords or include_keywords before analysis
+	SkippedByKeyword int `json:"skipped_by_keyword"`
+	// Duplicates counts relevant videos dropped as near-identical to a higher-scored one
+	Duplicates int `json:"duplicates"`
+	// LiveDeferred counts live streams and upcoming premieres left for a later run
+	LiveDeferred int `json:"live_deferred"`
 }
 
 // GetSummary implements the scheduler.Metrics interface
@@ -31,23 +44,85 @@ func (m YouTubeMetrics) GetSummary() string {
 		m.VideosFound, m.Analyzed, m.Relevant)
 }
 
+const (
+	// Backoff applied when Gemini reports quota exhaustion
+	quotaBackoffInitial = 30 * time.Second
+	quotaBackoffMax     = 5 * time.Minute
+	quotaMaxRetries     = 4
+)
+
+// reportedRetention is how long emailed video IDs are remembered so they are never
+// emailed twice; much longer than the analyzed-video retention
+const reportedRetention = 90 * 24 * time.Hour
+
+// deferredRetention is how long live streams and premieres are re-checked before
+// being given up on, e.g. after the stream was deleted
+const deferredRetention = 30 * 24 * time.Hour
+
+// runLockFile is the lease held in the data dir while a run is in progress
+const runLockFile = "curator_run.lock"
+
+// aiSelfTestTimeout bounds the startup AI self-test enabled by ai.self_test
+const aiSelfTestTimeout = 30 * time.Second
+
+// VideoSource provides videos to curate. *youtube.Client is the production implementation.
+type VideoSource interface {
+	GetSubscriptionVideos(ctx context.Context, maxResults int64) ([]*models.Video, error)
+	GetVideosByID(ctx context.Context, ids []string) ([]*models.Video, error)
+	RefreshToken() error
+}
+
+var _ VideoSource = (*youtube.Client)(nil)
+
+// Analyzer scores a single video against the configured guidelines.
+// *ai.Analyzer is the production implementation.
+type Analyzer interface {
+	AnalyzeVideo(ctx context.Context, video *models.Video) (*models.Analysis, error)
+}
+
+var _ Analyzer = (*ai.Analyzer)(nil)
+
 // YouTubeAgent implements the scheduler.Agent interface
 type YouTubeAgent struct {
-	config             *config.Config
-	youtubeClient      *youtube.Client
-	analyzer           *ai.Analyzer
-	emailSender        *email.Sender
-	videoTracker       *storage.VideoTracker
+	config        *config.Config
+	youtubeClient VideoSource
+	analyzer      Analyzer
+	emailSender   email.EmailSender
+	emailObserver email.SendObserver // set once by the scheduler, before Initialize
+	videoTracker  storage.Tracker
+	digestStore   *storage.DigestStore
+	reportedStore *storage.ReportedStore
+	deferredStore *storage.DeferredStore
+	runLock       *storage.RunLock // nil unless run_lock_minutes is set
+	now           func() time.Time
+
+	// tokenRefreshMu makes starting and stopping the background refresher atomic
+	tokenRefreshMu     sync.Mutex
 	tokenRefreshTicker *time.Ticker
 	tokenRefreshStop   chan bool
+	tokenRefreshDone   chan struct{} // closed when the refresher goroutine exits
 }
 
 func NewYouTubeAgent(cfg *config.Config) *YouTubeAgent {
 	return &YouTubeAgent{
 		config: cfg,
+		now:    time.Now,
+	}
+}
+
+// ObserveEmail implements scheduler.EmailObserver for the SMTP sender
+func (y *YouTubeAgent) ObserveEmail(observer func(latency time.Duration, err error)) {
+	y.emailObserver = observer
+	if sender, ok := y.emailSender.(*email.Sender); ok {
+		sender.SetObserver(observer)
 	}
 }
 
+// VideoTracker implements scheduler.TrackerProvider, returning nil before Initialize
+func (y *YouTubeAgent) VideoTracker() storage.Tracker {
+	return y.videoTracker
+}
+
 func (y *YouTubeAgent) Name() string {
 	return "YouTube Curator"
 }
@@ -55,9 +130,26 @@ func (y *YouTubeAgent) GetSchedule() string {
 	return y.config.YouTubeCurator.Schedule
 }
 
+// newVideoTracker opens the analyzed-video tracker for the configured backend,
+// defaulting to the JSON file
+func newVideoTracker(backend, dataDir string, maxAge time.Duration) (storage.Tracker, error) {
+	if backend == config.TrackerBackendSQLite {
+		return storage.NewSQLiteTracker(dataDir, maxAge)
+	}
+	return storage.NewVideoTracker(dataDir, maxAge)
+}
+
 func (y *YouTubeAgent) Initialize() error {
 	log.Printf("Initializing %s...", y.Name())
 
+	dataDir := y.config.DataDir
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	if err := storage.EnsureWritableDir(dataDir); err != nil {
+		return fmt.Errorf("invalid data directory: %w", err)
+	}
+
 	if y.youtubeClient == nil {
 		client, err := youtube.NewClient(&y.config.YouTubeCurator.YouTube)
 		if err != nil {
@@ -78,43 +170,468 @@ func (y *YouTubeAgent) Initialize() error {
 		}
 		y.analyzer = analyzer
 		log.Println("AI analyzer initialized")
+
+		if y.config.YouTubeCurator.AI.SelfTest {
+			ctx, cancel := context.WithTimeout(context.Background(), aiSelfTestTimeout)
+			err := analyzer.Ping(ctx)
+			cancel()
+			if err != nil {
+				return err
+			}
+			log.Printf("AI self-test passed (model %s)", y.config.YouTubeCurator.AI.Model)
+		}
 	}
 
 	if y.emailSender == nil {
-		y.emailSender = email.NewSender(&y.config.Email)
+		sender := email.NewSender(&y.config.Email)
+		sender.SetLocation(y.config.Location())
+		sender.SetObserver(y.emailObserver)
+		if y.config.Timezone == "" {
+			log.Println("No timezone configured; email dates are shown in UTC")
+		}
+		y.emailSender = sender
 		log.Println("Email sender initialized")
 	}
 
 	if y.videoTracker == nil {
-		// Track videos for 7 days to avoid re-analyzing
-		tracker, err := storage.NewVideoTracker("data", 7*24*time.Hour)
+		// Remember analyzed videos for the retention window to avoid re-analyzing
+		retentionDays := y.config.YouTubeCurator.TrackerRetentionDays
+		if retentionDays <= 0 {
+			retentionDays = 7
+		}
+		tracker, err := newVideoTracker(y.config.YouTubeCurator.TrackerBackend, dataDir, time.Duration(retentionDays)*24*time.Hour)
 		if err != nil {
 			return fmt.Errorf("failed to create video tracker: %w", err)
 		}
+		// Videos analyzed under other guidelines or prompts are evaluated again
+		tracker.SetSchemaVersion(ai.SchemaVersion(y.config))
 		y.videoTracker = tracker
 		log.Printf("Video tracker initialized (%d videos tracked)", tracker.GetAnalyzedCount())
 	}
 
+	if y.digestStore == nil {
+		// Holds relevant videos until an email is delivered (or the digest day in digest mode)
+		store, err := storage.NewDigestStore(dataDir)
+		if err != nil {
+			return fmt.Errorf("failed to create pending report store: %w", err)
+		}
+		y.digestStore = store
+		pending, _ := store.Pending()
+		if y.config.YouTubeCurator.DigestMode {
+			log.Printf("Digest mode enabled, sending on %s (%d videos pending)", y.config.YouTubeCurator.DigestDay, len(pending))
+		} else if len(pending) > 0 {
+			log.Printf("%d relevant videos pending from a previous failed email", len(pending))
+		}
+	}
+
+	if y.reportedStore == nil {
+		store, err := storage.NewReportedStore(dataDir, reportedRetention)
+		if err != nil {
+			return fmt.Errorf("failed to create reported video store: %w", err)
+		}
+		y.reportedStore = store
+	}
+
+	if y.deferredStore == nil {
+		store, err := storage.NewDeferredStore(dataDir, deferredRetention)
+		if err != nil {
+			return fmt.Errorf("failed to create deferred video store: %w", err)
+		}
+		y.deferredStore = store
+	}
+
+	if y.runLock == nil && y.config.YouTubeCurator.RunLockMinutes > 0 {
+		lock, err := storage.NewRunLock(dataDir, runLockFile, time.Duration(y.config.YouTubeCurator.RunLockMinutes)*time.Minute)
+		if err != nil {
+			return fmt.Errorf("failed to create run lock: %w", err)
+		}
+		y.runLock = lock
+	}
+
 	return nil
 }
 
+// deliverReport emails relevant analyses. Selections are persisted before sending so a
+// failed email is retried on the next run without re-analysis. In digest mode they
+// accumulate until the digest day comes around.
+func (y *YouTubeAgent) deliverReport(relevant, mentions []*models.Analysis, analyzed int) error {
+	digestMode := y.config.YouTubeCurator.DigestMode
+
+	// Outside digest mode only runs with selections need remembering; mentions
+	// alone never trigger an email
+	if len(relevant) > 0 || (digestMode && analyzed > 0) {
+		if err := y.digestStore.Add(relevant, analyzed); err != nil {
+			return fmt.Errorf("failed to store pending report: %w", err)
+		}
+		if len(mentions) > 0 {
+			if err := y.digestStore.AddMentions(mentions); err != nil {
+				return fmt.Errorf("failed to store pending mentions: %w", err)
+			}
+		}
+	}
+
+	pending, total := y.digestStore.Pending()
+	pendingMentions := y.digestStore.Mentions()
+	if digestMode {
+		digestDay, err := config.ParseWeekday(y.config.YouTubeCurator.DigestDay)
+		if err != nil {
+			return fmt.Errorf("invalid digest day: %w", err)
+		}
+		now := y.now().In(y.config.Location())
+		if !digestDue(now, y.digestStore.LastSent(), digestDay) {
+			log.Printf("Digest mode: %d relevant videos pending until %s", len(pending), digestDay)
+			return nil
+		}
+		if now.Weekday() != digestDay {
+			log.Printf("Digest mode: catching up on the digest missed on %s", digestDay)
+		}
+		if len(pending) == 0 {
+			log.Println("Digest due but no relevant videos accumulated, skipping email")
+			return y.digestStore.MarkSent(now)
+		}
+	}
+	// Never email a video twice, e.g. after a restart between send and bookkeeping or
+	// when two instances analyzed the same video. Explicitly requested videos are always sent.
+	if len(y.config.YouTubeCurator.VideoIDs) == 0 {
+		pending = y.excludeReported(pending)
+		pendingMentions = y.excludeReported(pendingMentions)
+	}
+	if len(pending) == 0 && digestMode {
+		return y.digestStore.MarkSent(y.now())
+	}
+	if len(pending) == 0 {
+		return y.digestStore.Clear()
+	}
+	if retried := len(pending) - len(relevant); retried > 0 && !digestMode {
+		log.Printf("Including %d relevant videos from a previous run whose email failed", retried)
+	}
+
+	report := &models.EmailReport{
+		Date:           y.now(),
+		Videos:         pending,
+		Total:          total,
+		Selected:       len(pending),
+		Mentions:       pendingMentions,
+		GroupByChannel: y.config.YouTubeCurator.GroupByChannel,
+	}
+	if err := y.emailSender.SendReport(report, y.config.YouTubeCurator.SubjectTemplate); err != nil {
+		return err
+	}
+
+	if digestMode {
+		log.Printf("Sent weekly digest with %d videos", len(pending))
+	}
+
+	var reportedIDs []string
+	for _, analysis := range append(pending, pendingMentions...) {
+		reportedIDs = append(reportedIDs, analysis.Video.ID)
+	}
+	if err := y.reportedStore.MarkReported(reportedIDs); err != nil {
+		log.Printf("Warning: Failed to record reported videos: %v", err)
+	}
+	return y.digestStore.MarkSent(y.now())
+}
+
+// digestDue reports whether the weekly digest should go out at now: on the digest day,
+// or on a later day when the last digest predates the most recent digest day (e.g. no
+// run happened that day). A digest already sent today is not sent again.
+func digestDue(now, lastSent time.Time, digestDay time.Weekday) bool {
+	if lastSent.IsZero() {
+		return now.Weekday() == digestDay
+	}
+	daysSince := (int(now.Weekday()) - int(digestDay) + 7) % 7
+	latest := time.Date(now.Year(), now.Month(), now.Day()-daysSince, 0, 0, 0, 0, now.Location())
+	return lastSent.Before(latest)
+}
+
+// bucketByScore splits analyses into relevant videos scoring at least min_score and
+// honorable mentions scoring from mention_min_score up to min_score
+func (y *YouTubeAgent) bucketByScore(analyses []*models.Analysis) (relevant, mentions []*models.Analysis) {
+	minScore := y.config.YouTubeCurator.MinScore
+	if minScore <= 0 {
+		minScore = 6
+	}
+	mentionMinScore := y.config.YouTubeCurator.MentionMinScore
+
+	for _, analysis := range analyses {
+		switch {
+		case analysis.Score >= minScore:
+			if analysis.IsRelevant {
+				relevant = append(relevant, analysis)
+			}
+		case mentionMinScore > 0 && analysis.Score >= mentionMinScore:
+			mentions = append(mentions, analysis)
+		}
+	}
+	return relevant, mentions
+}
+
+// adjustScore applies the configured channel score adjustment, if any, clamping
+// the result to the 1-10 scale
+func (y *YouTubeAgent) adjustScore(analysis *models.Analysis) {
+	adjustments := y.config.YouTubeCurator.ChannelScoreAdjustments
+	if len(adjustments) == 0 || analysis.Video == nil {
+		return
+	}
+
+	adjustment, ok := adjustments[analysis.Video.ChannelID]
+	if !ok || analysis.Video.ChannelID == "" {
+		adjustment, ok = channelAdjustmentByTitle(adjustments, analysis.Video.ChannelTitle)
+	}
+	if !ok || adjustment == 0 {
+		return
+	}
+
+	original := analysis.Score
+	analysis.Score = max(1, min(10, original+adjustment))
+	log.Printf("Adjusted score for %s from %d to %d (channel %s, %+d)",
+		analysis.Video.Title, original, analysis.Score, analysis.Video.ChannelTitle, adjustment)
+}
+
+// channelAdjustmentByTitle looks up an adjustment by channel title, ignoring case
+func channelAdjustmentByTitle(adjustments map[string]int, channelTitle string) (int, bool) {
+	if channelTitle == "" {
+		return 0, false
+	}
+	for key, adjustment := range adjustments {
+		if strings.EqualFold(key, channelTitle) {
+			return adjustment, true
+		}
+	}
+	return 0, false
+}
+
+// excludeReported drops analyses whose video was already included in a delivered email
+func (y *YouTubeAgent) excludeReported(analyses []*models.Analysis) []*models.Analysis {
+	var kept []*models.Analysis
+	for _, analysis := range analyses {
+		if y.reportedStore.IsReported(analysis.Video.ID) {
+			continue
+		}
+		kept = append(kept, analysis)
+	}
+	if skipped := len(analyses) - len(kept); skipped > 0 {
+		log.Printf("Skipping %d videos already included in a previous email", skipped)
+	}
+	return kept
+}
+
+// prioritizeVideos orders videos by publish time, newest first unless order is
+// config.AnalysisOrderOldest, breaking ties by view count
+func prioritizeVideos(videos []*models.Video, order string) {
+	oldestFirst := order == config.AnalysisOrderOldest
+	sort.SliceStable(videos, func(i, j int) bool {
+		if !videos[i].PublishedAt.Equal(videos[j].PublishedAt) {
+			return videos[i].PublishedAt.After(videos[j].PublishedAt) != oldestFirst
+		}
+		return videos[i].ViewCount > videos[j].ViewCount
+	})
+}
+
+// filterByLanguage keeps videos whose language matches the allowlist. Matching uses the
+// primary subtag, so "en" allows "en-US". Videos without language metadata are kept unless strict.
+func filterByLanguage(videos []*models.Video, allowed []string, strict bool) ([]*models.Video, int) {
+	var kept []*models.Video
+	for _, video := range videos {
+		if video.Language == "" {
+			if !strict {
+				kept = append(kept, video)
+			}
+			continue
+		}
+
+		lang := primaryLanguage(video.Language)
+		for _, a := range allowed {
+			if strings.EqualFold(lang, primaryLanguage(a)) {
+				kept = append(kept, video)
+				break
+			}
+		}
+	}
+	return kept, len(videos) - len(kept)
+}
+
+// primaryLanguage returns the primary subtag of a language code ("en-US" -> "en")
+func primaryLanguage(code string) string {
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		return code[:i]
+	}
+	return code
+}
+
+// deferLiveVideos splits off live streams and upcoming premieres, returning the
+// videos that can be analyzed now and the deferred ones
+func deferLiveVideos(videos []*models.Video) (kept, deferred []*models.Video) {
+	for _, video := range videos {
+		if video.IsLiveOrUpcoming() {
+			deferred = append(deferred, video)
+		} else {
+			kept = append(kept, video)
+		}
+	}
+	return kept, deferred
+}
+
+// appendDeferredVideos fetches previously deferred videos missing from videos, so
+// streams that outlast the crawl window are still analyzed once complete. Lookup
+// failures are logged and retried on the next run.
+func (y *YouTubeAgent) appendDeferredVideos(ctx context.Context, videos []*models.Video, deferredIDs []string) []*models.Video {
+	fetched := make(map[string]bool, len(videos))
+	for _, video := range videos {
+		fetched[video.ID] = true
+	}
+	var missing []string
+	for _, id := range deferredIDs {
+		if !fetched[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return videos
+	}
+
+	log.Printf("Re-checking %d previously deferred live or upcoming videos", len(missing))
+	deferred, err := y.youtubeClient.GetVideosByID(ctx, missing)
+	if err != nil {
+		log.Printf("Warning: Failed to re-check deferred videos: %v", err)
+		return videos
+	}
+	return append(videos, deferred...)
+}
+
+// videoIDs returns the IDs of videos
+func videoIDs(videos []*models.Video) []string {
+	ids := make([]string, 0, len(videos))
+	for _, video := range videos {
+		ids = append(ids, video.ID)
+	}
+	return ids
+}
+
+// filterByCategory keeps videos whose category is in the allowlist, which may mix
+// category names and IDs (matched case-insensitively). Videos without a category are kept.
+func filterByCategory(videos []*models.Video, allowed []string) ([]*models.Video, int) {
+	var kept []*models.Video
+	for _, video := range videos {
+		if video.Category == "" {
+			kept = append(kept, video)
+			continue
+		}
+		for _, a := range allowed {
+			if strings.EqualFold(video.Category, youtube.CategoryName(strings.TrimSpace(a))) {
+				kept = append(kept, video)
+				break
+			}
+		}
+	}
+	return kept, len(videos) - len(kept)
+}
+
+// filterByKeywords drops videos matching any exclude keyword and, when include is
+// non-empty, videos matching none of the include keywords. Exclusion wins when a
+// video matches both. Matching is a case-insensitive substring of title or description.
+func filterByKeywords(videos []*models.Video, include, exclude []string) ([]*models.Video, int) {
+	var kept []*models.Video
+	for _, video := range videos {
+		if matchesKeyword(video, exclude) {
+			continue
+		}
+		if len(include) > 0 && !matchesKeyword(video, include) {
+			continue
+		}
+		kept = append(kept, video)
+	}
+	return kept, len(videos) - len(kept)
+}
+
+// matchesKeyword reports whether the video's title or description contains any keyword,
+// ignoring case. Empty keywords never match.
+func matchesKeyword(video *models.Video, keywords []string) bool {
+	text := strings.ToLower(video.Title + "\n" + video.Description)
+	for _, keyword := range keywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" && strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchVideos returns the configured video list when set, otherwise recent subscription uploads
+func (y *YouTubeAgent) fetchVideos(ctx context.Context) ([]*models.Video, error) {
+	if requested := y.config.YouTubeCurator.VideoIDs; len(requested) > 0 {
+		ids := make([]string, 0, len(requested))
+		for _, v := range requested {
+			if id := youtube.ParseVideoID(v); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		log.Printf("Fetching %d requested videos (skipping subscriptions)...", len(ids))
+		videos, err := y.youtubeClient.GetVideosByID(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get requested videos: %w", err)
+		}
+		return videos, nil
+	}
+
+	log.Println("Fetching videos from YouTube subscriptions...")
+	videos, err := y.youtubeClient.GetSubscriptionVideos(ctx, 50)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription videos: %w", err)
+	}
+	return videos, nil
+}
+
+// analyzeWithQuotaBackoff analyzes a video, pausing with exponential backoff
+// while Gemini reports quota exhaustion
+func (y *YouTubeAgent) analyzeWithQuotaBackoff(ctx context.Context, video *models.Video) (*models.Analysis, error) {
+	backoff := quotaBackoffInitial
+
+	for attempt := 0; ; attempt++ {
+		analysis, err := y.analyzer.AnalyzeVideo(ctx, video)
+		if !errors.Is(err, ai.ErrQuotaExceeded) || attempt >= quotaMaxRetries {
+			return analysis, err
+		}
+
+		log.Printf("Gemini quota exceeded, pausing %v before retrying %s", backoff, video.Title)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > quotaBackoffMax {
+			backoff = quotaBackoffMax
+		}
+	}
+}
+
 // startTokenRefresher starts a background goroutine that refreshes the YouTube OAuth token periodically.
 // This ensures the token stays fresh even during long periods of inactivity between scheduled runs.
 // The refresher runs at the specified interval and saves refreshed tokens to disk automatically.
 func (y *YouTubeAgent) startTokenRefresher(interval time.Duration) {
+	y.tokenRefreshMu.Lock()
+	defer y.tokenRefreshMu.Unlock()
+
 	if y.tokenRefreshTicker != nil {
 		// Already running
 		return
 	}
 
 	log.Printf("Starting background token refresher (interval: %v)", interval)
-	y.tokenRefreshTicker = time.NewTicker(interval)
-	y.tokenRefreshStop = make(chan bool)
-
+	ticker := time.NewTicker(interval)
+	stop := make(chan bool)
+	done := make(chan struct{})
+	y.tokenRefreshTicker = ticker
+	y.tokenRefreshStop = stop
+	y.tokenRefreshDone = done
+
+	// Use the local ticker/channel: StopTokenRefresher clears the fields
 	go func() {
+		defer close(done)
 		for {
 			select {
-			case <-y.tokenRefreshTicker.C:
+			case <-ticker.C:
 				log.Println("Background token refresh triggered")
 				if y.youtubeClient != nil {
 					if err := y.youtubeClient.RefreshToken(); err != nil {
@@ -125,7 +642,7 @@ func (y *YouTubeAgent) startTokenRefresher(interval time.Duration) {
 				} else {
 					log.Println("Background token refresh skipped - client not initialized")
 				}
-			case <-y.tokenRefreshStop:
+			case <-stop:
 				log.Println("Stopping background token refresher")
 				return
 			}
@@ -137,20 +654,62 @@ func (y *YouTubeAgent) startTokenRefresher(interval time.Duration) {
 // This should be called when the application shuts down to ensure clean termination.
 // It's safe to call multiple times or even if the refresher was never started.
 func (y *YouTubeAgent) StopTokenRefresher() {
+	y.tokenRefreshMu.Lock()
+	defer y.tokenRefreshMu.Unlock()
+
 	if y.tokenRefreshTicker != nil {
 		y.tokenRefreshTicker.Stop()
 		if y.tokenRefreshStop != nil {
-			y.tokenRefreshStop <- true
 			close(y.tokenRefreshStop)
+			// Wait for the goroutine so nothing outlives the call
+			<-y.tokenRefreshDone
 		}
 		y.tokenRefreshTicker = nil
 		y.tokenRefreshStop = nil
+		y.tokenRefreshDone = nil
 	}
 }
 
+// Close stops the token refresher and closes the video tracker. Call it once on
+// shutdown, after the last run.
+func (y *YouTubeAgent) Close() error {
+	y.StopTokenRefresher()
+	if y.videoTracker == nil {
+		return nil
+	}
+	return y.videoTracker.Close()
+}
+
 func (y *YouTubeAgent) RunOnce(ctx context.Context, events *scheduler.AgentEvents) error {
 	startTime := time.Now()
 
+	// Another replica sharing the data dir may be mid-run; skip rather than
+	// analyze and email the same videos twice
+	if y.runLock != nil {
+		acquired, holder, err := y.runLock.TryAcquire()
+		if err != nil {
+			return fmt.Errorf("failed to acquire run lock: %w", err)
+		}
+		if !acquired {
+			log.Printf("Another instance (%s) is running - skipping this run", holder)
+			if events != nil && events.OnSkipped != nil {
+				events.OnSkipped("lock held by " + holder)
+			}
+			return nil
+		}
+		defer func() {
+			if err := y.runLock.Release(); err != nil {
+				log.Printf("Warning: Failed to release run lock: %v", err)
+			}
+		}()
+	}
+
+	// Long-running instances forget entries past tracker_retention_days every run,
+	// not only at startup
+	if err := y.videoTracker.Cleanup(); err != nil {
+		log.Printf("Warning: Failed to clean up video tracker: %v", err)
+	}
+
 	// Proactively refresh token if needed before starting work
 	if y.youtubeClient != nil {
 		if err := y.youtubeClient.RefreshToken(); err != nil {
@@ -159,70 +718,121 @@ func (y *YouTubeAgent) RunOnce(ctx context.Context, events *scheduler.AgentEvent
 		}
 	}
 
-	// Fetch videos from subscriptions
-	log.Println("Fetching videos from YouTube subscriptions...")
-	videos, err := y.youtubeClient.GetSubscriptionVideos(ctx, 50)
+	videos, err := y.fetchVideos(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get subscription videos: %w", err)
+		return err
 	}
 
-	if len(videos) == 0 {
+	videosFound := len(videos)
+	if videosFound == 0 {
 		log.Println("No new videos found")
-		duration := time.Since(startTime)
-		if events != nil && events.OnSuccess != nil {
-			metrics := YouTubeMetrics{
-				VideosFound:    0,
-				Analyzed:       0,
-				Relevant:       0,
-				Skipped:        0,
-				AnalysisErrors: 0,
-			}
-			events.OnSuccess(metrics, duration)
+	}
+
+	// Explicitly requested videos bypass the language filter and the analyzed-video check
+	manual := len(y.config.YouTubeCurator.VideoIDs) > 0
+
+	// Live streams and premieres have no complete recording yet. Their IDs are kept
+	// and re-fetched on later runs until YouTube reports them as finished, even once
+	// they have left the crawl window.
+	var pendingLive []string
+	if y.deferredStore != nil && !manual {
+		pendingLive = y.deferredStore.IDs()
+		videos = y.appendDeferredVideos(ctx, videos, pendingLive)
+	}
+	videos, live := deferLiveVideos(videos)
+	liveDeferred := len(live)
+	if liveDeferred > 0 {
+		log.Printf("Deferred %d live or upcoming videos until they are complete", liveDeferred)
+	}
+	if y.deferredStore != nil && (liveDeferred > 0 || len(pendingLive) > 0) {
+		if err := y.deferredStore.Update(videoIDs(live), videoIDs(videos)); err != nil {
+			log.Printf("Warning: Failed to save deferred videos: %v", err)
 		}
-		return nil
 	}
 
-	// Filter out already analyzed videos
+	var filtered int
+	if allowed := y.config.YouTubeCurator.Languages; len(allowed) > 0 && !manual {
+		var dropped int
+		videos, dropped = filterByLanguage(videos, allowed, y.config.YouTubeCurator.StrictLanguages)
+		if dropped > 0 {
+			log.Printf("Skipped %d videos outside the language allowlist %v", dropped, allowed)
+		}
+		filtered += dropped
+	}
+
+	if allowed := y.config.YouTubeCurator.AllowedCategories; len(allowed) > 0 && !manual {
+		var dropped int
+		videos, dropped = filterByCategory(videos, allowed)
+		if dropped > 0 {
+			log.Printf("Skipped %d videos outside the allowed categories %v", dropped, allowed)
+		}
+		filtered += dropped
+	}
+
+	var keywordSkipped int
+	include, exclude := y.config.YouTubeCurator.IncludeKeywords, y.config.YouTubeCurator.ExcludeKeywords
+	if (len(include) > 0 || len(exclude) > 0) && !manual {
+		videos, keywordSkipped = filterByKeywords(videos, include, exclude)
+		if keywordSkipped > 0 {
+			log.Printf("Skipped %d videos by keyword filters", keywordSkipped)
+		}
+	}
+
+	// Filter out already analyzed videos, and repeats within this run (e.g. a video
+	// listed in several playlists) that the tracker only learns about after analysis
 	var newVideos []*models.Video
-	var skippedCount int
+	var skippedCount, repeatedCount int
+	seen := make(map[string]bool)
 
 	for _, video := range videos {
-		if y.videoTracker.IsAnalyzed(video.ID) {
+		if seen[video.ID] {
+			repeatedCount++
+			continue
+		}
+		seen[video.ID] = true
+
+		if !manual && y.videoTracker.IsVideoAnalyzed(video) {
 			skippedCount++
 			continue
 		}
 		newVideos = append(newVideos, video)
 	}
+	if repeatedCount > 0 {
+		log.Printf("Ignored %d repeated videos in this run", repeatedCount)
+	}
 
-	if len(newVideos) == 0 {
-		duration := time.Since(startTime)
-		if events != nil && events.OnSuccess != nil {
-			metrics := YouTubeMetrics{
-				VideosFound:    len(videos),
-				Analyzed:       0,
-				Relevant:       0,
-				Skipped:        skippedCount,
-				AnalysisErrors: 0,
-			}
-			events.OnSuccess(metrics, duration)
-		}
-		return nil
+	// Cap the run to the highest-priority videos; the rest stay untracked for the next run
+	var deferredCount int
+	if limit := y.config.YouTubeCurator.MaxAnalysisPerRun; limit > 0 && len(newVideos) > limit {
+		prioritizeVideos(newVideos, y.config.YouTubeCurator.AnalysisOrder)
+		deferredCount = len(newVideos) - limit
+		newVideos = newVideos[:limit]
+		log.Printf("Analyzing the %d highest-priority videos, deferring %d to the next run", limit, deferredCount)
 	}
 
 	var analyses []*models.Analysis
 	var analysisErrors int
 	var skippedShorts int
-	var analyzedVideoIDs []string
+	var analyzedVideos []*models.Video
+	var tooManyFailures error
 
 	for i, video := range newVideos {
 		log.Printf("Analyzing video %d/%d: %s", i+1, len(newVideos), video.Title)
 
-		analysis, err := y.analyzer.AnalyzeVideo(ctx, video)
+		analysis, err := y.analyzeWithQuotaBackoff(ctx, video)
 		if err != nil {
 			if errors.Is(err, ai.ErrShortVideoSkipped) {
 				skippedShorts++
 				continue
 			}
+			if errors.Is(err, ai.ErrQuotaExceeded) || ctx.Err() != nil {
+				// Not a content failure: stop here and leave the rest for the next run
+				log.Printf("Stopping analysis after %d/%d videos: %v", i, len(newVideos), err)
+				if events != nil && events.OnPartialFailure != nil {
+					events.OnPartialFailure(fmt.Errorf("analysis paused, %d videos deferred: %w", len(newVideos)-i, err), time.Since(startTime))
+				}
+				break
+			}
 			analysisErrors++
 
 			// Report individual analysis failure as partial (recoverable)
@@ -230,27 +840,35 @@ func (y *YouTubeAgent) RunOnce(ctx context.Context, events *scheduler.AgentEvent
 				events.OnPartialFailure(fmt.Errorf("failed to analyze video %s: %w", video.Title, err), time.Since(startTime))
 			}
 
+			if !y.config.YouTubeCurator.RetryFailedAnalysis {
+				// Give up on this video rather than failing on it every run
+				analyzedVideos = append(analyzedVideos, video)
+			}
 			if analysisErrors > len(newVideos)/2 {
-				return fmt.Errorf("too many analysis failures (%d/%d), stopping", analysisErrors, i+1)
+				// Stop, but still record what was decided so far below
+				tooManyFailures = fmt.Errorf("too many analysis failures (%d/%d), stopping", analysisErrors, i+1)
+				break
 			}
 			continue
 		}
 
+		y.adjustScore(analysis)
 		analyses = append(analyses, analysis)
-		analyzedVideoIDs = append(analyzedVideoIDs, video.ID)
-
-		time.Sleep(2 * time.Second)
+		analyzedVideos = append(analyzedVideos, video)
 	}
 
-	// Mark videos as analyzed (even if they weren't relevant)
-	if len(analyzedVideoIDs) > 0 {
-		if err := y.videoTracker.MarkMultipleAnalyzed(analyzedVideoIDs); err != nil {
+	// Mark videos as analyzed (even if they weren't relevant, or failed when retries are off)
+	if len(analyzedVideos) > 0 {
+		if err := y.videoTracker.MarkVideosAnalyzed(analyzedVideos); err != nil {
 			// Report video tracking failure as partial (doesn't affect core functionality)
 			if events != nil && events.OnPartialFailure != nil {
 				events.OnPartialFailure(fmt.Errorf("failed to mark videos as analyzed: %w", err), time.Since(startTime))
 			}
 		}
 	}
+	if tooManyFailures != nil {
+		return tooManyFailures
+	}
 
 	if analysisErrors > 0 {
 		// Check if ALL videos failed to analyze (critical failure)
@@ -269,42 +887,44 @@ func (y *YouTubeAgent) RunOnce(ctx context.Context, events *scheduler.AgentEvent
 		}
 	}
 
-	// Filter relevant videos
-	var relevantVideos []*models.Analysis
-	for _, analysis := range analyses {
-		if analysis.IsRelevant && analysis.Score >= 6 {
-			relevantVideos = append(relevantVideos, analysis)
-		}
+	// Filter relevant videos, setting aside near misses as honorable mentions
+	relevantVideos, mentions := y.bucketByScore(analyses)
+
+	// Collapse re-uploads and mirrors of the same content across channels
+	relevantVideos, duplicates := dedupeSimilarTitles(relevantVideos, y.config.YouTubeCurator.DedupTitleSimilarity)
+	if duplicates > 0 {
+		log.Printf("Removed %d near-duplicate videos", duplicates)
 	}
 
-	// Send email report if there are relevant videos
-	if len(relevantVideos) > 0 {
-		report := &models.EmailReport{
-			Date:     time.Now(),
-			Videos:   relevantVideos,
-			Total:    len(analyses),
-			Selected: len(relevantVideos),
-		}
-
-		if err := y.emailSender.SendReport(report); err != nil {
-			// Report email failure as CRITICAL - email delivery is core functionality
-			if events != nil && events.OnCriticalFailure != nil {
-				events.OnCriticalFailure(fmt.Errorf("failed to send email report: %w", err), time.Since(startTime))
-			}
-			return fmt.Errorf("failed to send email report: %w", err)
+	// Send email report if there are relevant videos (or accumulate them in digest mode)
+	if err := y.deliverReport(relevantVideos, mentions, len(analyses)); err != nil {
+		// Report email failure as CRITICAL - email delivery is core functionality
+		if events != nil && events.OnCriticalFailure != nil {
+			events.OnCriticalFailure(fmt.Errorf("failed to send email report: %w", err), time.Since(startTime))
 		}
+		return fmt.Errorf("failed to send email report: %w", err)
 	}
 
 	// Record successful completion with detailed metrics
 	duration := time.Since(startTime)
-	if events != nil && events.OnSuccess != nil {
-		metrics := YouTubeMetrics{
-			VideosFound:    len(videos),
-			Analyzed:       len(analyses),
-			Relevant:       len(relevantVideos),
-			Skipped:        skippedCount,
-			AnalysisErrors: analysisErrors,
+	metrics := YouTubeMetrics{
+		VideosFound:      videosFound,
+		Analyzed:         len(analyses),
+		Relevant:         len(relevantVideos),
+		Skipped:          skippedCount,
+		AnalysisErrors:   analysisErrors,
+		Deferred:         deferredCount,
+		Filtered:         filtered,
+		SkippedByKeyword: keywordSkipped,
+		Duplicates:       duplicates,
+		LiveDeferred:     liveDeferred,
+	}
+	if len(newVideos) == 0 && y.config.YouTubeCurator.ReportIdleRuns {
+		// Nothing new to analyze: a quiet day, not an event worth a success notification
+		if events != nil && events.OnIdle != nil {
+			events.OnIdle(metrics, duration)
 		}
+	} else if events != nil && events.OnSuccess != nil {
 		events.OnSuccess(metrics, duration)
 	}
 
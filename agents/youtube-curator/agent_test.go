@@ -2,11 +2,22 @@ package youtubecurator
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"agent-stack/internal/models"
+	"agent-stack/shared/ai"
 	"agent-stack/shared/config"
 	"agent-stack/shared/scheduler"
+	"agent-stack/shared/storage"
 )
 
 func TestYouTubeAgentName(t *testing.T) {
@@ -262,6 +273,52 @@ func TestConcurrentTokenRefresh(t *testing.T) {
 	}
 }
 
+// TestConcurrentTokenRefresherStress races many starts against each other and
+// checks that exactly one refresher runs; meaningful under go test -race
+func TestConcurrentTokenRefresherStress(t *testing.T) {
+	agent := NewYouTubeAgent(&config.Config{})
+	baseline := runtime.NumGoroutine()
+
+	const starters = 100
+	tickers := make(chan *time.Ticker, starters)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < starters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			agent.startTokenRefresher(time.Hour)
+
+			agent.tokenRefreshMu.Lock()
+			tickers <- agent.tokenRefreshTicker
+			agent.tokenRefreshMu.Unlock()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(tickers)
+
+	distinct := map[*time.Ticker]bool{}
+	for ticker := range tickers {
+		distinct[ticker] = true
+	}
+	if len(distinct) != 1 || distinct[nil] {
+		t.Errorf("Expected every start to observe the same ticker, got %d distinct", len(distinct))
+	}
+
+	agent.StopTokenRefresher()
+
+	// The starter goroutines have exited; only the runtime's own may linger briefly
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if leaked := runtime.NumGoroutine() - baseline; leaked > 0 {
+		t.Errorf("Expected no leaked goroutines after stop, got %d extra", leaked)
+	}
+}
+
 func TestAgentRunOnceStructure(t *testing.T) {
 	// Test the structure of RunOnce with mock events
 
@@ -296,3 +353,1172 @@ func TestAgentRunOnceStructure(t *testing.T) {
 	// Verify the events structure compiles correctly
 	_ = context.Background()
 }
+
+// fakeVideoSource records which YouTube lookups the agent performs
+type fakeVideoSource struct {
+	subscriptionVideos []*models.Video
+	subscriptionCalls  int
+	requestedIDs       []string
+	// videosByID overrides the placeholder videos GetVideosByID returns
+	videosByID map[string]*models.Video
+}
+
+func (f *fakeVideoSource) GetSubscriptionVideos(ctx context.Context, maxResults int64) ([]*models.Video, error) {
+	f.subscriptionCalls++
+	return f.subscriptionVideos, nil
+}
+
+func (f *fakeVideoSource) GetVideosByID(ctx context.Context, ids []string) ([]*models.Video, error) {
+	f.requestedIDs = append(f.requestedIDs, ids...)
+	var videos []*models.Video
+	for _, id := range ids {
+		if video, ok := f.videosByID[id]; ok {
+			videos = append(videos, video)
+			continue
+		}
+		videos = append(videos, &models.Video{ID: id, URL: "https://www.youtube.com/watch?v=" + id})
+	}
+	return videos, nil
+}
+
+func (f *fakeVideoSource) RefreshToken() error {
+	return nil
+}
+
+func TestFetchVideosWithRequestedIDsSkipsSubscriptions(t *testing.T) {
+	cfg := &config.Config{
+		YouTubeCurator: config.YouTubeCuratorConfig{
+			VideoIDs: []string{"abc123", "https://youtu.be/def456"},
+		},
+	}
+	source := &fakeVideoSource{}
+	agent := NewYouTubeAgent(cfg)
+	agent.youtubeClient = source
+
+	videos, err := agent.fetchVideos(context.Background())
+	if err != nil {
+		t.Fatalf("fetchVideos() error: %v", err)
+	}
+
+	if source.subscriptionCalls != 0 {
+		t.Errorf("Expected subscriptions to be skipped, got %d calls", source.subscriptionCalls)
+	}
+	if len(videos) != 2 {
+		t.Fatalf("Expected 2 videos, got %d", len(videos))
+	}
+	if source.requestedIDs[0] != "abc123" || source.requestedIDs[1] != "def456" {
+		t.Errorf("Unexpected requested IDs: %v", source.requestedIDs)
+	}
+}
+
+func TestFetchVideosDefaultsToSubscriptions(t *testing.T) {
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{{ID: "sub1"}}}
+	agent := NewYouTubeAgent(&config.Config{})
+	agent.youtubeClient = source
+
+	videos, err := agent.fetchVideos(context.Background())
+	if err != nil {
+		t.Fatalf("fetchVideos() error: %v", err)
+	}
+
+	if source.subscriptionCalls != 1 {
+		t.Errorf("Expected 1 subscription call, got %d", source.subscriptionCalls)
+	}
+	if len(source.requestedIDs) != 0 {
+		t.Errorf("Expected no ID lookups, got %v", source.requestedIDs)
+	}
+	if len(videos) != 1 {
+		t.Errorf("Expected 1 video, got %d", len(videos))
+	}
+}
+
+func TestInitializeUsesConfiguredDataDir(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "state", "curator")
+	cfg := &config.Config{
+		DataDir: dataDir,
+		YouTubeCurator: config.YouTubeCuratorConfig{
+			AI: config.AIConfig{GeminiAPIKey: "test-api-key", Model: "gemini-2.5-flash"},
+		},
+	}
+
+	agent := NewYouTubeAgent(cfg)
+	agent.youtubeClient = &fakeVideoSource{}
+	if err := agent.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	if err := agent.videoTracker.MarkVideosAnalyzed([]*models.Video{{ID: "abc123"}}); err != nil {
+		t.Fatalf("Failed to mark video analyzed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "analyzed_videos.json")); err != nil {
+		t.Errorf("Expected tracker file under configured data dir: %v", err)
+	}
+}
+
+func TestTrackerRetentionDays(t *testing.T) {
+	tests := []struct {
+		name          string
+		retentionDays int
+		expectTracked bool
+	}{
+		{"Older than retention is re-analyzed", 1, false},
+		{"Within retention stays analyzed", 3, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataDir := t.TempDir()
+			seed := `[{"video_id": "old123", "analyzed_at": "` + time.Now().Add(-48*time.Hour).Format(time.RFC3339) + `"}]`
+			if err := os.WriteFile(filepath.Join(dataDir, "analyzed_videos.json"), []byte(seed), 0644); err != nil {
+				t.Fatalf("Failed to seed tracker file: %v", err)
+			}
+
+			cfg := &config.Config{
+				DataDir: dataDir,
+				YouTubeCurator: config.YouTubeCuratorConfig{
+					AI:                   config.AIConfig{GeminiAPIKey: "test-api-key", Model: "gemini-2.5-flash"},
+					TrackerRetentionDays: tt.retentionDays,
+				},
+			}
+			agent := NewYouTubeAgent(cfg)
+			agent.youtubeClient = &fakeVideoSource{}
+			if err := agent.Initialize(); err != nil {
+				t.Fatalf("Initialize() error: %v", err)
+			}
+
+			if got := agent.videoTracker.IsAnalyzed("old123"); got != tt.expectTracked {
+				t.Errorf("IsAnalyzed() = %v, want %v", got, tt.expectTracked)
+			}
+		})
+	}
+}
+
+func TestChangingGuidelinesReanalyzesTrackedVideos(t *testing.T) {
+	dataDir := t.TempDir()
+	video := &models.Video{ID: "abc123", Title: "Deep dive"}
+
+	// runWith initializes an agent on the shared data dir and returns the videos it analyzed
+	runWith := func(criteria ...string) []string {
+		cfg := &config.Config{
+			DataDir: dataDir,
+			YouTubeCurator: config.YouTubeCuratorConfig{
+				AI: config.AIConfig{GeminiAPIKey: "test-api-key", Model: "gemini-2.5-flash"},
+			},
+		}
+		for _, text := range criteria {
+			cfg.YouTubeCurator.Guidelines.Criteria = append(cfg.YouTubeCurator.Guidelines.Criteria, config.Criterion{Text: text})
+		}
+
+		agent := NewYouTubeAgent(cfg)
+		agent.youtubeClient = &fakeVideoSource{subscriptionVideos: []*models.Video{video}}
+		analyzer := &fakeAnalyzer{}
+		agent.analyzer = analyzer
+		agent.emailSender = &fakeEmailSender{}
+		if err := agent.Initialize(); err != nil {
+			t.Fatalf("Initialize() error: %v", err)
+		}
+		defer agent.videoTracker.Close()
+
+		if err := agent.RunOnce(context.Background(), nil); err != nil {
+			t.Fatalf("RunOnce() error: %v", err)
+		}
+		return analyzer.analyzed
+	}
+
+	if analyzed := runWith("Educational value"); len(analyzed) != 1 {
+		t.Fatalf("Expected the new video analyzed, got %v", analyzed)
+	}
+	if analyzed := runWith("Educational value"); len(analyzed) != 0 {
+		t.Errorf("Expected no re-analysis with unchanged guidelines, got %v", analyzed)
+	}
+	if analyzed := runWith("Educational value", "Avoid clickbait"); strings.Join(analyzed, ",") != "abc123" {
+		t.Errorf("Expected changed guidelines to re-analyze the tracked video, got %v", analyzed)
+	}
+}
+
+// fakeEmailSender records emails instead of sending them over SMTP
+type fakeEmailSender struct {
+	reports  []*models.EmailReport
+	subjects []string
+	failures int // number of upcoming SendReport calls that fail
+}
+
+func (f *fakeEmailSender) SendReport(report *models.EmailReport, subjectTemplate string) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("smtp unavailable")
+	}
+	f.reports = append(f.reports, report)
+	return nil
+}
+
+func (f *fakeEmailSender) SendHTML(subject, htmlBody string) error {
+	f.subjects = append(f.subjects, subject)
+	return nil
+}
+
+func TestDigestModeAccumulatesAndFlushesOnDigestDay(t *testing.T) {
+	cfg := &config.Config{
+		DataDir: t.TempDir(),
+		YouTubeCurator: config.YouTubeCuratorConfig{
+			AI:         config.AIConfig{GeminiAPIKey: "test-api-key", Model: "gemini-2.5-flash"},
+			DigestMode: true,
+			DigestDay:  "sunday",
+		},
+	}
+	sender := &fakeEmailSender{}
+	agent := NewYouTubeAgent(cfg)
+	agent.youtubeClient = &fakeVideoSource{}
+	agent.emailSender = sender
+	if err := agent.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	relevant := func(id string) []*models.Analysis {
+		return []*models.Analysis{{Video: &models.Video{ID: id}, IsRelevant: true, Score: 8}}
+	}
+
+	// Monday and Wednesday runs only accumulate
+	for _, run := range []struct {
+		day time.Time
+		id  string
+	}{
+		{time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC), "monday"},
+		{time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC), "wednesday"},
+	} {
+		agent.now = func() time.Time { return run.day }
+		if err := agent.deliverReport(relevant(run.id), nil, 4); err != nil {
+			t.Fatalf("deliverReport() on %s error: %v", run.day.Weekday(), err)
+		}
+	}
+	if len(sender.reports) != 0 {
+		t.Fatalf("Expected no email before digest day, got %d", len(sender.reports))
+	}
+
+	// Sunday run flushes everything accumulated, including its own results
+	agent.now = func() time.Time { return time.Date(2025, 3, 16, 9, 0, 0, 0, time.UTC) }
+	if err := agent.deliverReport(relevant("sunday"), nil, 2); err != nil {
+		t.Fatalf("deliverReport() on digest day error: %v", err)
+	}
+	if len(sender.reports) != 1 {
+		t.Fatalf("Expected 1 digest email, got %d", len(sender.reports))
+	}
+	report := sender.reports[0]
+	if report.Selected != 3 || len(report.Videos) != 3 {
+		t.Errorf("Expected 3 videos in digest, got %d", len(report.Videos))
+	}
+	if report.Total != 10 {
+		t.Errorf("Expected 10 analyzed videos in digest, got %d", report.Total)
+	}
+
+	if pending, _ := agent.digestStore.Pending(); len(pending) != 0 {
+		t.Errorf("Expected accumulator cleared after digest, got %d pending", len(pending))
+	}
+}
+
+func TestDigestModeCatchesUpWithoutResending(t *testing.T) {
+	cfg := &config.Config{
+		DataDir: t.TempDir(),
+		YouTubeCurator: config.YouTubeCuratorConfig{
+			AI:         config.AIConfig{GeminiAPIKey: "test-api-key", Model: "gemini-2.5-flash"},
+			DigestMode: true,
+			DigestDay:  "sunday",
+		},
+	}
+	sender := &fakeEmailSender{}
+	agent := NewYouTubeAgent(cfg)
+	agent.youtubeClient = &fakeVideoSource{}
+	agent.emailSender = sender
+	if err := agent.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+
+	runs := []struct {
+		at         time.Time
+		id         string
+		wantEmails int
+	}{
+		{time.Date(2025, 3, 9, 9, 0, 0, 0, time.UTC), "sunday-morning", 1},
+		// A second run on the digest day keeps its videos for next week
+		{time.Date(2025, 3, 9, 18, 0, 0, 0, time.UTC), "sunday-evening", 1},
+		{time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC), "wednesday", 1},
+		// No run on Sunday 3/16, so Monday sends the missed digest
+		{time.Date(2025, 3, 17, 9, 0, 0, 0, time.UTC), "monday", 2},
+		{time.Date(2025, 3, 17, 18, 0, 0, 0, time.UTC), "monday-evening", 2},
+	}
+	for _, run := range runs {
+		agent.now = func() time.Time { return run.at }
+		analyses := []*models.Analysis{{Video: &models.Video{ID: run.id}, IsRelevant: true, Score: 8}}
+		if err := agent.deliverReport(analyses, nil, 1); err != nil {
+			t.Fatalf("deliverReport() at %s error: %v", run.at, err)
+		}
+		if len(sender.reports) != run.wantEmails {
+			t.Fatalf("Expected %d digest emails after the %s run, got %d", run.wantEmails, run.id, len(sender.reports))
+		}
+	}
+
+	var ids []string
+	for _, analysis := range sender.reports[1].Videos {
+		ids = append(ids, analysis.Video.ID)
+	}
+	if strings.Join(ids, ",") != "sunday-evening,wednesday,monday" {
+		t.Errorf("Expected the catch-up digest to hold the week's videos, got %v", ids)
+	}
+
+	// The last send survives a restart
+	restarted := NewYouTubeAgent(cfg)
+	restarted.youtubeClient = &fakeVideoSource{}
+	restarted.emailSender = sender
+	if err := restarted.Initialize(); err != nil {
+		t.Fatalf("Initialize() error: %v", err)
+	}
+	restarted.now = func() time.Time { return time.Date(2025, 3, 18, 9, 0, 0, 0, time.UTC) }
+	if err := restarted.deliverReport(nil, nil, 1); err != nil {
+		t.Fatalf("deliverReport() error: %v", err)
+	}
+	if len(sender.reports) != 2 {
+		t.Errorf("Expected no digest the day after a catch-up, got %d emails", len(sender.reports))
+	}
+}
+
+func TestFilterByLanguage(t *testing.T) {
+	videos := []*models.Video{
+		{ID: "english", Language: "en"},
+		{ID: "american", Language: "en-US"},
+		{ID: "french", Language: "fr"},
+		{ID: "japanese", Language: "ja"},
+		{ID: "unknown", Language: ""},
+	}
+
+	tests := []struct {
+		name     string
+		allowed  []string
+		strict   bool
+		expected []string
+	}{
+		{"Primary subtag matches regional variants", []string{"en"}, false, []string{"english", "american", "unknown"}},
+		{"Multiple languages", []string{"EN", "fr"}, false, []string{"english", "american", "french", "unknown"}},
+		{"Strict drops missing metadata", []string{"en"}, true, []string{"english", "american"}},
+		{"Regional allowlist entry matches primary language", []string{"fr-CA"}, false, []string{"french", "unknown"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, dropped := filterByLanguage(videos, tt.allowed, tt.strict)
+
+			var ids []string
+			for _, v := range kept {
+				ids = append(ids, v.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Kept %v, want %v", ids, tt.expected)
+			}
+			if dropped != len(videos)-len(tt.expected) {
+				t.Errorf("Dropped %d, want %d", dropped, len(videos)-len(tt.expected))
+			}
+		})
+	}
+}
+
+func TestFilterByCategory(t *testing.T) {
+	videos := []*models.Video{
+		{ID: "lecture", Category: "Education"},
+		{ID: "lab", Category: "Science & Technology"},
+		{ID: "match", Category: "Sports"},
+		{ID: "unknown", Category: ""},
+	}
+
+	kept, dropped := filterByCategory(videos, []string{"education", "28"})
+	var ids []string
+	for _, v := range kept {
+		ids = append(ids, v.ID)
+	}
+	if strings.Join(ids, ",") != "lecture,lab,unknown" {
+		t.Errorf("Kept %v, want names and IDs matched with uncategorized videos kept", ids)
+	}
+	if dropped != 1 {
+		t.Errorf("Dropped %d, want 1", dropped)
+	}
+}
+
+// fakeAnalyzer returns canned analyses keyed by video ID and records calls
+type fakeAnalyzer struct {
+	results  map[string]*models.Analysis
+	errs     map[string]error
+	analyzed []string
+}
+
+func (f *fakeAnalyzer) AnalyzeVideo(ctx context.Context, video *models.Video) (*models.Analysis, error) {
+	f.analyzed = append(f.analyzed, video.ID)
+	if err := f.errs[video.ID]; err != nil {
+		return nil, err
+	}
+	if analysis, ok := f.results[video.ID]; ok {
+		analysis.Video = video
+		return analysis, nil
+	}
+	return &models.Analysis{Video: video, Summary: "Not relevant", Score: 2}, nil
+}
+
+// newTestAgent wires an agent with fakes and a temporary video tracker
+func newTestAgent(t *testing.T, cfg *config.Config, source *fakeVideoSource, analyzer *fakeAnalyzer) (*YouTubeAgent, *fakeEmailSender) {
+	t.Helper()
+
+	dataDir := t.TempDir()
+	tracker, err := storage.NewVideoTracker(dataDir, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create video tracker: %v", err)
+	}
+	pending, err := storage.NewDigestStore(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create pending report store: %v", err)
+	}
+	reported, err := storage.NewReportedStore(dataDir, reportedRetention)
+	if err != nil {
+		t.Fatalf("Failed to create reported video store: %v", err)
+	}
+	deferred, err := storage.NewDeferredStore(dataDir, deferredRetention)
+	if err != nil {
+		t.Fatalf("Failed to create deferred video store: %v", err)
+	}
+
+	sender := &fakeEmailSender{}
+	agent := NewYouTubeAgent(cfg)
+	agent.youtubeClient = source
+	agent.analyzer = analyzer
+	agent.emailSender = sender
+	agent.videoTracker = tracker
+	agent.digestStore = pending
+	agent.reportedStore = reported
+	agent.deferredStore = deferred
+	return agent, sender
+}
+
+// lifecycleTracker counts Cleanup and Close calls on top of a real tracker
+type lifecycleTracker struct {
+	storage.Tracker
+	cleanups, closes int
+}
+
+func (l *lifecycleTracker) Cleanup() error {
+	l.cleanups++
+	return l.Tracker.Cleanup()
+}
+
+func (l *lifecycleTracker) Close() error {
+	l.closes++
+	return l.Tracker.Close()
+}
+
+func TestTrackerCleanupPerRunAndCloseOnShutdown(t *testing.T) {
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{{ID: "one"}}}
+	agent, _ := newTestAgent(t, &config.Config{}, source, &fakeAnalyzer{})
+	tracker := &lifecycleTracker{Tracker: agent.videoTracker}
+	agent.videoTracker = tracker
+
+	for i := 0; i < 2; i++ {
+		if err := agent.RunOnce(context.Background(), nil); err != nil {
+			t.Fatalf("RunOnce() error: %v", err)
+		}
+	}
+	if tracker.cleanups != 2 {
+		t.Errorf("Expected one tracker cleanup per run, got %d", tracker.cleanups)
+	}
+
+	if err := agent.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if tracker.closes != 1 {
+		t.Errorf("Expected Close to close the tracker once, got %d", tracker.closes)
+	}
+}
+
+func TestRunOnceFiltersAnalyzesAndEmails(t *testing.T) {
+	seen := &models.Video{ID: "seen", Title: "Already analyzed", Language: "en"}
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{
+		seen,
+		{ID: "french", Title: "En français", Language: "fr"},
+		{ID: "good", Title: "Deep dive", Language: "en"},
+		{ID: "meh", Title: "Vlog", Language: "en"},
+	}}
+	analyzer := &fakeAnalyzer{results: map[string]*models.Analysis{
+		"good": {IsRelevant: true, Summary: "Useful", Score: 8},
+	}}
+	cfg := &config.Config{YouTubeCurator: config.YouTubeCuratorConfig{Languages: []string{"en"}}}
+	agent, sender := newTestAgent(t, cfg, source, analyzer)
+
+	if err := agent.videoTracker.MarkVideosAnalyzed([]*models.Video{seen}); err != nil {
+		t.Fatalf("Failed to seed tracker: %v", err)
+	}
+
+	var metrics YouTubeMetrics
+	events := &scheduler.AgentEvents{
+		OnSuccess: func(m scheduler.Metrics, duration time.Duration) {
+			metrics = m.(YouTubeMetrics)
+		},
+	}
+
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+
+	if strings.Join(analyzer.analyzed, ",") != "good,meh" {
+		t.Errorf("Expected only new in-language videos analyzed, got %v", analyzer.analyzed)
+	}
+	if len(sender.reports) != 1 {
+		t.Fatalf("Expected 1 email, got %d", len(sender.reports))
+	}
+	if report := sender.reports[0]; len(report.Videos) != 1 || report.Videos[0].Video.ID != "good" || report.Total != 2 {
+		t.Errorf("Unexpected report: %d videos, total %d", len(report.Videos), report.Total)
+	}
+	if metrics.VideosFound != 4 || metrics.Filtered != 1 || metrics.Analyzed != 2 || metrics.Relevant != 1 || metrics.Skipped != 1 {
+		t.Errorf("Unexpected metrics: %+v", metrics)
+	}
+	if !agent.videoTracker.IsAnalyzed("good") || !agent.videoTracker.IsAnalyzed("meh") {
+		t.Error("Expected analyzed videos to be tracked")
+	}
+}
+
+func TestRunOnceAnalyzesRepeatedVideoOnce(t *testing.T) {
+	video := &models.Video{ID: "dup", Title: "Deep dive"}
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{
+		video,
+		{ID: "other", Title: "Vlog"},
+		{ID: "dup", Title: "Deep dive"},
+	}}
+	analyzer := &fakeAnalyzer{}
+	agent, _ := newTestAgent(t, &config.Config{}, source, analyzer)
+
+	var metrics YouTubeMetrics
+	events := &scheduler.AgentEvents{
+		OnSuccess: func(m scheduler.Metrics, duration time.Duration) {
+			metrics = m.(YouTubeMetrics)
+		},
+	}
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+
+	if strings.Join(analyzer.analyzed, ",") != "dup,other" {
+		t.Errorf("Expected each video analyzed once, got %v", analyzer.analyzed)
+	}
+	if metrics.Analyzed != 2 {
+		t.Errorf("Expected 2 analyses, got %d", metrics.Analyzed)
+	}
+}
+
+func TestRunOnceSkippedWhileRunLockHeld(t *testing.T) {
+	lockDir := t.TempDir()
+	other, err := storage.NewRunLock(lockDir, runLockFile, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create run lock: %v", err)
+	}
+	// Simulate another replica in the middle of a run
+	if acquired, _, err := other.TryAcquire(); err != nil || !acquired {
+		t.Fatalf("Expected other instance to acquire the lock, got %v (err: %v)", acquired, err)
+	}
+
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{{ID: "abc"}}}
+	analyzer := &fakeAnalyzer{}
+	agent, sender := newTestAgent(t, &config.Config{}, source, analyzer)
+	agent.runLock, err = storage.NewRunLock(lockDir, runLockFile, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create run lock: %v", err)
+	}
+
+	succeeded := false
+	var skipReason string
+	events := &scheduler.AgentEvents{
+		OnSuccess: func(m scheduler.Metrics, duration time.Duration) { succeeded = true },
+		OnSkipped: func(reason string) { skipReason = reason },
+	}
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+	if source.subscriptionCalls != 0 || len(analyzer.analyzed) != 0 || len(sender.reports) != 0 || succeeded {
+		t.Errorf("Expected run to be skipped while the lock is held, got %d fetches and %d analyses",
+			source.subscriptionCalls, len(analyzer.analyzed))
+	}
+	if !strings.Contains(skipReason, "lock held") {
+		t.Errorf("Expected the skip to be reported with the lock holder, got %q", skipReason)
+	}
+
+	if err := other.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+	if source.subscriptionCalls != 1 || !succeeded {
+		t.Errorf("Expected run to proceed once the lock is free, got %d fetches", source.subscriptionCalls)
+	}
+	if acquired, _, err := other.TryAcquire(); err != nil || !acquired {
+		t.Errorf("Expected the lock to be released after the run, got %v (err: %v)", acquired, err)
+	}
+}
+
+func TestRunOnceRelevanceFilteringAndMetrics(t *testing.T) {
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{
+		{ID: "relevant"},
+		{ID: "low-score"},
+		{ID: "irrelevant"},
+		{ID: "short"},
+		{ID: "broken"},
+	}}
+	analyzer := &fakeAnalyzer{
+		results: map[string]*models.Analysis{
+			"relevant":   {IsRelevant: true, Summary: "Great", Score: 9},
+			"low-score":  {IsRelevant: true, Summary: "Marginal", Score: 5},
+			"irrelevant": {IsRelevant: false, Summary: "Off topic", Score: 8},
+		},
+		errs: map[string]error{
+			"short":  ai.ErrShortVideoSkipped,
+			"broken": errors.New("model returned garbage"),
+		},
+	}
+	cfg := &config.Config{YouTubeCurator: config.YouTubeCuratorConfig{RetryFailedAnalysis: true}}
+	agent, sender := newTestAgent(t, cfg, source, analyzer)
+
+	var metrics YouTubeMetrics
+	var partialFailures int
+	events := &scheduler.AgentEvents{
+		OnSuccess: func(m scheduler.Metrics, duration time.Duration) {
+			metrics = m.(YouTubeMetrics)
+		},
+		OnPartialFailure: func(err error, duration time.Duration) {
+			partialFailures++
+		},
+	}
+
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+
+	if metrics.VideosFound != 5 || metrics.Analyzed != 3 || metrics.Relevant != 1 || metrics.AnalysisErrors != 1 {
+		t.Errorf("Unexpected metrics: %+v", metrics)
+	}
+	// One for the failed video and one run-level summary
+	if partialFailures != 2 {
+		t.Errorf("Expected 2 partial failures, got %d", partialFailures)
+	}
+	if len(sender.reports) != 1 || len(sender.reports[0].Videos) != 1 || sender.reports[0].Videos[0].Video.ID != "relevant" {
+		t.Errorf("Expected a report containing only the relevant video")
+	}
+
+	// Skipped shorts and failed videos are not tracked, so they are retried next run
+	if agent.videoTracker.IsAnalyzed("short") || agent.videoTracker.IsAnalyzed("broken") {
+		t.Error("Skipped or failed videos should not be marked analyzed")
+	}
+	if !agent.videoTracker.IsAnalyzed("irrelevant") {
+		t.Error("Irrelevant videos should still be marked analyzed")
+	}
+}
+
+func TestRunOnceReportsIdleWhenNothingNew(t *testing.T) {
+	seen := &models.Video{ID: "seen", Title: "Already analyzed"}
+
+	for _, reportIdle := range []bool{true, false} {
+		t.Run(fmt.Sprintf("report_idle_runs=%v", reportIdle), func(t *testing.T) {
+			source := &fakeVideoSource{subscriptionVideos: []*models.Video{seen}}
+			cfg := &config.Config{YouTubeCurator: config.YouTubeCuratorConfig{ReportIdleRuns: reportIdle}}
+			agent, _ := newTestAgent(t, cfg, source, &fakeAnalyzer{})
+			if err := agent.videoTracker.MarkVideosAnalyzed([]*models.Video{seen}); err != nil {
+				t.Fatalf("Failed to seed tracker: %v", err)
+			}
+
+			var successes, idles int
+			events := &scheduler.AgentEvents{
+				OnSuccess: func(m scheduler.Metrics, duration time.Duration) { successes++ },
+				OnIdle:    func(m scheduler.Metrics, duration time.Duration) { idles++ },
+			}
+			if err := agent.RunOnce(context.Background(), events); err != nil {
+				t.Fatalf("RunOnce() error: %v", err)
+			}
+
+			if reportIdle && (idles != 1 || successes != 0) {
+				t.Errorf("Expected the run classified as idle, got %d idle and %d success events", idles, successes)
+			}
+			if !reportIdle && (idles != 0 || successes != 1) {
+				t.Errorf("Expected the run reported as a success, got %d idle and %d success events", idles, successes)
+			}
+		})
+	}
+}
+
+func TestRetryFailedAnalysis(t *testing.T) {
+	for _, retry := range []bool{true, false} {
+		t.Run(fmt.Sprintf("retry=%v", retry), func(t *testing.T) {
+			source := &fakeVideoSource{subscriptionVideos: []*models.Video{
+				{ID: "ok1"}, {ID: "ok2"}, {ID: "broken"},
+			}}
+			analyzer := &fakeAnalyzer{errs: map[string]error{"broken": errors.New("model returned garbage")}}
+			cfg := &config.Config{YouTubeCurator: config.YouTubeCuratorConfig{RetryFailedAnalysis: retry}}
+			agent, _ := newTestAgent(t, cfg, source, analyzer)
+
+			if err := agent.RunOnce(context.Background(), nil); err != nil {
+				t.Fatalf("RunOnce() error: %v", err)
+			}
+
+			if !agent.videoTracker.IsAnalyzed("ok1") || !agent.videoTracker.IsAnalyzed("ok2") {
+				t.Error("Expected successfully analyzed videos to be tracked")
+			}
+			if tracked := agent.videoTracker.IsAnalyzed("broken"); tracked == retry {
+				t.Errorf("Expected failed video tracked=%v with retry_failed_analysis=%v, got %v", !retry, retry, tracked)
+			}
+		})
+	}
+}
+
+func TestRetryFailedAnalysisWhenMostVideosFail(t *testing.T) {
+	for _, retry := range []bool{true, false} {
+		t.Run(fmt.Sprintf("retry=%v", retry), func(t *testing.T) {
+			source := &fakeVideoSource{subscriptionVideos: []*models.Video{
+				{ID: "ok"}, {ID: "broken1"}, {ID: "broken2"}, {ID: "broken3"}, {ID: "later"},
+			}}
+			analyzer := &fakeAnalyzer{errs: map[string]error{
+				"broken1": errors.New("model returned garbage"),
+				"broken2": errors.New("model returned garbage"),
+				"broken3": errors.New("model returned garbage"),
+			}}
+			cfg := &config.Config{YouTubeCurator: config.YouTubeCuratorConfig{RetryFailedAnalysis: retry}}
+			agent, _ := newTestAgent(t, cfg, source, analyzer)
+
+			// Stops on the third failure out of five, before reaching "later"
+			if err := agent.RunOnce(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "too many analysis failures") {
+				t.Fatalf("Expected the run to stop on too many failures, got %v", err)
+			}
+
+			if !agent.videoTracker.IsAnalyzed("ok") {
+				t.Error("Expected the successfully analyzed video to be tracked despite the early stop")
+			}
+			for _, id := range []string{"broken1", "broken2", "broken3"} {
+				if tracked := agent.videoTracker.IsAnalyzed(id); tracked == retry {
+					t.Errorf("Expected failed video %s tracked=%v with retry_failed_analysis=%v, got %v", id, !retry, retry, tracked)
+				}
+			}
+			if agent.videoTracker.IsAnalyzed("later") {
+				t.Error("Expected videos after the early stop to stay untracked")
+			}
+		})
+	}
+}
+
+func TestRunOnceEmailsOnlyWhenRelevant(t *testing.T) {
+	tests := []struct {
+		name        string
+		results     map[string]*models.Analysis
+		expectEmail bool
+	}{
+		{
+			name:        "Relevant video sends report",
+			results:     map[string]*models.Analysis{"a": {IsRelevant: true, Summary: "Good", Score: 7}},
+			expectEmail: true,
+		},
+		{
+			name:        "No relevant videos sends nothing",
+			results:     map[string]*models.Analysis{"a": {IsRelevant: false, Summary: "Skip", Score: 3}},
+			expectEmail: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &fakeVideoSource{subscriptionVideos: []*models.Video{{ID: "a"}, {ID: "b"}}}
+			agent, sender := newTestAgent(t, &config.Config{}, source, &fakeAnalyzer{results: tt.results})
+
+			if err := agent.RunOnce(context.Background(), nil); err != nil {
+				t.Fatalf("RunOnce() error: %v", err)
+			}
+
+			sent := len(sender.reports) + len(sender.subjects)
+			if tt.expectEmail && sent != 1 {
+				t.Errorf("Expected exactly 1 email, got %d", sent)
+			}
+			if !tt.expectEmail && sent != 0 {
+				t.Errorf("Expected no email, got %d", sent)
+			}
+		})
+	}
+}
+
+func TestMaxAnalysisPerRunDefersRemainder(t *testing.T) {
+	base := time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{
+		{ID: "oldest", PublishedAt: base.Add(-4 * time.Hour)},
+		{ID: "newest", PublishedAt: base},
+		{ID: "popular", PublishedAt: base.Add(-time.Hour), ViewCount: 5000},
+		{ID: "quiet", PublishedAt: base.Add(-time.Hour), ViewCount: 10},
+		{ID: "older", PublishedAt: base.Add(-3 * time.Hour)},
+	}}
+	analyzer := &fakeAnalyzer{}
+	cfg := &config.Config{YouTubeCurator: config.YouTubeCuratorConfig{MaxAnalysisPerRun: 2}}
+	agent, _ := newTestAgent(t, cfg, source, analyzer)
+
+	var metrics YouTubeMetrics
+	events := &scheduler.AgentEvents{
+		OnSuccess: func(m scheduler.Metrics, duration time.Duration) {
+			metrics = m.(YouTubeMetrics)
+		},
+	}
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+
+	if strings.Join(analyzer.analyzed, ",") != "newest,popular" {
+		t.Errorf("Expected the 2 highest-priority videos analyzed, got %v", analyzer.analyzed)
+	}
+	if metrics.Deferred != 3 {
+		t.Errorf("Expected 3 deferred videos, got %d", metrics.Deferred)
+	}
+	for _, id := range []string{"quiet", "older", "oldest"} {
+		if agent.videoTracker.IsAnalyzed(id) {
+			t.Errorf("Deferred video %s should remain eligible for the next run", id)
+		}
+	}
+}
+
+func TestAnalysisOrderUnderCap(t *testing.T) {
+	base := time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		order    string
+		expected string
+	}{
+		{"", "newest,popular"},
+		{config.AnalysisOrderNewest, "newest,popular"},
+		{config.AnalysisOrderOldest, "oldest,older"},
+	}
+
+	for _, tt := range tests {
+		t.Run("order="+tt.order, func(t *testing.T) {
+			source := &fakeVideoSource{subscriptionVideos: []*models.Video{
+				{ID: "older", PublishedAt: base.Add(-3 * time.Hour)},
+				{ID: "newest", PublishedAt: base},
+				{ID: "popular", PublishedAt: base.Add(-time.Hour), ViewCount: 5000},
+				{ID: "oldest", PublishedAt: base.Add(-4 * time.Hour)},
+			}}
+			analyzer := &fakeAnalyzer{}
+			cfg := &config.Config{YouTubeCurator: config.YouTubeCuratorConfig{MaxAnalysisPerRun: 2, AnalysisOrder: tt.order}}
+			agent, _ := newTestAgent(t, cfg, source, analyzer)
+
+			if err := agent.RunOnce(context.Background(), nil); err != nil {
+				t.Fatalf("RunOnce() error: %v", err)
+			}
+			if got := strings.Join(analyzer.analyzed, ","); got != tt.expected {
+				t.Errorf("Expected %s analyzed, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunOnceRetriesFailedEmailWithoutReanalysis(t *testing.T) {
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{{ID: "good"}, {ID: "meh"}}}
+	analyzer := &fakeAnalyzer{results: map[string]*models.Analysis{
+		"good": {IsRelevant: true, Summary: "Useful", Score: 8},
+	}}
+	agent, sender := newTestAgent(t, &config.Config{}, source, analyzer)
+	sender.failures = 1
+
+	if err := agent.RunOnce(context.Background(), nil); err == nil {
+		t.Fatal("Expected first run to fail on email send")
+	}
+	if len(analyzer.analyzed) != 2 {
+		t.Fatalf("Expected 2 analyses in first run, got %d", len(analyzer.analyzed))
+	}
+
+	if err := agent.RunOnce(context.Background(), nil); err != nil {
+		t.Fatalf("Retry run error: %v", err)
+	}
+
+	if len(analyzer.analyzed) != 2 {
+		t.Errorf("Retry should not re-call the analyzer, got %d total calls", len(analyzer.analyzed))
+	}
+	if len(sender.reports) != 1 {
+		t.Fatalf("Expected the retry to send 1 email, got %d", len(sender.reports))
+	}
+	if report := sender.reports[0]; len(report.Videos) != 1 || report.Videos[0].Video.ID != "good" {
+		t.Errorf("Expected the previously selected video in the retried report")
+	}
+	if pending, _ := agent.digestStore.Pending(); len(pending) != 0 {
+		t.Errorf("Expected pending report cleared after successful send, got %d", len(pending))
+	}
+}
+
+func TestRunOnceNeverEmailsVideoTwice(t *testing.T) {
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{{ID: "good"}}}
+	analyzer := &fakeAnalyzer{results: map[string]*models.Analysis{
+		"good": {IsRelevant: true, Summary: "Useful", Score: 8},
+	}}
+	agent, sender := newTestAgent(t, &config.Config{}, source, analyzer)
+
+	if err := agent.RunOnce(context.Background(), nil); err != nil {
+		t.Fatalf("First run error: %v", err)
+	}
+	if len(sender.reports) != 1 {
+		t.Fatalf("Expected first run to send 1 email, got %d", len(sender.reports))
+	}
+
+	// Simulate lost analysis state (e.g. a second instance) so the video is re-analyzed
+	tracker, err := storage.NewVideoTracker(t.TempDir(), 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create video tracker: %v", err)
+	}
+	agent.videoTracker = tracker
+	source.subscriptionVideos = append(source.subscriptionVideos, &models.Video{ID: "fresh"})
+	analyzer.results["fresh"] = &models.Analysis{IsRelevant: true, Summary: "Also useful", Score: 7}
+
+	if err := agent.RunOnce(context.Background(), nil); err != nil {
+		t.Fatalf("Second run error: %v", err)
+	}
+	if len(sender.reports) != 2 {
+		t.Fatalf("Expected second run to send 1 more email, got %d total", len(sender.reports))
+	}
+	if report := sender.reports[1]; len(report.Videos) != 1 || report.Videos[0].Video.ID != "fresh" {
+		t.Errorf("Expected only the new video in the second email, got %d videos", len(report.Videos))
+	}
+}
+
+func TestRunOnceSkipsExcludedKeywords(t *testing.T) {
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{
+		{ID: "haul", Title: "My Huge Tech HAUL"},
+		{ID: "reaction", Title: "Compilers explained", Description: "A reaction video to a talk"},
+		{ID: "lecture", Title: "Compilers explained", Description: "Full lecture"},
+	}}
+	analyzer := &fakeAnalyzer{}
+	cfg := &config.Config{YouTubeCurator: config.YouTubeCuratorConfig{ExcludeKeywords: []string{"haul", "Reaction"}}}
+	agent, _ := newTestAgent(t, cfg, source, analyzer)
+
+	var metrics YouTubeMetrics
+	events := &scheduler.AgentEvents{
+		OnSuccess: func(m scheduler.Metrics, duration time.Duration) { metrics = m.(YouTubeMetrics) },
+	}
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+
+	if len(analyzer.analyzed) != 1 || analyzer.analyzed[0] != "lecture" {
+		t.Errorf("Expected only the lecture to be analyzed, got %v", analyzer.analyzed)
+	}
+	if metrics.SkippedByKeyword != 2 {
+		t.Errorf("Expected 2 videos skipped by keyword, got %d", metrics.SkippedByKeyword)
+	}
+	if metrics.VideosFound != 3 {
+		t.Errorf("Expected all 3 crawled videos to be counted as found, got %d", metrics.VideosFound)
+	}
+}
+
+func TestRunOnceDefersLiveAndUpcomingVideos(t *testing.T) {
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{
+		{ID: "stream", Title: "Live coding", Duration: "P0D", LiveBroadcastContent: "live"},
+		{ID: "premiere", Title: "Launch premiere", Duration: "P0D", LiveBroadcastContent: "upcoming"},
+		{ID: "upload", Title: "Regular upload", Duration: "PT12M", DurationSeconds: 720, LiveBroadcastContent: "none"},
+	}}
+	analyzer := &fakeAnalyzer{}
+	agent, _ := newTestAgent(t, &config.Config{}, source, analyzer)
+
+	var metrics YouTubeMetrics
+	events := &scheduler.AgentEvents{
+		OnSuccess: func(m scheduler.Metrics, duration time.Duration) { metrics = m.(YouTubeMetrics) },
+	}
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+	if strings.Join(analyzer.analyzed, ",") != "upload" {
+		t.Errorf("Expected only the regular upload to be analyzed, got %v", analyzer.analyzed)
+	}
+	if metrics.LiveDeferred != 2 {
+		t.Errorf("Expected 2 live or upcoming videos deferred, got %d", metrics.LiveDeferred)
+	}
+
+	// Once the stream has ended it is analyzed like any other video
+	source.subscriptionVideos[0].LiveBroadcastContent = "none"
+	source.subscriptionVideos[0].Duration, source.subscriptionVideos[0].DurationSeconds = "PT1H", 3600
+	if err := agent.RunOnce(context.Background(), events); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+	if strings.Join(analyzer.analyzed, ",") != "upload,stream" {
+		t.Errorf("Expected the finished stream to be analyzed on the next run, got %v", analyzer.analyzed)
+	}
+	if metrics.LiveDeferred != 1 {
+		t.Errorf("Expected the premiere to stay deferred, got %d", metrics.LiveDeferred)
+	}
+}
+
+func TestRunOnceRechecksDeferredVideosOutsideCrawlWindow(t *testing.T) {
+	premiere := &models.Video{ID: "premiere", Title: "Launch premiere", Duration: "P0D", LiveBroadcastContent: "upcoming"}
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{premiere}}
+	analyzer := &fakeAnalyzer{}
+	agent, _ := newTestAgent(t, &config.Config{}, source, analyzer)
+
+	if err := agent.RunOnce(context.Background(), nil); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+	if len(analyzer.analyzed) != 0 {
+		t.Fatalf("Expected the upcoming premiere to be deferred, got %v", analyzer.analyzed)
+	}
+
+	// The premiere leaves the crawl window while still upcoming, then airs
+	source.subscriptionVideos = nil
+	source.videosByID = map[string]*models.Video{"premiere": premiere}
+	if err := agent.RunOnce(context.Background(), nil); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+	if len(analyzer.analyzed) != 0 || strings.Join(source.requestedIDs, ",") != "premiere" {
+		t.Fatalf("Expected the still-upcoming premiere to be re-checked by ID and stay deferred, got analyzed %v, requested %v", analyzer.analyzed, source.requestedIDs)
+	}
+
+	source.videosByID["premiere"] = &models.Video{ID: "premiere", Title: "Launch premiere", Duration: "PT45M", DurationSeconds: 2700, LiveBroadcastContent: "none"}
+	if err := agent.RunOnce(context.Background(), nil); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+	if strings.Join(analyzer.analyzed, ",") != "premiere" {
+		t.Errorf("Expected the finished premiere to be analyzed, got %v", analyzer.analyzed)
+	}
+
+	// Once analyzed it is no longer re-checked
+	source.requestedIDs = nil
+	if err := agent.RunOnce(context.Background(), nil); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+	if len(source.requestedIDs) != 0 {
+		t.Errorf("Expected resolved videos to be dropped from the deferred list, got %v", source.requestedIDs)
+	}
+}
+
+func TestFilterByKeywords(t *testing.T) {
+	videos := []*models.Video{
+		{ID: "go", Title: "Golang generics deep dive"},
+		{ID: "k8s", Title: "Intro", Description: "Kubernetes operators from scratch"},
+		{ID: "go-reaction", Title: "Reacting to Golang hot takes", Description: "reaction"},
+		{ID: "cooking", Title: "Pasta night"},
+	}
+
+	tests := []struct {
+		name          string
+		include       []string
+		exclude       []string
+		expectKept    []string
+		expectSkipped int
+	}{
+		{"Include only", []string{"golang", "KUBERNETES"}, nil, []string{"go", "k8s", "go-reaction"}, 1},
+		{"Exclude wins over include", []string{"golang"}, []string{"reaction"}, []string{"go"}, 3},
+		{"Exclude only", nil, []string{"pasta"}, []string{"go", "k8s", "go-reaction"}, 1},
+		{"No filters", nil, nil, []string{"go", "k8s", "go-reaction", "cooking"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, skipped := filterByKeywords(videos, tt.include, tt.exclude)
+
+			var ids []string
+			for _, video := range kept {
+				ids = append(ids, video.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.expectKept, ",") {
+				t.Errorf("Expected kept %v, got %v", tt.expectKept, ids)
+			}
+			if skipped != tt.expectSkipped {
+				t.Errorf("Expected %d skipped, got %d", tt.expectSkipped, skipped)
+			}
+		})
+	}
+}
+
+func TestChannelScoreAdjustments(t *testing.T) {
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{
+		{ID: "boosted", ChannelTitle: "High Signal"},
+		{ID: "demoted", ChannelID: "UCnoisy", ChannelTitle: "Noisy"},
+		{ID: "clamped", ChannelTitle: "high signal"},
+		{ID: "untouched", ChannelTitle: "Other"},
+	}}
+	analyzer := &fakeAnalyzer{results: map[string]*models.Analysis{
+		"boosted":   {IsRelevant: true, Summary: "Borderline", Score: 5},
+		"demoted":   {IsRelevant: true, Summary: "Decent", Score: 7},
+		"clamped":   {IsRelevant: true, Summary: "Great", Score: 9},
+		"untouched": {IsRelevant: true, Summary: "Borderline", Score: 5},
+	}}
+	cfg := &config.Config{YouTubeCurator: config.YouTubeCuratorConfig{
+		ChannelScoreAdjustments: map[string]int{"High Signal": 2, "UCnoisy": -3},
+	}}
+	agent, sender := newTestAgent(t, cfg, source, analyzer)
+
+	if err := agent.RunOnce(context.Background(), nil); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+	if len(sender.reports) != 1 {
+		t.Fatalf("Expected 1 email, got %d", len(sender.reports))
+	}
+
+	scores := map[string]int{}
+	for _, analysis := range sender.reports[0].Videos {
+		scores[analysis.Video.ID] = analysis.Score
+	}
+	expected := map[string]int{"boosted": 7, "clamped": 10}
+	if !reflect.DeepEqual(scores, expected) {
+		t.Errorf("Expected relevant scores %v, got %v", expected, scores)
+	}
+	if score := analyzer.results["demoted"].Score; score != 4 {
+		t.Errorf("Expected demoted score 4, got %d", score)
+	}
+}
+
+func TestMentionBuckets(t *testing.T) {
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{
+		{ID: "selected"},
+		{ID: "threshold"},
+		{ID: "near-miss"},
+		{ID: "mention-floor"},
+		{ID: "below-floor"},
+		{ID: "irrelevant-high"},
+	}}
+	analyzer := &fakeAnalyzer{results: map[string]*models.Analysis{
+		"selected":        {IsRelevant: true, Summary: "Great", Score: 9},
+		"threshold":       {IsRelevant: true, Summary: "Good", Score: 7},
+		"near-miss":       {IsRelevant: true, Summary: "Almost", Score: 6},
+		"mention-floor":   {IsRelevant: false, Summary: "Maybe", Score: 5},
+		"below-floor":     {IsRelevant: false, Summary: "No", Score: 4},
+		"irrelevant-high": {IsRelevant: false, Summary: "Off-topic", Score: 8},
+	}}
+	cfg := &config.Config{YouTubeCurator: config.YouTubeCuratorConfig{MinScore: 7, MentionMinScore: 5}}
+	agent, sender := newTestAgent(t, cfg, source, analyzer)
+
+	if err := agent.RunOnce(context.Background(), nil); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+	if len(sender.reports) != 1 {
+		t.Fatalf("Expected 1 email, got %d", len(sender.reports))
+	}
+
+	ids := func(analyses []*models.Analysis) string {
+		var result []string
+		for _, analysis := range analyses {
+			result = append(result, analysis.Video.ID)
+		}
+		return strings.Join(result, ",")
+	}
+	report := sender.reports[0]
+	if got := ids(report.Videos); got != "selected,threshold" {
+		t.Errorf("Expected selected videos [selected threshold], got [%s]", got)
+	}
+	if got := ids(report.Mentions); got != "near-miss,mention-floor" {
+		t.Errorf("Expected mentions [near-miss mention-floor], got [%s]", got)
+	}
+	if report.Selected != 2 {
+		t.Errorf("Expected mentions not counted as selected, got %d", report.Selected)
+	}
+}
+
+func TestMentionsAloneSendNoEmail(t *testing.T) {
+	source := &fakeVideoSource{subscriptionVideos: []*models.Video{{ID: "near-miss"}}}
+	analyzer := &fakeAnalyzer{results: map[string]*models.Analysis{
+		"near-miss": {IsRelevant: true, Summary: "Almost", Score: 5},
+	}}
+	cfg := &config.Config{YouTubeCurator: config.YouTubeCuratorConfig{MentionMinScore: 4}}
+	agent, sender := newTestAgent(t, cfg, source, analyzer)
+
+	if err := agent.RunOnce(context.Background(), nil); err != nil {
+		t.Fatalf("RunOnce() error: %v", err)
+	}
+	if len(sender.reports) != 0 {
+		t.Errorf("Expected no email for mentions alone, got %d", len(sender.reports))
+	}
+}
@@ -2,28 +2,56 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"agent-stack/agents/youtube-curator"
 	"agent-stack/shared/config"
+	"agent-stack/shared/httpclient"
 	"agent-stack/shared/scheduler"
+	"agent-stack/shared/version"
 )
 
 func main() {
+	once := flag.Bool("once", false, "run a single curation pass and exit")
+	videos := flag.String("videos", "", "comma-separated video IDs or URLs to analyze instead of subscriptions (implies --once)")
+	serveAfterOnce := flag.Bool("serve-after-once", false, "with --once, keep serving health endpoints reflecting the run's outcome until stopped")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets redacted and exit")
+	flag.Parse()
+
+	log.Printf("%s %s", "youtube-curator", version.Get())
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	if *videos != "" {
+		cfg.YouTubeCurator.VideoIDs = strings.Split(*videos, ",")
+		*once = true
+	}
+
+	if *printConfig {
+		if err := cfg.WriteEffective(os.Stdout); err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
+	}
+
 	// Validate YouTube Curator specific configuration
 	if err := cfg.ValidateYouTubeCurator(); err != nil {
 		log.Fatalf("Failed to validate YouTube Curator configuration: %v", err)
 	}
 
+	if err := httpclient.SetProxy(cfg.ProxyURL); err != nil {
+		log.Fatalf("Failed to configure proxy: %v", err)
+	}
+
 	// Create context that responds to signals
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
@@ -32,18 +60,30 @@ func main() {
 	agent := youtubecurator.NewYouTubeAgent(cfg)
 	s := scheduler.New(cfg, agent)
 
-	if len(os.Args) > 1 && os.Args[1] == "--once" {
+	if *once {
 		fmt.Println("Running once...")
 		if err := agent.Initialize(); err != nil {
 			log.Fatalf("Failed to initialize agent: %v", err)
 		}
+		defer closeAgent(agent)
 
-		if err := s.RunOnce(ctx); err != nil {
-			log.Fatalf("Failed to run: %v", err)
-		}
+		runErr := s.RunOnce(ctx)
 
 		// Stop token refresher when running once
 		agent.StopTokenRefresher()
+
+		if *serveAfterOnce {
+			if runErr != nil {
+				log.Printf("Run failed: %v", runErr)
+			}
+			if err := s.ServeHealth(ctx); err != nil && ctx.Err() == nil {
+				log.Fatalf("Health server failed: %v", err)
+			}
+			return
+		}
+		if runErr != nil {
+			log.Fatalf("Failed to run: %v", runErr)
+		}
 		return
 	}
 
@@ -52,10 +92,17 @@ func main() {
 	// Ensure cleanup on exit
 	defer func() {
 		log.Println("Shutting down...")
-		agent.StopTokenRefresher()
+		closeAgent(agent)
 	}()
 
 	if err := s.Start(ctx); err != nil {
 		log.Fatalf("Scheduler failed: %v", err)
 	}
 }
+
+// closeAgent releases the agent's resources, logging rather than failing on errors
+func closeAgent(agent *youtubecurator.YouTubeAgent) {
+	if err := agent.Close(); err != nil {
+		log.Printf("Warning: Failed to close agent: %v", err)
+	}
+}
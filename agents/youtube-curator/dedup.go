@@ -0,0 +1,95 @@
+package youtubecurator
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"agent-stack/internal/models"
+)
+
+// dedupeSimilarTitles collapses analyses whose normalized titles are at least
+// threshold similar (0-1), keeping the highest-scored instance of each group in
+// its original position. Re-uploads and mirrors of the same talk or clip across
+// channels then appear only once. A threshold of 0 disables de-duplication.
+func dedupeSimilarTitles(analyses []*models.Analysis, threshold float64) ([]*models.Analysis, int) {
+	if threshold <= 0 || len(analyses) < 2 {
+		return analyses, 0
+	}
+
+	titles := make([]string, len(analyses))
+	order := make([]int, len(analyses))
+	for i, analysis := range analyses {
+		titles[i] = normalizeTitle(analysis.Video.Title)
+		order[i] = i
+	}
+	// Visit the best-scored instances first so they win their group
+	sort.SliceStable(order, func(a, b int) bool {
+		return analyses[order[a]].Score > analyses[order[b]].Score
+	})
+
+	keep := make([]bool, len(analyses))
+	var kept []int
+	for _, i := range order {
+		duplicate := false
+		for _, k := range kept {
+			if titleSimilarity(titles[i], titles[k]) >= threshold {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			keep[i] = true
+			kept = append(kept, i)
+		}
+	}
+
+	var result []*models.Analysis
+	for i, analysis := range analyses {
+		if keep[i] {
+			result = append(result, analysis)
+		}
+	}
+	return result, len(analyses) - len(result)
+}
+
+// normalizeTitle lowercases a title and reduces punctuation and whitespace runs
+// to single spaces so cosmetic differences don't affect similarity
+func normalizeTitle(title string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// titleSimilarity returns the Levenshtein ratio of two strings: 1 for identical
+// strings, 0 for completely different ones
+func titleSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein returns the edit distance between two rune slices
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
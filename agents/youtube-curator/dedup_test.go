@@ -0,0 +1,61 @@
+package youtubecurator
+
+import (
+	"math"
+	"testing"
+
+	"agent-stack/internal/models"
+)
+
+func TestTitleSimilarity(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected float64
+	}{
+		{"Identical", "go generics", "go generics", 1},
+		{"Both empty", "", "", 1},
+		{"One edit", "kitten", "sitten", 1 - 1.0/6},
+		{"Classic example", "kitten", "sitting", 1 - 3.0/7},
+		{"Completely different", "abc", "xyz", 0},
+		{"Multibyte runes count once", "café", "cafe", 0.75},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := titleSimilarity(tt.a, tt.b); math.Abs(got-tt.expected) > 1e-9 {
+				t.Errorf("Expected similarity %.4f, got %.4f", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	if got := normalizeTitle("  GopherCon 2025:  Keynote -- Rob Pike!! "); got != "gophercon 2025 keynote rob pike" {
+		t.Errorf("Unexpected normalized title %q", got)
+	}
+}
+
+func TestDedupeSimilarTitles(t *testing.T) {
+	analyses := []*models.Analysis{
+		{Video: &models.Video{ID: "mirror", Title: "GopherCon 2025: Keynote - Rob Pike", ChannelTitle: "Clips"}, Score: 7},
+		{Video: &models.Video{ID: "other", Title: "Rust async explained"}, Score: 8},
+		{Video: &models.Video{ID: "original", Title: "GopherCon 2025 Keynote | Rob Pike", ChannelTitle: "GopherCon"}, Score: 9},
+	}
+
+	result, removed := dedupeSimilarTitles(analyses, 0.85)
+	if removed != 1 {
+		t.Errorf("Expected 1 duplicate removed, got %d", removed)
+	}
+	if len(result) != 2 || result[0].Video.ID != "other" || result[1].Video.ID != "original" {
+		var ids []string
+		for _, analysis := range result {
+			ids = append(ids, analysis.Video.ID)
+		}
+		t.Errorf("Expected [other original], got %v", ids)
+	}
+
+	if result, removed := dedupeSimilarTitles(analyses, 0); removed != 0 || len(result) != 3 {
+		t.Errorf("Expected a zero threshold to disable de-duplication, got %d removed", removed)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -291,12 +292,17 @@ func (c *Client) RefreshToken() error {
 }
 
 func (c *Client) GetSubscriptionVideos(ctx context.Context, maxResults int64) ([]*models.Video, error) {
+	// Curated playlists replace the subscription crawl entirely
+	if len(c.config.PlaylistIDs) > 0 {
+		return c.GetPlaylistVideos(ctx, c.config.PlaylistIDs, maxResults)
+	}
+
 	since := time.Now().AddDate(0, 0, -1) // Last 24 hours
 
 	// Step 1: Get user's subscriptions
 	subscriptionsCall := c.service.Subscriptions.List([]string{"snippet"}).
 		Mine(true).
-		MaxResults(50)
+		MaxResults(int64(c.config.SubscriptionsPageSize))
 
 	subscriptionsResponse, err := subscriptionsCall.Do()
 	if err != nil {
@@ -363,24 +369,13 @@ func (c *Client) GetSubscriptionVideos(ctx context.Context, maxResults int64) ([
 	}
 
 	for channelID, playlistID := range channelUploadPlaylists {
-		playlistCall := c.service.PlaylistItems.List([]string{"snippet"}).
-			PlaylistId(playlistID).
-			MaxResults(videosPerChannel)
-
-		playlistResponse, err := playlistCall.Do()
+		// Filter videos from last 24 hours
+		videoIDs, err := c.playlistVideoIDs(ctx, playlistID, videosPerChannel, since)
 		if err != nil {
 			log.Printf("Failed to get playlist items for channel %s: %v", channelID, err)
 			continue
 		}
-
-		// Filter videos from last 24 hours
-		for _, item := range playlistResponse.Items {
-			if publishedAt, err := time.Parse(time.RFC3339, item.Snippet.PublishedAt); err == nil {
-				if publishedAt.After(since) {
-					allVideoIDs = append(allVideoIDs, item.Snippet.ResourceId.VideoId)
-				}
-			}
-		}
+		allVideoIDs = append(allVideoIDs, videoIDs...)
 
 		// Stop if we have enough videos
 		if int64(len(allVideoIDs)) >= maxResults {
@@ -401,17 +396,147 @@ func (c *Client) GetSubscriptionVideos(ctx context.Context, maxResults int64) ([
 	log.Printf("Found %d recent videos from subscriptions", len(allVideoIDs))
 
 	// Step 4: Get detailed video information in batches
+	allVideos := c.getVideoDetails(ctx, allVideoIDs)
+
+	log.Printf("Retrieved %d videos from %d subscriptions", len(allVideos), len(subscriptionsResponse.Items))
+
+	return allVideos, nil
+}
+
+// GetPlaylistVideos fetches the most recent items of specific playlists instead of
+// crawling subscriptions. Items are not limited to the last 24 hours since curated
+// playlists are filled at any pace; the video tracker skips already-analyzed ones.
+func (c *Client) GetPlaylistVideos(ctx context.Context, playlistIDs []string, maxResults int64) ([]*models.Video, error) {
+	perPlaylist := min(max(maxResults, 1), 50) // playlistItems.list pages hold at most 50
+
+	var allVideoIDs []string
+	var failed int
+	for _, playlistID := range playlistIDs {
+		videoIDs, err := c.playlistVideoIDs(ctx, playlistID, perPlaylist, time.Time{})
+		if err != nil {
+			log.Printf("Failed to get playlist items for playlist %s: %v", playlistID, err)
+			failed++
+			continue
+		}
+		allVideoIDs = append(allVideoIDs, videoIDs...)
+	}
+	if failed == len(playlistIDs) {
+		return nil, fmt.Errorf("failed to get items for all %d playlists", failed)
+	}
+
+	if len(allVideoIDs) == 0 {
+		log.Println("No videos found in configured playlists")
+		return []*models.Video{}, nil
+	}
+	if int64(len(allVideoIDs)) > maxResults {
+		allVideoIDs = allVideoIDs[:maxResults]
+	}
+
+	allVideos := c.getVideoDetails(ctx, allVideoIDs)
+	log.Printf("Retrieved %d videos from %d playlists", len(allVideos), len(playlistIDs)-failed)
+	return allVideos, nil
+}
+
+// playlistVideoIDs lists up to maxResults video IDs from a playlist, keeping only
+// items published after since unless it is zero
+func (c *Client) playlistVideoIDs(ctx context.Context, playlistID string, maxResults int64, since time.Time) ([]string, error) {
+	playlistResponse, err := c.service.PlaylistItems.List([]string{"snippet"}).
+		PlaylistId(playlistID).
+		MaxResults(maxResults).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+
+	var videoIDs []string
+	for _, item := range playlistResponse.Items {
+		if item.Snippet == nil || item.Snippet.ResourceId == nil || item.Snippet.ResourceId.VideoId == "" {
+			continue
+		}
+		if !since.IsZero() {
+			publishedAt, err := time.Parse(time.RFC3339, item.Snippet.PublishedAt)
+			if err != nil || !publishedAt.After(since) {
+				continue
+			}
+		}
+		videoIDs = append(videoIDs, item.Snippet.ResourceId.VideoId)
+	}
+	return videoIDs, nil
+}
+
+// thumbnailURL picks an email-friendly thumbnail, preferring the medium (320x180)
+// size, or returns empty if the snippet has none
+func thumbnailURL(thumbnails *youtube.ThumbnailDetails) string {
+	if thumbnails == nil {
+		return ""
+	}
+	for _, thumbnail := range []*youtube.Thumbnail{thumbnails.Medium, thumbnails.High, thumbnails.Default, thumbnails.Standard, thumbnails.Maxres} {
+		if thumbnail != nil && thumbnail.Url != "" {
+			return thumbnail.Url
+		}
+	}
+	return ""
+}
+
+// categoryNames maps YouTube's global video category IDs to their names
+var categoryNames = map[string]string{
+	"1":  "Film & Animation",
+	"2":  "Autos & Vehicles",
+	"10": "Music",
+	"15": "Pets & Animals",
+	"17": "Sports",
+	"19": "Travel & Events",
+	"20": "Gaming",
+	"22": "People & Blogs",
+	"23": "Comedy",
+	"24": "Entertainment",
+	"25": "News & Politics",
+	"26": "Howto & Style",
+	"27": "Education",
+	"28": "Science & Technology",
+	"29": "Nonprofits & Activism",
+}
+
+// CategoryName returns the name of a YouTube category ID, or the input unchanged
+// when it is not a known ID (e.g. already a name)
+func CategoryName(id string) string {
+	if name, ok := categoryNames[id]; ok {
+		return name
+	}
+	return id
+}
+
+// GetVideosByID fetches details for specific videos, bypassing the subscription crawl
+func (c *Client) GetVideosByID(ctx context.Context, ids []string) ([]*models.Video, error) {
+	if len(ids) == 0 {
+		return []*models.Video{}, nil
+	}
+
+	videos := c.getVideoDetails(ctx, ids)
+	if len(videos) == 0 {
+		return nil, fmt.Errorf("no details found for %d requested videos", len(ids))
+	}
+
+	log.Printf("Retrieved %d of %d requested videos", len(videos), len(ids))
+	return videos, nil
+}
+
+// getVideoDetails looks up snippet, duration and statistics for video IDs via videos.list
+func (c *Client) getVideoDetails(ctx context.Context, videoIDs []string) []*models.Video {
+	const batchSize = 50
 	var allVideos []*models.Video
 
-	for i := 0; i < len(allVideoIDs); i += batchSize {
+	for i := 0; i < len(videoIDs); i += batchSize {
 		end := i + batchSize
-		if end > len(allVideoIDs) {
-			end = len(allVideoIDs)
+		if end > len(videoIDs) {
+			end = len(videoIDs)
 		}
 
-		batchIDs := allVideoIDs[i:end]
+		batchIDs := videoIDs[i:end]
 		videosCall := c.service.Videos.List([]string{"snippet", "contentDetails", "statistics"}).
-			Id(strings.Join(batchIDs, ","))
+			Id(strings.Join(batchIDs, ",")).
+			Context(ctx)
 
 		videosResponse, err := videosCall.Do()
 		if err != nil {
@@ -426,9 +551,21 @@ func (c *Client) GetSubscriptionVideos(ctx context.Context, maxResults int64) ([
 				Title:           item.Snippet.Title,
 				Description:     item.Snippet.Description,
 				ChannelTitle:    item.Snippet.ChannelTitle,
+				ChannelID:       item.Snippet.ChannelId,
 				Duration:        item.ContentDetails.Duration,
 				DurationSeconds: durationSeconds,
 				URL:             fmt.Sprintf("https://www.youtube.com/watch?v=%s", item.Id),
+				ThumbnailURL:    thumbnailURL(item.Snippet.Thumbnails),
+				Tags:            item.Snippet.Tags,
+				Category:        CategoryName(item.Snippet.CategoryId),
+			}
+			// "live" or "upcoming" until a stream or premiere has finished
+			video.LiveBroadcastContent = item.Snippet.LiveBroadcastContent
+
+			// Prefer the spoken language; fall back to the title/description language
+			video.Language = item.Snippet.DefaultAudioLanguage
+			if video.Language == "" {
+				video.Language = item.Snippet.DefaultLanguage
 			}
 
 			if publishedAt, err := time.Parse(time.RFC3339, item.Snippet.PublishedAt); err == nil {
@@ -443,7 +580,30 @@ func (c *Client) GetSubscriptionVideos(ctx context.Context, maxResults int64) ([
 		}
 	}
 
-	log.Printf("Retrieved %d videos from %d subscriptions", len(allVideos), len(subscriptionsResponse.Items))
+	return allVideos
+}
 
-	return allVideos, nil
+// ParseVideoID extracts a video ID from a bare ID or a YouTube watch/short link
+func ParseVideoID(s string) string {
+	s = strings.TrimSpace(s)
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return s
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	switch host {
+	case "youtu.be":
+		return strings.Trim(u.Path, "/")
+	case "youtube.com", "m.youtube.com", "music.youtube.com":
+		if id := u.Query().Get("v"); id != "" {
+			return id
+		}
+		for _, prefix := range []string{"/shorts/", "/live/"} {
+			if strings.HasPrefix(u.Path, prefix) {
+				return strings.Trim(strings.TrimPrefix(u.Path, prefix), "/")
+			}
+		}
+	}
+	return s
 }
@@ -1,13 +1,22 @@
 package youtube
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"agent-stack/shared/config"
+
 	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+	"google.golang.org/api/youtube/v3"
 )
 
 func TestTokenSaver(t *testing.T) {
@@ -363,3 +372,159 @@ func TestTokenSaverConcurrency(t *testing.T) {
 	// If we get here without panicking, concurrency is handled correctly
 	t.Log("Concurrent token access handled successfully")
 }
+
+func TestParseVideoID(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{" dQw4w9WgXcQ ", "dQw4w9WgXcQ"},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"https://youtube.com/watch?v=dQw4w9WgXcQ&t=42", "dQw4w9WgXcQ"},
+		{"https://youtu.be/dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"https://www.youtube.com/shorts/abc123", "abc123"},
+		{"https://m.youtube.com/live/xyz789", "xyz789"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := ParseVideoID(tt.input); got != tt.expected {
+				t.Errorf("ParseVideoID(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+// newFakeYouTubeClient serves canned playlistItems and videos responses and
+// records which playlists were requested
+func newFakeYouTubeClient(t *testing.T, cfg *config.YouTubeConfig, playlists map[string][]string) (*Client, *[]string) {
+	t.Helper()
+
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/playlistItems"):
+			playlistID := r.URL.Query().Get("playlistId")
+			requested = append(requested, playlistID)
+			var items []string
+			for _, id := range playlists[playlistID] {
+				items = append(items, fmt.Sprintf(`{"snippet": {"publishedAt": "2020-01-01T00:00:00Z", "resourceId": {"kind": "youtube#video", "videoId": %q}}}`, id))
+			}
+			fmt.Fprintf(w, `{"items": [%s]}`, strings.Join(items, ","))
+		case strings.HasSuffix(r.URL.Path, "/videos"):
+			var items []string
+			for _, id := range strings.Split(r.URL.Query().Get("id"), ",") {
+				items = append(items, fmt.Sprintf(`{"id": %q, "snippet": {"title": "Video %s", "channelTitle": "Channel", "categoryId": "27", "tags": ["go"]}, "contentDetails": {"duration": "PT10M"}, "statistics": {"viewCount": "42"}}`, id, id))
+			}
+			fmt.Fprintf(w, `{"items": [%s]}`, strings.Join(items, ","))
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	service, err := youtube.NewService(context.Background(), option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("Failed to create YouTube service: %v", err)
+	}
+	return &Client{service: service, config: cfg}, &requested
+}
+
+func TestGetSubscriptionVideosUsesPlaylists(t *testing.T) {
+	cfg := &config.YouTubeConfig{PlaylistIDs: []string{"PLwatch", "PLtalks"}}
+	client, requested := newFakeYouTubeClient(t, cfg, map[string][]string{
+		"PLwatch": {"vid1", "vid2"},
+		"PLtalks": {"vid3"},
+	})
+
+	videos, err := client.GetSubscriptionVideos(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("GetSubscriptionVideos() error: %v", err)
+	}
+
+	if strings.Join(*requested, ",") != "PLwatch,PLtalks" {
+		t.Errorf("Expected both playlists to be requested, got %v", *requested)
+	}
+	var ids []string
+	for _, video := range videos {
+		ids = append(ids, video.ID)
+	}
+	// Old items are kept: curated playlists are not limited to the last 24 hours
+	if strings.Join(ids, ",") != "vid1,vid2,vid3" {
+		t.Errorf("Expected videos [vid1 vid2 vid3], got %v", ids)
+	}
+	if len(videos) > 0 && (videos[0].Title != "Video vid1" || videos[0].DurationSeconds != 600) {
+		t.Errorf("Expected video details to be fetched, got %+v", videos[0])
+	}
+	if len(videos) > 0 && (videos[0].Category != "Education" || strings.Join(videos[0].Tags, ",") != "go") {
+		t.Errorf("Expected tags and category name to be populated, got %+v", videos[0])
+	}
+}
+
+func TestGetSubscriptionVideosUsesConfiguredPageSize(t *testing.T) {
+	var maxResults string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/subscriptions") {
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+		maxResults = r.URL.Query().Get("maxResults")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"items": []}`)
+	}))
+	defer server.Close()
+
+	service, err := youtube.NewService(context.Background(), option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatalf("Failed to create YouTube service: %v", err)
+	}
+	client := &Client{service: service, config: &config.YouTubeConfig{SubscriptionsPageSize: 12}}
+
+	if _, err := client.GetSubscriptionVideos(context.Background(), 10); err != nil {
+		t.Fatalf("GetSubscriptionVideos() error: %v", err)
+	}
+	if maxResults != "12" {
+		t.Errorf("Expected subscriptions requested with maxResults=12, got %q", maxResults)
+	}
+}
+
+func TestGetPlaylistVideosCapsResults(t *testing.T) {
+	client, _ := newFakeYouTubeClient(t, &config.YouTubeConfig{}, map[string][]string{
+		"PLwatch": {"vid1", "vid2", "vid3"},
+	})
+
+	videos, err := client.GetPlaylistVideos(context.Background(), []string{"PLwatch"}, 2)
+	if err != nil {
+		t.Fatalf("GetPlaylistVideos() error: %v", err)
+	}
+	if len(videos) != 2 {
+		t.Errorf("Expected 2 videos, got %d", len(videos))
+	}
+}
+
+func TestThumbnailURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		thumbnails *youtube.ThumbnailDetails
+		expected   string
+	}{
+		{"No thumbnails", nil, ""},
+		{"Prefers medium", &youtube.ThumbnailDetails{
+			Default: &youtube.Thumbnail{Url: "https://i.ytimg.com/vi/abc/default.jpg"},
+			Medium:  &youtube.Thumbnail{Url: "https://i.ytimg.com/vi/abc/mqdefault.jpg"},
+		}, "https://i.ytimg.com/vi/abc/mqdefault.jpg"},
+		{"Falls back to default", &youtube.ThumbnailDetails{
+			Default: &youtube.Thumbnail{Url: "https://i.ytimg.com/vi/abc/default.jpg"},
+		}, "https://i.ytimg.com/vi/abc/default.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := thumbnailURL(tt.thumbnails); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
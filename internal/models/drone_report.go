@@ -8,6 +8,38 @@ type DroneFlightReport struct {
 	LocationName    string           `json:"location_name"`
 	WeatherAnalysis *WeatherAnalysis `json:"weather_analysis"`
 	TFRCheck        *TFRCheck        `json:"tfr_check"`
-	IsFlyable       bool             `json:"is_flyable"`
+	IsFlyable       bool             `json:"is_flyable"` // true unless Tier is TierNoGo
+	Tier            FlightTier       `json:"tier"`
 	Summary         string           `json:"summary"`
+	Reasons         []string         `json:"reasons,omitempty"` // why conditions are not flyable
+	// Debug is set when debug_report is enabled and rendered as a collapsible email footer
+	Debug *DroneReportDebug `json:"debug,omitempty"`
+}
+
+// DroneReportDebug holds the raw and parsed weather data behind a report, as indented JSON
+type DroneReportDebug struct {
+	RawResponse   string `json:"raw_response"`
+	ParsedWeather string `json:"parsed_weather"`
+}
+
+// DroneCheckSnapshot captures the inputs and outcome of the most recent flight check for debugging
+type DroneCheckSnapshot struct {
+	CheckedAt       time.Time        `json:"checked_at"`
+	IsFlyable       bool             `json:"is_flyable"`
+	Tier            FlightTier       `json:"tier"`
+	Reasons         []string         `json:"reasons"`
+	WeatherAnalysis *WeatherAnalysis `json:"weather_analysis"`
+	TFRCheck        *TFRCheck        `json:"tfr_check"`
+}
+
+// NoFlyStreak describes a run of consecutive no-fly checks for the heads-up email
+type NoFlyStreak struct {
+	LocationName string    `json:"location_name"`
+	Runs         int       `json:"runs"`
+	Since        time.Time `json:"since"`
+	Date         time.Time `json:"date"`
+	Reasons      []string  `json:"reasons"` // why the latest check was not flyable
+	// NextFlyableDays and OutlookDays carry the multi-day outlook from the latest check
+	NextFlyableDays []DailyForecast `json:"next_flyable_days,omitempty"`
+	OutlookDays     int             `json:"outlook_days,omitempty"`
 }
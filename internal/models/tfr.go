@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // TFR represents a Temporary Flight Restriction from FAA API
 type TFR struct {
@@ -11,15 +14,31 @@ type TFR struct {
 	EndTime   time.Time `json:"end_time"`
 	Latitude  float64   `json:"latitude"`
 	Longitude float64   `json:"longitude"`
-	Radius    float64   `json:"radius"` // nautical miles
+	Radius    float64   `json:"radius"`  // nautical miles
+	AltMin    int       `json:"alt_min"` // feet, lower bound of restricted airspace
+	AltMax    int       `json:"alt_max"` // feet, upper bound; 0 when unknown (treated as unlimited)
 	Reason    string    `json:"reason"`
+	// DatesUnknown is set when the effective dates could not be parsed and were assumed
+	DatesUnknown bool `json:"dates_unknown,omitempty"`
+	// DistanceMiles is the distance from the checked location to the TFR center
+	DistanceMiles float64 `json:"distance_miles"`
 }
 
 // TFRCheck contains the results of checking for TFRs in the area
 type TFRCheck struct {
 	HasActiveTFRs bool      `json:"has_active_tfrs"`
 	ActiveTFRs    []*TFR    `json:"active_tfrs"`
-	CheckRadius   int       `json:"check_radius"` // miles
+	CheckRadius   int       `json:"check_radius"` // in RadiusUnit
+	RadiusUnit    string    `json:"radius_unit"`  // "miles" or "km", as configured
 	CheckTime     time.Time `json:"check_time"`
 	Summary       string    `json:"summary"` // e.g., "None active within 25 miles"
 }
+
+// Distance formats how far a TFR is from the checked location in the check's
+// unit, e.g. "3.2 mi" or "5.1 km"
+func (c *TFRCheck) Distance(tfr *TFR) string {
+	if c.RadiusUnit == "km" {
+		return fmt.Sprintf("%.1f km", tfr.DistanceMiles*1.609344)
+	}
+	return fmt.Sprintf("%.1f mi", tfr.DistanceMiles)
+}
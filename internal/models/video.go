@@ -7,11 +7,24 @@ type Video struct {
 	Title           string    `json:"title"`
 	Description     string    `json:"description"`
 	ChannelTitle    string    `json:"channel_title"`
+	ChannelID       string    `json:"channel_id,omitempty"`
 	PublishedAt     time.Time `json:"published_at"`
 	Duration        string    `json:"duration"`
 	DurationSeconds int       `json:"duration_seconds"`
 	ViewCount       int64     `json:"view_count"`
 	URL             string    `json:"url"`
+	ThumbnailURL    string    `json:"thumbnail_url,omitempty"`
+	Language        string    `json:"language,omitempty"` // BCP-47 code from YouTube metadata, may be empty
+	Tags            []string  `json:"tags,omitempty"`
+	Category        string    `json:"category,omitempty"` // category name, or the raw ID when unknown
+	// LiveBroadcastContent is YouTube's "live", "upcoming" or "none"; empty when unknown
+	LiveBroadcastContent string `json:"live_broadcast_content,omitempty"`
+}
+
+// IsLiveOrUpcoming reports whether the video is a live stream in progress or a
+// scheduled premiere/stream, which has no complete recording to analyze yet
+func (v *Video) IsLiveOrUpcoming() bool {
+	return v.LiveBroadcastContent == "live" || v.LiveBroadcastContent == "upcoming"
 }
 
 type Analysis struct {
@@ -28,4 +41,8 @@ type EmailReport struct {
 	Videos   []*Analysis `json:"videos"`
 	Total    int         `json:"total_analyzed"`
 	Selected int         `json:"selected"`
+	// Mentions scored just below the relevance threshold and are listed separately
+	Mentions []*Analysis `json:"mentions,omitempty"`
+	// GroupByChannel renders Videos under a header per channel
+	GroupByChannel bool `json:"group_by_channel,omitempty"`
 }
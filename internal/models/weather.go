@@ -1,34 +1,91 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // HourlyForecast represents hourly weather forecast data
 type HourlyForecast struct {
 	Times      []time.Time `json:"times"`
 	WindSpeeds []float64   `json:"wind_speeds"` // km/h
 	WindGusts  []float64   `json:"wind_gusts"`  // km/h
+	// PrecipProbabilities is the chance of precipitation per hour in percent; empty if unavailable
+	PrecipProbabilities []float64 `json:"precip_probabilities,omitempty"`
+}
+
+// DailyForecast holds one day's aggregates from the daily forecast
+type DailyForecast struct {
+	Date                 time.Time `json:"date"`
+	MaxWindKmh           float64   `json:"max_wind_kmh"`
+	MaxGustsKmh          float64   `json:"max_gusts_kmh"`
+	MinTempC             float64   `json:"min_temp_c"`
+	MaxTempC             float64   `json:"max_temp_c"`
+	PrecipitationMm      float64   `json:"precipitation_mm"`       // daily total
+	PrecipProbabilityPct float64   `json:"precip_probability_pct"` // highest hourly chance; 0 if unavailable
 }
 
 // WeatherData represents current weather conditions from Open-Meteo API
 type WeatherData struct {
-	Latitude      float64         `json:"latitude"`
-	Longitude     float64         `json:"longitude"`
-	Temperature   float64         `json:"temperature"`    // Celsius
-	WindSpeed     float64         `json:"wind_speed"`     // km/h (changed from m/s)
-	WindDir       int             `json:"wind_direction"` // degrees
-	Visibility    float64         `json:"visibility"`     // km
-	Precipitation float64         `json:"precipitation"`  // mm
-	Time          time.Time       `json:"time"`
-	Timezone      string          `json:"timezone"`              // IANA timezone (e.g., "America/Los_Angeles")
-	HourlyData    *HourlyForecast `json:"hourly_data,omitempty"` // Hourly forecast data
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Temperature float64 `json:"temperature"` // Celsius
+	// ApparentTemperature is the feels-like temperature in Celsius (wind chill / humidity adjusted)
+	ApparentTemperature float64         `json:"apparent_temperature"`
+	WindSpeed           float64         `json:"wind_speed"`     // km/h (changed from m/s)
+	WindDir             int             `json:"wind_direction"` // degrees
+	Visibility          float64         `json:"visibility"`     // km
+	Precipitation       float64         `json:"precipitation"`  // mm
+	Time                time.Time       `json:"time"`
+	Timezone            string          `json:"timezone"`              // IANA timezone (e.g., "America/Los_Angeles")
+	HourlyData          *HourlyForecast `json:"hourly_data,omitempty"` // Hourly forecast data
+	DailyData           []DailyForecast `json:"daily_data,omitempty"`  // Daily aggregates, when forecast_days is set
+	// RawResponse is the provider's response body exactly as received, for debug reports
+	RawResponse json.RawMessage `json:"-"`
 }
 
+// FlightTier grades conditions beyond a plain flyable/not-flyable decision
+type FlightTier string
+
+const (
+	TierGood    FlightTier = "good"    // every metric comfortably within limits
+	TierCaution FlightTier = "caution" // flyable, but some metric is close to its limit
+	TierNoGo    FlightTier = "no-go"   // some limit is exceeded
+)
+
 // WeatherAnalysis contains the analysis of weather conditions for drone flying
 type WeatherAnalysis struct {
 	Data            *WeatherData `json:"data"`
 	IsFlyable       bool         `json:"is_flyable"`
 	Reasons         []string     `json:"reasons"`
-	AvgWindSpeedKmh float64      `json:"avg_wind_speed_kmh"` // Average wind speed over 24h forecast
-	AvgWindGustsKmh float64      `json:"avg_wind_gusts_kmh"` // Average wind gusts over 24h forecast
-	WindForecast    string       `json:"wind_forecast"`      // e.g., "Light and stable"
+	Highlights      []string     `json:"highlights"`         // Thresholds passed and by how much
+	Cautions        []string     `json:"cautions,omitempty"` // Thresholds passed by less than the caution margin
+	Tier            FlightTier   `json:"tier"`
+	AvgWindSpeedKmh float64      `json:"avg_wind_speed_kmh"` // Average wind speed over the forecast horizon
+	AvgWindGustsKmh float64      `json:"avg_wind_gusts_kmh"` // Average wind gusts over the forecast horizon
+	AvgWindHours    int          `json:"avg_wind_hours"`     // Number of forecast hours averaged
+	// MaxPrecipProbabilityPct is the highest hourly chance of precipitation over PrecipProbabilityHours
+	MaxPrecipProbabilityPct float64 `json:"max_precip_probability_pct"`
+	PrecipProbabilityHours  int     `json:"precip_probability_hours"` // 0 when the forecast had no probabilities
+	WindForecast            string  `json:"wind_forecast"`            // e.g., "Light and stable"
+	// BestWindow is the longest stretch of forecast hours with flyable wind, if
+	// one lasts at least the configured minimum
+	BestWindow *FlightWindow `json:"best_window,omitempty"`
+	// NextFlyableDays lists the days after today whose daily aggregates are within
+	// limits, out of the OutlookDays evaluated (0 when forecast_days is disabled)
+	NextFlyableDays []DailyForecast `json:"next_flyable_days,omitempty"`
+	OutlookDays     int             `json:"outlook_days,omitempty"`
+}
+
+// FlightWindow is a contiguous stretch of forecast hours with wind under the limit.
+// End is exclusive: the start of the hour after the last good hour.
+type FlightWindow struct {
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	MaxWindKmh float64   `json:"max_wind_kmh"`
+}
+
+// Duration returns the length of the window
+func (w *FlightWindow) Duration() time.Duration {
+	return w.End.Sub(w.Start)
 }
@@ -2,11 +2,17 @@ package ai
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"agent-stack/internal/models"
 	"agent-stack/shared/config"
@@ -14,12 +20,39 @@ import (
 	"google.golang.org/genai"
 )
 
+// contentGenerator is the subset of the Gemini models API used by the analyzer
+type contentGenerator interface {
+	GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error)
+}
+
 type Analyzer struct {
-	client            *genai.Client
+	generator         contentGenerator
 	model             string
-	guidelines        []string
+	fallbackModels    []string // tried in order when the primary model errors or returns nothing
+	guidelines        []config.Criterion
 	longVideoMinutes  int
 	shortVideoMinutes int
+	minVideoSeconds   int // overrides shortVideoMinutes when positive
+	mediaMIMEType     string
+	metadataOnly      bool // never send the video itself, only its metadata
+	limiter           *rateLimiter
+}
+
+// promptVersion is bumped whenever buildAnalysisPrompt changes in a way that
+// should invalidate earlier analyses
+const promptVersion = 1
+
+// SchemaVersion fingerprints everything that shapes an analysis result: the
+// prompt template, the guidelines, metadata-only mode and the user's
+// youtube_curator.analysis_version. Videos tracked under another version are
+// analyzed again.
+func SchemaVersion(cfg *config.Config) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "prompt=%d\nmetadata_only=%t\nversion=%s\n", promptVersion, cfg.YouTubeCurator.AI.MetadataOnly, cfg.YouTubeCurator.AnalysisVersion)
+	for _, criterion := range cfg.YouTubeCurator.Guidelines.Criteria {
+		fmt.Fprintf(h, "criterion=%q priority=%q\n", criterion.Text, criterion.Priority)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
 }
 
 func NewAnalyzer(cfg *config.Config) (*Analyzer, error) {
@@ -34,16 +67,31 @@ func NewAnalyzer(cfg *config.Config) (*Analyzer, error) {
 	}
 
 	a := &Analyzer{
-		client:            client,
+		generator:         client.Models,
 		model:             cfg.YouTubeCurator.AI.Model,
+		fallbackModels:    cfg.YouTubeCurator.AI.FallbackModels,
 		guidelines:        cfg.YouTubeCurator.Guidelines.Criteria,
 		longVideoMinutes:  cfg.YouTubeCurator.Video.LongMinutes,
 		shortVideoMinutes: cfg.YouTubeCurator.Video.ShortMinutes,
+		minVideoSeconds:   cfg.YouTubeCurator.Video.MinDurationSeconds,
+		mediaMIMEType:     cfg.YouTubeCurator.AI.MediaMIMEType,
+		metadataOnly:      cfg.YouTubeCurator.AI.MetadataOnly,
+		limiter:           newRateLimiter(cfg.YouTubeCurator.AI.RequestsPerMinute, 1),
 	}
 
 	return a, nil
 }
 
+// Ping sends a minimal request to the primary model, verifying the API key and
+// model name without analyzing anything
+func (a *Analyzer) Ping(ctx context.Context) error {
+	contents := []*genai.Content{genai.NewContentFromText("Reply with OK.", genai.RoleUser)}
+	if _, err := a.generateWithModel(ctx, a.model, contents); err != nil {
+		return fmt.Errorf("AI self-test against model %s failed (check gemini_api_key and ai.model): %w", a.model, err)
+	}
+	return nil
+}
+
 func (a *Analyzer) AnalyzeVideo(ctx context.Context, video *models.Video) (*models.Analysis, error) {
 	if video == nil {
 		return nil, fmt.Errorf("video cannot be nil")
@@ -56,12 +104,15 @@ func (a *Analyzer) AnalyzeVideo(ctx context.Context, video *models.Video) (*mode
 	durationMinutes := video.DurationSeconds / 60
 
 	// Skip short videos if configured
-	if a.shortVideoMinutes > 0 && durationMinutes > 0 && durationMinutes <= a.shortVideoMinutes {
-		log.Printf("Skipping short video: %s (%d minutes) - %s", video.Title, durationMinutes, video.ChannelTitle)
+	if a.isShortVideo(video) {
+		log.Printf("Skipping short video: %s (%d seconds) - %s", video.Title, video.DurationSeconds, video.ChannelTitle)
 		return nil, ErrShortVideoSkipped
 	}
 	useFallback := a.longVideoMinutes > 0 && durationMinutes > a.longVideoMinutes
 
+	if a.metadataOnly {
+		return a.analyzeMetadataOnly(ctx, video)
+	}
 	if useFallback {
 		log.Printf("Using metadata-only analysis for long video: %s (%d minutes) - %s", video.Title, durationMinutes, video.ChannelTitle)
 		return a.analyzeMetadataOnly(ctx, video)
@@ -71,15 +122,18 @@ func (a *Analyzer) AnalyzeVideo(ctx context.Context, video *models.Video) (*mode
 
 	parts := []*genai.Part{
 		genai.NewPartFromText(prompt),
-		genai.NewPartFromURI(video.URL, "video/mp4"),
+		a.buildMediaPart(video.URL),
 	}
 
 	contents := []*genai.Content{
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
 
-	result, err := a.client.Models.GenerateContent(ctx, a.model, contents, nil)
+	result, err := a.generateContent(ctx, contents)
 	if err != nil {
+		if errors.Is(err, ErrQuotaExceeded) {
+			return nil, fmt.Errorf("failed to analyze video %s: %w", video.ID, err)
+		}
 		// If token limit error, fallback to metadata analysis
 		if strings.Contains(err.Error(), "token count") || strings.Contains(err.Error(), "INVALID_ARGUMENT") {
 			log.Printf("Token limit exceeded for video %s (%d minutes), falling back to metadata-only analysis", video.Title, durationMinutes)
@@ -102,11 +156,141 @@ func (a *Analyzer) AnalyzeVideo(ctx context.Context, video *models.Video) (*mode
 	return analysis, nil
 }
 
+// isShortVideo reports whether the video falls below the configured duration floor:
+// min_duration_seconds when set, otherwise short_minutes. Unknown durations are kept.
+func (a *Analyzer) isShortVideo(video *models.Video) bool {
+	if video.DurationSeconds <= 0 {
+		return false
+	}
+	if a.minVideoSeconds > 0 {
+		return video.DurationSeconds < a.minVideoSeconds
+	}
+	durationMinutes := video.DurationSeconds / 60
+	return a.shortVideoMinutes > 0 && durationMinutes > 0 && durationMinutes <= a.shortVideoMinutes
+}
+
+// generateContent calls the primary model and, when it errors or returns an empty
+// response, each fallback model in order. Every attempt waits for the rate limiter
+// first so that all requests (including metadata fallbacks) count toward the quota.
+// If no model succeeds, the last model's outcome is returned, unless an earlier model
+// hit the quota: then ErrQuotaExceeded is returned so callers back off instead.
+func (a *Analyzer) generateContent(ctx context.Context, contents []*genai.Content) (*genai.GenerateContentResponse, error) {
+	chain := append([]string{a.model}, a.fallbackModels...)
+
+	var (
+		result   *genai.GenerateContentResponse
+		err      error
+		quotaErr error
+	)
+	for i, model := range chain {
+		result, err = a.generateWithModel(ctx, model, contents)
+		if err == nil && result != nil && result.Text() != "" {
+			return result, nil
+		}
+		if quotaErr == nil && errors.Is(err, ErrQuotaExceeded) {
+			quotaErr = err
+		}
+		if ctx.Err() != nil || i == len(chain)-1 {
+			break
+		}
+
+		reason := "empty response"
+		if err != nil {
+			reason = err.Error()
+		}
+		log.Printf("Model %s failed (%s), trying fallback model %s", model, reason, chain[i+1])
+	}
+	if quotaErr != nil && !errors.Is(err, ErrQuotaExceeded) {
+		if err != nil {
+			return nil, fmt.Errorf("%w (fallback model also failed: %v)", quotaErr, err)
+		}
+		return nil, quotaErr
+	}
+	return result, err
+}
+
+// generateWithModel makes a single rate-limited call to the given model
+func (a *Analyzer) generateWithModel(ctx context.Context, model string, contents []*genai.Content) (*genai.GenerateContentResponse, error) {
+	if err := a.limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limiter wait aborted: %w", err)
+	}
+	result, err := a.generator.GenerateContent(ctx, model, contents, nil)
+	if err != nil && isQuotaError(err) {
+		return nil, fmt.Errorf("%w: %v", ErrQuotaExceeded, err)
+	}
+	return result, err
+}
+
+// isQuotaError reports whether err is a Gemini rate-limit or quota response
+func isQuotaError(err error) bool {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == http.StatusTooManyRequests || strings.Contains(apiErr.Status, "RESOURCE_EXHAUSTED")
+}
+
+// buildMediaPart creates the video part for a URL. YouTube links are passed as
+// plain file URIs, which Gemini handles natively; other sources use the configured MIME type.
+func (a *Analyzer) buildMediaPart(videoURL string) *genai.Part {
+	if isYouTubeURL(videoURL) {
+		return &genai.Part{FileData: &genai.FileData{FileURI: videoURL}}
+	}
+
+	mimeType := a.mediaMIMEType
+	if mimeType == "" {
+		mimeType = "video/mp4"
+	}
+	return genai.NewPartFromURI(videoURL, mimeType)
+}
+
+// isYouTubeURL reports whether rawURL points at a YouTube video page
+func isYouTubeURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	switch host {
+	case "youtube.com", "m.youtube.com", "music.youtube.com":
+		return u.Path == "/watch" || strings.HasPrefix(u.Path, "/shorts/") || strings.HasPrefix(u.Path, "/live/")
+	case "youtu.be":
+		return len(u.Path) > 1
+	}
+	return false
+}
+
 // ErrShortVideoSkipped signals the caller that the video was intentionally skipped due to duration
 var ErrShortVideoSkipped = errors.New("short video skipped")
 
+// ErrQuotaExceeded signals that Gemini rejected the request for rate-limit or quota
+// reasons; the video itself is fine and can be retried later
+var ErrQuotaExceeded = errors.New("gemini quota exceeded")
+
+// formatCriteria renders the guidelines as a bulleted list. Weighted criteria are
+// tagged with their priority, followed by a note on how to factor it into the score.
+func formatCriteria(criteria []config.Criterion) string {
+	var b strings.Builder
+	weighted := false
+	for i, criterion := range criteria {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("- " + criterion.Text)
+		if criterion.Priority != "" {
+			weighted = true
+			fmt.Fprintf(&b, " (%s priority)", criterion.Priority)
+		}
+	}
+	if weighted {
+		b.WriteString("\n\nCriteria marked high priority matter most: weigh them most heavily in the score, then medium, then low or unmarked ones.")
+	}
+	return b.String()
+}
+
 func (a *Analyzer) buildAnalysisPrompt(video *models.Video, metadataOnly bool) string {
-	guidelines := strings.Join(a.guidelines, "\n- ")
+	guidelines := formatCriteria(a.guidelines)
 
 	var analysisType, instructions, summaryDesc, reasoningDesc string
 	var descriptionLength int
@@ -142,11 +326,13 @@ func (a *Analyzer) buildAnalysisPrompt(video *models.Video, metadataOnly bool) s
 	prompt := fmt.Sprintf(`You are an AI assistant that %s to determine if they are worth watching based on specific criteria.
 
 EVALUATION CRITERIA:
-- %s
+%s
 
 VIDEO METADATA:
 Title: %s
 Channel: %s
+Category: %s
+Tags: %s
 Description: %s
 Duration: %s%s
 View Count: %d
@@ -166,7 +352,9 @@ Please provide your analysis in the following JSON format:
 		guidelines,
 		video.Title,
 		video.ChannelTitle,
-		truncateString(video.Description, descriptionLength),
+		orNone(video.Category),
+		orNone(formatTags(video.Tags)),
+		sanitizeDescription(video.Description, descriptionLength),
 		video.Duration,
 		metadataNote,
 		video.ViewCount,
@@ -243,7 +431,7 @@ func (a *Analyzer) analyzeMetadataOnly(ctx context.Context, video *models.Video)
 		genai.NewContentFromParts(parts, genai.RoleUser),
 	}
 
-	result, err := a.client.Models.GenerateContent(ctx, a.model, contents, nil)
+	result, err := a.generateContent(ctx, contents)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze video metadata %s: %w", video.ID, err)
 	}
@@ -309,9 +497,65 @@ func (a *Analyzer) sanitizeJSON(jsonStr string) string {
 	return strings.Join(sanitizedLines, "\n")
 }
 
-func truncateString(s string, maxLength int) string {
-	if len(s) <= maxLength {
-		return s
+// maxPromptTags caps how many of a video's tags are listed in the prompt
+const maxPromptTags = 20
+
+// formatTags joins up to maxPromptTags tags for the prompt, cleaned like descriptions
+func formatTags(tags []string) string {
+	if len(tags) > maxPromptTags {
+		tags = tags[:maxPromptTags]
+	}
+	cleaned := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = sanitizeDescription(tag, maxTokenRunes); tag != "" {
+			cleaned = append(cleaned, tag)
+		}
+	}
+	return strings.Join(cleaned, ", ")
+}
+
+// orNone stands in for missing metadata in the prompt
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// maxTokenRunes caps a single whitespace-free run of text in the prompt, such as a
+// long tracking URL or an encoded blob, which costs many tokens for no meaning
+const maxTokenRunes = 100
+
+// sanitizeDescription prepares a video description for the prompt: control
+// characters and invalid UTF-8 are dropped, whitespace runs collapse to one
+// space, overlong tokens are shortened, and the result is capped at maxRunes
+func sanitizeDescription(s string, maxRunes int) string {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r), r == utf8.RuneError:
+			return -1
+		}
+		return r
+	}, s)
+
+	tokens := strings.Fields(cleaned)
+	for i, token := range tokens {
+		tokens[i] = truncateString(token, maxTokenRunes)
+	}
+	return truncateString(strings.Join(tokens, " "), maxRunes)
+}
+
+// truncateString shortens s to at most maxRunes runes, appending "..." when
+// cut. It cuts at a rune boundary so multi-byte characters are never split.
+func truncateString(s string, maxRunes int) string {
+	runes := 0
+	for i := range s {
+		if runes == maxRunes {
+			return s[:i] + "..."
+		}
+		runes++
 	}
-	return s[:maxLength] + "..."
+	return s
 }
@@ -0,0 +1,408 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"agent-stack/internal/models"
+	"agent-stack/shared/config"
+
+	"google.golang.org/genai"
+)
+
+func TestBuildMediaPart(t *testing.T) {
+	a := &Analyzer{mediaMIMEType: "video/webm"}
+
+	tests := []struct {
+		name       string
+		url        string
+		expectMIME string
+	}{
+		{"YouTube watch URL", "https://www.youtube.com/watch?v=dQw4w9WgXcQ", ""},
+		{"YouTube short link", "https://youtu.be/dQw4w9WgXcQ", ""},
+		{"YouTube shorts", "https://youtube.com/shorts/abc123", ""},
+		{"Direct video file", "https://cdn.example.com/video.webm", "video/webm"},
+		{"YouTube channel page is not a video", "https://www.youtube.com/@somechannel", "video/webm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			part := a.buildMediaPart(tt.url)
+			if part.FileData == nil {
+				t.Fatal("Expected file data part")
+			}
+			if part.FileData.FileURI != tt.url {
+				t.Errorf("FileURI = %q, want %q", part.FileData.FileURI, tt.url)
+			}
+			if part.FileData.MIMEType != tt.expectMIME {
+				t.Errorf("MIMEType = %q, want %q", part.FileData.MIMEType, tt.expectMIME)
+			}
+		})
+	}
+}
+
+func TestBuildMediaPartDefaultMIME(t *testing.T) {
+	a := &Analyzer{}
+	part := a.buildMediaPart("https://cdn.example.com/video")
+	if part.FileData.MIMEType != "video/mp4" {
+		t.Errorf("Expected default MIME type video/mp4, got %q", part.FileData.MIMEType)
+	}
+}
+
+// fakeGenerator returns a fixed response or error, counting calls and recording contents
+type fakeGenerator struct {
+	response *genai.GenerateContentResponse
+	err      error
+	calls    int
+	contents [][]*genai.Content
+}
+
+func (f *fakeGenerator) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	f.calls++
+	f.contents = append(f.contents, contents)
+	return f.response, f.err
+}
+
+func TestAnalyzeVideoQuotaExceeded(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"HTTP 429", genai.APIError{Code: 429, Status: "429 Too Many Requests"}},
+		{"RESOURCE_EXHAUSTED status", genai.APIError{Code: 400, Status: "RESOURCE_EXHAUSTED"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeGenerator{err: tt.err}
+			a := &Analyzer{generator: fake}
+
+			_, err := a.AnalyzeVideo(context.Background(), &models.Video{ID: "abc", URL: "https://www.youtube.com/watch?v=abc"})
+			if !errors.Is(err, ErrQuotaExceeded) {
+				t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+			}
+			// Quota errors must not trigger the metadata-only fallback
+			if fake.calls != 1 {
+				t.Errorf("Expected 1 model call, got %d", fake.calls)
+			}
+		})
+	}
+}
+
+func TestAnalyzeVideoOtherErrorNotQuota(t *testing.T) {
+	fake := &fakeGenerator{err: genai.APIError{Code: 500, Status: "INTERNAL"}}
+	a := &Analyzer{generator: fake}
+
+	_, err := a.AnalyzeVideo(context.Background(), &models.Video{ID: "abc", URL: "https://www.youtube.com/watch?v=abc"})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Server error should not be reported as quota exhaustion: %v", err)
+	}
+}
+
+func TestAnalyzeVideoMetadataOnly(t *testing.T) {
+	fake := &fakeGenerator{response: &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{Content: genai.NewContentFromText(`{"is_relevant": true, "summary": "Covers Go generics", "score": 8}`, genai.RoleModel)}},
+	}}
+	a := &Analyzer{generator: fake, metadataOnly: true, longVideoMinutes: 60}
+
+	// A short-enough video would normally be sent to Gemini in full
+	video := &models.Video{ID: "abc", Title: "Go generics", URL: "https://www.youtube.com/watch?v=abc", DurationSeconds: 600}
+	analysis, err := a.AnalyzeVideo(context.Background(), video)
+	if err != nil {
+		t.Fatalf("AnalyzeVideo() error: %v", err)
+	}
+	if analysis.Score != 8 {
+		t.Errorf("Expected score 8, got %d", analysis.Score)
+	}
+
+	if fake.calls != 1 {
+		t.Fatalf("Expected 1 model call, got %d", fake.calls)
+	}
+	for _, content := range fake.contents[0] {
+		for _, part := range content.Parts {
+			if part.FileData != nil {
+				t.Errorf("Expected no media part in metadata-only mode, got %+v", part.FileData)
+			}
+		}
+	}
+}
+
+func TestBuildAnalysisPromptWeightedCriteria(t *testing.T) {
+	a := &Analyzer{guidelines: []config.Criterion{
+		{Text: "Educational value", Priority: config.PriorityHigh},
+		{Text: "Production quality", Priority: config.PriorityLow},
+		{Text: "Avoid clickbait"},
+	}}
+
+	prompt := a.buildAnalysisPrompt(&models.Video{Title: "Go generics"}, true)
+	for _, expected := range []string{
+		"- Educational value (high priority)\n",
+		"- Production quality (low priority)\n",
+		"- Avoid clickbait\n",
+		"weigh them most heavily in the score",
+	} {
+		if !strings.Contains(prompt, expected) {
+			t.Errorf("Expected %q in prompt, got:\n%s", expected, prompt)
+		}
+	}
+
+	plain := &Analyzer{guidelines: []config.Criterion{{Text: "Avoid clickbait"}}}
+	if prompt := plain.buildAnalysisPrompt(&models.Video{}, true); strings.Contains(prompt, "priority") {
+		t.Errorf("Expected no priority wording for unweighted criteria, got:\n%s", prompt)
+	}
+}
+
+func TestBuildAnalysisPromptIncludesTagsAndCategory(t *testing.T) {
+	video := &models.Video{Title: "Go generics", Category: "Education", Tags: []string{"golang", "generics\n", "tutorial"}}
+	prompt := (&Analyzer{}).buildAnalysisPrompt(video, true)
+	for _, expected := range []string{"Category: Education\n", "Tags: golang, generics, tutorial\n"} {
+		if !strings.Contains(prompt, expected) {
+			t.Errorf("Expected %q in prompt, got:\n%s", expected, prompt)
+		}
+	}
+
+	prompt = (&Analyzer{}).buildAnalysisPrompt(&models.Video{Title: "Untagged"}, true)
+	for _, expected := range []string{"Category: none\n", "Tags: none\n"} {
+		if !strings.Contains(prompt, expected) {
+			t.Errorf("Expected %q for a video without metadata, got:\n%s", expected, prompt)
+		}
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		maxRunes int
+		expected string
+	}{
+		{"Short ASCII", "hello", 10, "hello"},
+		{"Exact length", "hello", 5, "hello"},
+		{"ASCII cut", "hello world", 5, "hello..."},
+		{"CJK cut", "日本語のテキスト", 3, "日本語..."},
+		{"Emoji cut", "🚁🌤️✈️ drones", 2, "🚁🌤..."},
+		{"Mixed cut after multibyte", "Go言語で書く", 3, "Go言..."},
+		{"Zero length", "日本", 0, "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateString(tt.input, tt.maxRunes)
+			if got != tt.expected {
+				t.Errorf("truncateString(%q, %d) = %q, want %q", tt.input, tt.maxRunes, got, tt.expected)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("Expected valid UTF-8, got %q", got)
+			}
+			if body := strings.TrimSuffix(got, "..."); utf8.RuneCountInString(body) > tt.maxRunes {
+				t.Errorf("Expected at most %d runes, got %d", tt.maxRunes, utf8.RuneCountInString(body))
+			}
+		})
+	}
+}
+
+func TestSanitizeDescription(t *testing.T) {
+	longToken := "https://example.com/" + strings.Repeat("x", 200)
+	got := sanitizeDescription("Line one\r\n\n\tLine\x00 two\x1b[31m   "+longToken, 500)
+	want := "Line one Line two[31m " + string([]rune(longToken)[:maxTokenRunes]) + "..."
+	if got != want {
+		t.Errorf("sanitizeDescription() = %q, want %q", got, want)
+	}
+
+	// Each CJK character is three bytes, so a byte cut at 500 would land mid-character
+	description := strings.Repeat("日本語のテキスト ", 100)
+	got = sanitizeDescription(description, 500)
+	if !utf8.ValidString(got) {
+		t.Fatalf("Expected valid UTF-8, got %q", got)
+	}
+	if body := strings.TrimSuffix(got, "..."); body != string([]rune(description)[:500]) {
+		t.Errorf("Expected the first 500 runes, got %d runes", utf8.RuneCountInString(body))
+	}
+
+	prompt := (&Analyzer{}).buildAnalysisPrompt(&models.Video{Description: description}, false)
+	if !utf8.ValidString(prompt) {
+		t.Error("Expected the prompt to stay valid UTF-8")
+	}
+}
+
+// modelGenerator answers per model name, recording which models were called
+type modelGenerator struct {
+	responses map[string]*genai.GenerateContentResponse
+	errs      map[string]error
+	called    []string
+	contents  [][]*genai.Content
+}
+
+func (m *modelGenerator) GenerateContent(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	m.called = append(m.called, model)
+	m.contents = append(m.contents, contents)
+	if err := m.errs[model]; err != nil {
+		return nil, err
+	}
+	if resp, ok := m.responses[model]; ok {
+		return resp, nil
+	}
+	return &genai.GenerateContentResponse{}, nil
+}
+
+func TestAnalyzeVideoFallbackModels(t *testing.T) {
+	success := &genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{Content: genai.NewContentFromText(`{"is_relevant": true, "summary": "Covers Go generics", "score": 7}`, genai.RoleModel)}},
+	}
+
+	tests := []struct {
+		name       string
+		errs       map[string]error
+		responses  map[string]*genai.GenerateContentResponse
+		wantCalled []string
+	}{
+		{
+			name:       "Primary errors",
+			errs:       map[string]error{"gemini-2.5-flash": genai.APIError{Code: 503, Status: "UNAVAILABLE"}},
+			responses:  map[string]*genai.GenerateContentResponse{"gemini-2.5-flash-lite": success},
+			wantCalled: []string{"gemini-2.5-flash", "gemini-2.5-flash-lite"},
+		},
+		{
+			name:       "Primary and first fallback return empty",
+			responses:  map[string]*genai.GenerateContentResponse{"gemini-2.0-flash": success},
+			wantCalled: []string{"gemini-2.5-flash", "gemini-2.5-flash-lite", "gemini-2.0-flash"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &modelGenerator{responses: tt.responses, errs: tt.errs}
+			a := &Analyzer{
+				generator:      fake,
+				model:          "gemini-2.5-flash",
+				fallbackModels: []string{"gemini-2.5-flash-lite", "gemini-2.0-flash"},
+			}
+
+			video := &models.Video{ID: "abc", Title: "Go generics", URL: "https://www.youtube.com/watch?v=abc", DurationSeconds: 600}
+			analysis, err := a.AnalyzeVideo(context.Background(), video)
+			if err != nil {
+				t.Fatalf("AnalyzeVideo() error: %v", err)
+			}
+			if analysis.Score != 7 {
+				t.Errorf("Expected score 7 from the fallback model, got %d", analysis.Score)
+			}
+
+			if strings.Join(fake.called, ",") != strings.Join(tt.wantCalled, ",") {
+				t.Errorf("Expected models %v to be called, got %v", tt.wantCalled, fake.called)
+			}
+			// The fallback model gets the full video, not the metadata-only prompt
+			last := fake.contents[len(fake.contents)-1][0]
+			if len(last.Parts) != 2 || last.Parts[1].FileData == nil {
+				t.Errorf("Expected the fallback model to receive the video, got %+v", last.Parts)
+			}
+		})
+	}
+}
+
+func TestAnalyzeVideoFallbackAfterQuota(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      map[string]error
+		responses map[string]*genai.GenerateContentResponse
+	}{
+		{
+			name: "Primary quota, fallback 500",
+			errs: map[string]error{
+				"gemini-2.5-flash":      genai.APIError{Code: 429, Status: "RESOURCE_EXHAUSTED"},
+				"gemini-2.5-flash-lite": genai.APIError{Code: 500, Status: "INTERNAL"},
+			},
+		},
+		{
+			name: "Primary quota, fallback empty",
+			errs: map[string]error{"gemini-2.5-flash": genai.APIError{Code: 429, Status: "RESOURCE_EXHAUSTED"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &modelGenerator{responses: tt.responses, errs: tt.errs}
+			a := &Analyzer{
+				generator:      fake,
+				model:          "gemini-2.5-flash",
+				fallbackModels: []string{"gemini-2.5-flash-lite"},
+			}
+
+			video := &models.Video{ID: "abc", Title: "Go generics", URL: "https://www.youtube.com/watch?v=abc", DurationSeconds: 600}
+			_, err := a.AnalyzeVideo(context.Background(), video)
+			if !errors.Is(err, ErrQuotaExceeded) {
+				t.Errorf("Expected ErrQuotaExceeded when the primary hit the quota, got %v", err)
+			}
+			// Quota errors must not trigger the metadata-only fallback
+			if strings.Join(fake.called, ",") != "gemini-2.5-flash,gemini-2.5-flash-lite" {
+				t.Errorf("Expected only the primary and fallback model calls, got %v", fake.called)
+			}
+		})
+	}
+}
+
+func TestMinDurationSeconds(t *testing.T) {
+	tests := []struct {
+		name        string
+		seconds     int
+		expectSkip  bool
+		shortMinute int
+	}{
+		{"Well under the floor", 60, true, 0},
+		{"One second under the floor", 99, true, 0},
+		{"At the floor", 100, false, 0},
+		{"Just under two minutes", 119, false, 0},
+		{"Takes precedence over short_minutes", 119, false, 1},
+		{"Unknown duration is kept", 0, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeGenerator{response: &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{Content: genai.NewContentFromText(`{"is_relevant": true, "score": 7}`, genai.RoleModel)}},
+			}}
+			a := &Analyzer{generator: fake, minVideoSeconds: 100, shortVideoMinutes: tt.shortMinute}
+
+			video := &models.Video{ID: "abc", URL: "https://www.youtube.com/watch?v=abc", DurationSeconds: tt.seconds}
+			_, err := a.AnalyzeVideo(context.Background(), video)
+			if skipped := errors.Is(err, ErrShortVideoSkipped); skipped != tt.expectSkip {
+				t.Errorf("Expected skip %t for %ds video, got err %v", tt.expectSkip, tt.seconds, err)
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      map[string]error
+		expectErr bool
+	}{
+		{"Valid key and model", nil, false},
+		{"Unknown model", map[string]error{"gemini-typo": genai.APIError{Code: 404, Status: "NOT_FOUND"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &modelGenerator{errs: tt.errs}
+			a := &Analyzer{generator: fake, model: "gemini-typo", fallbackModels: []string{"gemini-2.5-flash"}}
+
+			err := a.Ping(context.Background())
+			if tt.expectErr {
+				if err == nil || !strings.Contains(err.Error(), "gemini-typo") {
+					t.Errorf("Expected self-test error naming the model, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("Expected self-test to pass, got %v", err)
+			}
+			if len(fake.called) != 1 || fake.called[0] != "gemini-typo" {
+				t.Errorf("Expected a single call to the primary model, got %v", fake.called)
+			}
+		})
+	}
+}
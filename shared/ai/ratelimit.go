@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket gating outbound model requests.
+// A nil limiter never blocks.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // time to earn one token
+	burst    float64
+	tokens   float64
+	last     time.Time
+}
+
+// newRateLimiter allows perMinute requests per minute with up to burst requests
+// at once. It returns nil (unlimited) when perMinute is not positive.
+func newRateLimiter(perMinute, burst int) *rateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		interval: time.Minute / time.Duration(perMinute),
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a request may proceed or ctx is done
+func (r *rateLimiter) Wait(ctx context.Context) error {
+	if r == nil {
+		return ctx.Err()
+	}
+
+	for {
+		r.mu.Lock()
+		now := time.Now()
+		r.tokens += float64(now.Sub(r.last)) / float64(r.interval)
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+		r.last = now
+
+		if r.tokens >= 1 {
+			r.tokens--
+			r.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - r.tokens) * float64(r.interval))
+		r.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
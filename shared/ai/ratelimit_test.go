@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterCapsCallRate(t *testing.T) {
+	limiter := newRateLimiter(600, 1) // one call every 100ms
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() error: %v", err)
+		}
+	}
+	elapsed := time.Since(start)
+
+	// First call is immediate, the remaining four wait ~100ms each
+	if elapsed < 350*time.Millisecond {
+		t.Errorf("5 calls completed in %v, expected at least ~400ms", elapsed)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("5 calls took %v, limiter is too slow", elapsed)
+	}
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	limiter := newRateLimiter(60, 3)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Burst of 3 should not block, took %v", elapsed)
+	}
+}
+
+func TestRateLimiterRespectsContext(t *testing.T) {
+	limiter := newRateLimiter(1, 1) // one call per minute
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := limiter.Wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Wait did not return promptly on cancellation (took %v)", elapsed)
+	}
+}
+
+func TestNilRateLimiterIsUnlimited(t *testing.T) {
+	limiter := newRateLimiter(0, 1)
+	if limiter != nil {
+		t.Fatal("Expected nil limiter when rate is disabled")
+	}
+	for i := 0; i < 100; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() error: %v", err)
+		}
+	}
+}
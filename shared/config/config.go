@@ -1,19 +1,41 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/mail"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	_ "time/tzdata" // the runtime image ships without a zoneinfo database
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
 )
 
+var hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+
 type Config struct {
 	YouTubeCurator YouTubeCuratorConfig `yaml:"youtube_curator"`
 	DroneWeather   DroneWeatherConfig   `yaml:"drone_weather"`
 	Email          EmailConfig          `yaml:"email"`
 	Monitoring     MonitoringConfig     `yaml:"monitoring"`
+	// DataDir holds persisted state (OAuth tokens, analyzed videos)
+	DataDir string `yaml:"data_dir" env:"DATA_DIR"`
+	// ProxyURL routes all outbound HTTP calls through an http, https or socks5
+	// proxy; when unset the standard HTTP_PROXY/HTTPS_PROXY variables apply
+	ProxyURL string `yaml:"proxy_url" env:"PROXY_URL"`
+	// Timezone is the IANA zone cron schedules run in and curator email dates are
+	// shown in; when unset schedules use the server zone and emails use UTC
+	Timezone string `yaml:"timezone"`
 }
 
 type YouTubeCuratorConfig struct {
@@ -22,18 +44,88 @@ type YouTubeCuratorConfig struct {
 	Video      VideoConfig      `yaml:"video"`
 	Guidelines GuidelinesConfig `yaml:"guidelines"`
 	Schedule   string           `yaml:"schedule"`
+	// SubjectTemplate is a Go template rendered against models.EmailReport
+	SubjectTemplate string `yaml:"subject_template"`
+	// VideoIDs, when set, analyzes exactly these videos instead of crawling subscriptions
+	VideoIDs []string `yaml:"video_ids"`
+	// TrackerRetentionDays is how long analyzed videos are remembered before they may be re-analyzed
+	TrackerRetentionDays int `yaml:"tracker_retention_days"`
+	// TrackerBackend stores analyzed videos in a JSON file ("json") or a SQLite database ("sqlite")
+	TrackerBackend string `yaml:"tracker_backend"`
+	// DigestMode accumulates relevant videos across runs and emails them once on DigestDay
+	DigestMode bool   `yaml:"digest_mode"`
+	DigestDay  string `yaml:"digest_day"` // weekday name, e.g. "sunday"
+	// Languages restricts analysis to videos in these languages (e.g. "en", "fr"); empty allows all
+	Languages []string `yaml:"languages"`
+	// StrictLanguages also drops videos without language metadata when Languages is set
+	StrictLanguages bool `yaml:"strict_languages"`
+	// AllowedCategories, when set, only analyzes videos in these YouTube categories,
+	// given as names ("Education") or IDs ("27"); videos without a category are kept
+	AllowedCategories []string `yaml:"allowed_categories"`
+	// ExcludeKeywords skips videos whose title or description contains any of these (case-insensitive)
+	ExcludeKeywords []string `yaml:"exclude_keywords"`
+	// IncludeKeywords, when set, only analyzes videos matching at least one (exclude_keywords still wins)
+	IncludeKeywords []string `yaml:"include_keywords"`
+	// GroupByChannel lists emailed videos under a header per channel instead of one flat list
+	GroupByChannel bool `yaml:"group_by_channel"`
+	// AnalysisVersion is mixed into the analysis schema version; change it to have
+	// every tracked video re-evaluated. Guideline and prompt changes do so already.
+	AnalysisVersion string `yaml:"analysis_version"`
+	// ReportIdleRuns records runs that found no new videos as idle rather than
+	// successful, so success notifications and metrics can ignore quiet days
+	ReportIdleRuns bool `yaml:"report_idle_runs"`
+	// RetryFailedAnalysis leaves videos whose analysis errored untracked so the next
+	// run retries them; when false they are marked analyzed to avoid repeated failures
+	RetryFailedAnalysis bool `yaml:"retry_failed_analysis"`
+	// RunLockMinutes, when set, holds a lease file in data_dir during each run so replicas
+	// sharing it never run concurrently; leases older than this are taken over
+	RunLockMinutes int `yaml:"run_lock_minutes"`
+	// MaxAnalysisPerRun caps how many new videos are analyzed per run; 0 means unlimited
+	MaxAnalysisPerRun int `yaml:"max_analysis_per_run"`
+	// AnalysisOrder picks which videos a capped run analyzes first: "newest" for
+	// freshness or "oldest" so nothing ages out of the lookback window unseen
+	AnalysisOrder string `yaml:"analysis_order"`
+	// MinScore is the lowest AI score (1-10) at which a relevant video is emailed
+	MinScore int `yaml:"min_score"`
+	// MentionMinScore lists videos scoring from this up to MinScore as honorable
+	// mentions in a separate section; 0 disables mentions
+	MentionMinScore int `yaml:"mention_min_score"`
+	// DedupTitleSimilarity collapses relevant videos in a run whose normalized titles are
+	// at least this similar (0-1 Levenshtein ratio), keeping the highest score; 0 disables
+	DedupTitleSimilarity float64 `yaml:"dedup_title_similarity"`
+	// ChannelScoreAdjustments adds to the AI score of videos from a channel, keyed by
+	// channel ID or title (case-insensitive); results are clamped to 1-10
+	ChannelScoreAdjustments map[string]int `yaml:"channel_score_adjustments"`
 }
 
 type YouTubeConfig struct {
-	ClientID            string `yaml:"client_id" env:"GOOGLE_CLIENT_ID"`
-	ClientSecret        string `yaml:"client_secret" env:"GOOGLE_CLIENT_SECRET"`
+	ClientID     string `yaml:"client_id" env:"GOOGLE_CLIENT_ID"`
+	ClientSecret string `yaml:"client_secret" env:"GOOGLE_CLIENT_SECRET"`
+	// ClientSecretFile is a client_secret.json downloaded from Google Cloud Console,
+	// used to fill in ClientID and ClientSecret when they are not set directly
+	ClientSecretFile    string `yaml:"client_secret_file"`
 	TokenFile           string `yaml:"token_file"`
 	TokenRefreshMinutes int    `yaml:"token_refresh_minutes"`
+	// PlaylistIDs, when set, pulls videos from these playlists instead of crawling subscriptions
+	PlaylistIDs []string `yaml:"playlist_ids"`
+	// SubscriptionsPageSize is how many subscriptions are fetched per crawl (1-50)
+	SubscriptionsPageSize int `yaml:"subscriptions_page_size"`
 }
 
 type AIConfig struct {
-	GeminiAPIKey string `yaml:"gemini_api_key" env:"GEMINI_API_KEY"`
-	Model        string `yaml:"model"`
+	GeminiAPIKey  string `yaml:"gemini_api_key" env:"GEMINI_API_KEY"`
+	Model         string `yaml:"model"`
+	MediaMIMEType string `yaml:"media_mime_type"` // for non-YouTube video URLs
+	// RequestsPerMinute caps Gemini calls across all analysis paths; negative disables limiting
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	// MetadataOnly analyzes every video from its title and description without sending the video itself
+	MetadataOnly bool `yaml:"metadata_only"`
+	// FallbackModels are tried in order when the primary model errors or returns an
+	// empty response, before falling back to metadata-only analysis
+	FallbackModels []string `yaml:"fallback_models"`
+	// SelfTest sends a tiny request at startup so a bad API key or model name fails
+	// initialization instead of the first analysis
+	SelfTest bool `yaml:"self_test"`
 }
 
 type EmailConfig struct {
@@ -43,33 +135,291 @@ type EmailConfig struct {
 	Password   string `yaml:"password" env:"EMAIL_PASSWORD"`
 	FromEmail  string `yaml:"from_email"`
 	ToEmail    string `yaml:"to_email"`
+	// HeloHost is the hostname sent in EHLO/HELO; empty uses "localhost"
+	HeloHost string `yaml:"helo_host"`
+	// TLSMode is "tls" for implicit TLS from the first byte or "starttls" to upgrade
+	// a plain connection; empty picks implicit TLS on port 465 and STARTTLS otherwise
+	TLSMode string `yaml:"tls_mode"`
+	// SaveReportsDir, when set, keeps a timestamped copy of every rendered email's
+	// HTML in this directory, whether or not delivery succeeds
+	SaveReportsDir string `yaml:"save_reports_dir"`
+	// SMTPTimeoutSeconds bounds connecting to the SMTP server and the whole exchange
+	// after it; 0 uses DefaultSMTPTimeoutSeconds
+	SMTPTimeoutSeconds int `yaml:"smtp_timeout_seconds"`
 }
 
+// DefaultSMTPTimeoutSeconds is used when email.smtp_timeout_seconds is unset
+const DefaultSMTPTimeoutSeconds = 30
+
+// SMTP TLS modes for email.tls_mode
+const (
+	TLSModeImplicit = "tls"
+	TLSModeStartTLS = "starttls"
+)
+
 type GuidelinesConfig struct {
-	Criteria []string `yaml:"criteria"`
+	Criteria []Criterion `yaml:"criteria"`
+}
+
+// Criterion is one analysis guideline. In YAML it is either a plain string or a
+// mapping with a priority, e.g. {text: "Educational value", priority: high}.
+type Criterion struct {
+	Text     string `yaml:"text"`
+	Priority string `yaml:"priority"` // high, medium or low; empty for unweighted
+}
+
+// Criterion priorities, telling the model how much each guideline should count
+const (
+	PriorityHigh   = "high"
+	PriorityMedium = "medium"
+	PriorityLow    = "low"
+)
+
+// UnmarshalYAML accepts both plain string criteria and text/priority mappings
+func (c *Criterion) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*c = Criterion{Text: node.Value}
+		return nil
+	}
+	type plain Criterion
+	return node.Decode((*plain)(c))
+}
+
+// MarshalYAML writes unweighted criteria back as plain strings
+func (c Criterion) MarshalYAML() (interface{}, error) {
+	if c.Priority == "" {
+		return c.Text, nil
+	}
+	type plain Criterion
+	return plain(c), nil
 }
 
 type MonitoringConfig struct {
-	HealthPort int `yaml:"health_port"`
+	HealthPort     int    `yaml:"health_port"`
+	HealthBindAddr string `yaml:"health_bind_addr"` // empty binds all interfaces
+	StatsDAddr     string `yaml:"statsd_addr"`      // host:port, empty disables metrics
+	StatsDPrefix   string `yaml:"statsd_prefix"`
+	// MaxStalenessSeconds fails /health when no run has succeeded within the window; 0 disables
+	MaxStalenessSeconds int `yaml:"max_staleness_seconds"`
+	// ScheduleSkewSeconds flags scheduled runs arriving further than this from the time
+	// their cron expression expected; negative disables the check
+	ScheduleSkewSeconds int `yaml:"schedule_skew_seconds"`
+	// AdminToken enables the /tracker/mark endpoint for requests bearing it; empty disables
+	AdminToken string `yaml:"admin_token" env:"HEALTH_ADMIN_TOKEN"`
 }
 
 type VideoConfig struct {
 	ShortMinutes int `yaml:"short_minutes"`
 	LongMinutes  int `yaml:"long_minutes"`
+	// MinDurationSeconds skips videos shorter than this many seconds and, when set,
+	// replaces the minute-granularity short_minutes check
+	MinDurationSeconds int `yaml:"min_duration_seconds"`
 }
 
 type DroneWeatherConfig struct {
-	HomeLatitude       float64 `yaml:"home_latitude"`
-	HomeLongitude      float64 `yaml:"home_longitude"`
-	HomeName           string  `yaml:"home_name"`
-	SearchRadiusMiles  int     `yaml:"search_radius_miles"`
-	MaxWindSpeedKmh    int     `yaml:"max_wind_speed_kmh"`
-	MinVisibilityKm    int     `yaml:"min_visibility_km"`
-	MaxPrecipitationMm float64 `yaml:"max_precipitation_mm"`
-	MinTempC           float64 `yaml:"min_temp_c"`
-	MaxTempC           float64 `yaml:"max_temp_c"`
-	WeatherURL         string  `yaml:"weather_url"`
-	Schedule           string  `yaml:"schedule"`
+	HomeLatitude         float64  `yaml:"home_latitude"`
+	HomeLongitude        float64  `yaml:"home_longitude"`
+	HomeName             string   `yaml:"home_name"`
+	SearchRadiusMiles    int      `yaml:"search_radius_miles"`
+	FlightFloorFt        int      `yaml:"flight_floor_ft"`
+	FlightCeilingFt      int      `yaml:"flight_ceiling_ft"`
+	MaxWindSpeedKmh      int      `yaml:"max_wind_speed_kmh"`
+	MinVisibilityKm      int      `yaml:"min_visibility_km"`
+	MaxPrecipitationMm   float64  `yaml:"max_precipitation_mm"`
+	MinTempC             float64  `yaml:"min_temp_c"`
+	MaxTempC             float64  `yaml:"max_temp_c"`
+	TempComparison       string   `yaml:"temp_comparison"` // "actual" or "apparent" for the min_temp_c check
+	ForecastHours        int      `yaml:"forecast_hours"`  // hourly wind forecast horizon used for averages
+	Units                string   `yaml:"units"`           // "metric" or "imperial" units requested from the weather API
+	UserAgent            string   `yaml:"user_agent"`      // sent on weather and TFR requests; empty uses the agent-stack default
+	WeatherURL           string   `yaml:"weather_url"`
+	TFRURL               string   `yaml:"tfr_url"`
+	TFRURLs              []string `yaml:"tfr_urls"` // tried in order; overrides tfr_url when set
+	UnparseableTFRPolicy string   `yaml:"unparseable_tfr_policy"`
+	RequireTFRSuccess    bool     `yaml:"require_tfr_success"` // treat a failed TFR check as not flyable
+	TFRBlocksFlight      bool     `yaml:"tfr_blocks_flight"`   // treat any active TFR in range as not flyable
+	Schedule             string   `yaml:"schedule"`
+	// MaxPrecipProbabilityPct marks conditions not flyable when any forecast hour's chance
+	// of precipitation exceeds it; 0 disables the check
+	MaxPrecipProbabilityPct int `yaml:"max_precip_probability_pct"`
+	// CautionMarginPct puts flyable days in the caution tier when any metric is within
+	// this percentage of its limit; negative disables the caution tier
+	CautionMarginPct int `yaml:"caution_margin_pct"`
+	// NoFlyHeadsUpRuns sends a single "still no good days" email once this many
+	// consecutive checks are not flyable; 0 disables the heads-up
+	NoFlyHeadsUpRuns int `yaml:"no_fly_heads_up_runs"`
+	// SearchRadiusKm sets the TFR search radius in kilometers and takes precedence
+	// over search_radius_miles when set
+	SearchRadiusKm int `yaml:"search_radius_km"`
+	// ForecastDays evaluates this many days after today against the thresholds using
+	// daily aggregates and lists the flyable ones in reports; 0 disables the outlook
+	ForecastDays int `yaml:"forecast_days"`
+	// MinWindowMinutes is the shortest run of good forecast hours reported as the best window.
+	// Forecasts are hourly, so each good hour counts as 60 minutes.
+	MinWindowMinutes int `yaml:"min_window_minutes"`
+	// WeatherProvider is the primary weather source; FallbackWeatherProvider, when set,
+	// is tried if the primary fails, querying FallbackWeatherURL (default: weather_url for
+	// the same provider, otherwise the fallback provider's public endpoint)
+	WeatherProvider         string `yaml:"weather_provider"`
+	FallbackWeatherProvider string `yaml:"fallback_weather_provider"`
+	FallbackWeatherURL      string `yaml:"fallback_weather_url"`
+	// OpenWeatherMapAPIKey authenticates the openweathermap provider (One Call 3.0)
+	OpenWeatherMapAPIKey string `yaml:"openweathermap_api_key" env:"OPENWEATHERMAP_API_KEY"`
+	// HTTPTimeoutSeconds bounds each weather and TFR request
+	HTTPTimeoutSeconds int `yaml:"http_timeout_seconds"`
+	// WeatherFetchAttempts is how many times a weather fetch is tried on 5xx responses
+	// or network errors before the run fails
+	WeatherFetchAttempts int `yaml:"weather_fetch_attempts"`
+	// DebugHTTP logs weather and TFR request URLs and saves raw responses (size-capped)
+	// under <data_dir>/http_debug for diagnosing API changes
+	DebugHTTP bool `yaml:"debug_http"`
+	// DebugReport appends the raw Open-Meteo response and parsed weather data to
+	// flyable emails in a collapsible section, for tuning thresholds
+	DebugReport bool `yaml:"debug_report"`
+	// NotifyCooldownHours suppresses further flyable emails for this long after one is
+	// sent, until a no-fly check resets it; 0 disables the cooldown
+	NotifyCooldownHours int `yaml:"notify_cooldown_hours"`
+	// NotifyResetNoFlyRuns is how many no-fly checks in a row end the cooldown early;
+	// raise it so a brief bad spell between good checks doesn't re-send
+	NotifyResetNoFlyRuns int `yaml:"notify_reset_no_fly_runs"`
+	// SubjectTemplate is a Go template rendered against models.DroneFlightReport
+	SubjectTemplate string `yaml:"subject_template"`
+}
+
+// Backends for youtube_curator.tracker_backend
+const (
+	TrackerBackendJSON   = "json"
+	TrackerBackendSQLite = "sqlite"
+)
+
+// Orders for youtube_curator.analysis_order
+const (
+	AnalysisOrderNewest = "newest"
+	AnalysisOrderOldest = "oldest"
+)
+
+// MaxSubscriptionsPageSize is the largest page the YouTube subscriptions API serves
+const MaxSubscriptionsPageSize = 50
+
+// MaxForecastHours is the longest horizon Open-Meteo serves (16 days)
+const MaxForecastHours = 384
+
+// MaxForecastDays is the longest outlook after today that fits Open-Meteo's 16-day horizon
+const MaxForecastDays = 15
+
+// Weather providers for drone_weather.weather_provider and fallback_weather_provider
+const (
+	WeatherProviderOpenMeteo      = "open-meteo"
+	WeatherProviderOpenWeatherMap = "openweathermap"
+)
+
+// DefaultWeatherURL returns the public endpoint of a weather provider
+func DefaultWeatherURL(provider string) string {
+	if provider == WeatherProviderOpenWeatherMap {
+		return "https://api.openweathermap.org/data/3.0/onecall"
+	}
+	return "https://api.open-meteo.com/v1/forecast"
+}
+
+// Units requested from the weather API. Thresholds and reports stay in
+// km/h and Celsius either way.
+const (
+	UnitsMetric   = "metric"
+	UnitsImperial = "imperial"
+)
+
+// Temperatures compared against drone_weather.min_temp_c
+const (
+	TempComparisonActual   = "actual"   // measured air temperature
+	TempComparisonApparent = "apparent" // feels-like temperature including wind chill
+)
+
+// Policies for TFRs whose effective dates cannot be parsed
+const (
+	UnparseableTFRActive = "active" // treat as currently active
+	UnparseableTFRSkip   = "skip"   // ignore entirely
+	UnparseableTFRFlag   = "flag"   // include but call out separately in the summary
+)
+
+// Defaults for CONFIG_READ_ATTEMPTS and CONFIG_READ_DELAY_SECONDS
+const (
+	defaultConfigReadAttempts = 3
+	defaultConfigReadDelay    = 2 * time.Second
+)
+
+// sleep is swapped out in tests to observe retries without waiting
+var sleep = time.Sleep
+
+// readConfigFile reads path, making up to attempts tries spaced delay apart
+func readConfigFile(path string, attempts int, delay time.Duration) ([]byte, error) {
+	var err error
+	for attempt := 1; ; attempt++ {
+		var data []byte
+		data, err = os.ReadFile(path)
+		if err == nil {
+			return data, nil
+		}
+		if attempt >= attempts {
+			return nil, err
+		}
+		log.Printf("Config file %s not readable (attempt %d/%d), retrying in %v: %v", path, attempt, attempts, delay, err)
+		sleep(delay)
+	}
+}
+
+// envRefPattern matches ${VAR} references, and $${VAR} escapes of them
+var envRefPattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${VAR} references in the scalar values under node with
+// environment values. Keys and comments are left alone, and values are substituted
+// after YAML parsing so they are never reinterpreted as YAML syntax. Any other $,
+// including $VAR and $$, is literal; $${VAR} yields a literal ${VAR}.
+// Undefined variables are an error unless allowUndefined, which expands them to "".
+func expandEnv(node *yaml.Node, allowUndefined bool) error {
+	var undefined []string
+	expandEnvNode(node, &undefined)
+
+	if len(undefined) > 0 {
+		if !allowUndefined {
+			return fmt.Errorf("undefined environment variables: %s (define them, write a literal ${ as $${, or set CONFIG_ALLOW_UNDEFINED_VARS=true)", strings.Join(undefined, ", "))
+		}
+		log.Printf("Warning: Undefined environment variables in config expanded to empty: %s", strings.Join(undefined, ", "))
+	}
+	return nil
+}
+
+// expandEnvNode expands the scalar values under node, collecting undefined names
+func expandEnvNode(node *yaml.Node, undefined *[]string) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		expanded := envRefPattern.ReplaceAllStringFunc(node.Value, func(ref string) string {
+			if strings.HasPrefix(ref, "$$") {
+				return ref[1:]
+			}
+			name := ref[2 : len(ref)-1]
+			if value, ok := os.LookupEnv(name); ok {
+				return value
+			}
+			*undefined = append(*undefined, name)
+			return ""
+		})
+		if expanded != node.Value {
+			node.Value = expanded
+			// Let unquoted values resolve by their expanded content, e.g. a port number
+			if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				node.Tag = ""
+			}
+		}
+	case yaml.MappingNode:
+		// Content alternates keys and values; only values are expanded
+		for i := 1; i < len(node.Content); i += 2 {
+			expandEnvNode(node.Content[i], undefined)
+		}
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			expandEnvNode(child, undefined)
+		}
+	}
 }
 
 func Load() (*Config, error) {
@@ -80,28 +430,80 @@ func Load() (*Config, error) {
 		configFile = "config.yaml"
 	}
 
-	data, err := os.ReadFile(configFile)
+	// The config volume may not be mounted yet when a container starts, so
+	// retry briefly before giving up. Configured via env since no config is loaded yet.
+	attempts, delay := defaultConfigReadAttempts, defaultConfigReadDelay
+	if v := os.Getenv("CONFIG_READ_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			attempts = n
+		}
+	}
+	if v := os.Getenv("CONFIG_READ_DELAY_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			delay = time.Duration(n) * time.Second
+		}
+	}
+	data, err := readConfigFile(configFile, attempts, delay)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file %s: %w", configFile, err)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
 		return nil, fmt.Errorf("failed to parse config file %s: %w", configFile, err)
 	}
 
+	// Expand ${VAR} references so secrets can live in the environment
+	allowUndefined, _ := strconv.ParseBool(os.Getenv("CONFIG_ALLOW_UNDEFINED_VARS"))
+	if err := expandEnv(&document, allowUndefined); err != nil {
+		return nil, fmt.Errorf("failed to expand config file %s: %w", configFile, err)
+	}
+
+	var cfg Config
+	// Booleans that default to true are set before parsing so an explicit false sticks
+	cfg.YouTubeCurator.RetryFailedAnalysis = true
+	if len(document.Content) > 0 {
+		if err := document.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", configFile, err)
+		}
+	}
+
+	if path := cfg.YouTubeCurator.YouTube.ClientSecretFile; path != "" {
+		clientID, clientSecret, err := ParseClientSecretFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.YouTubeCurator.YouTube.ClientID == "" {
+			cfg.YouTubeCurator.YouTube.ClientID = clientID
+		}
+		if cfg.YouTubeCurator.YouTube.ClientSecret == "" {
+			cfg.YouTubeCurator.YouTube.ClientSecret = clientSecret
+		}
+	}
 	if cfg.YouTubeCurator.YouTube.ClientID == "" {
 		cfg.YouTubeCurator.YouTube.ClientID = os.Getenv("GOOGLE_CLIENT_ID")
 	}
 	if cfg.YouTubeCurator.YouTube.ClientSecret == "" {
 		cfg.YouTubeCurator.YouTube.ClientSecret = os.Getenv("GOOGLE_CLIENT_SECRET")
 	}
+	if cfg.DataDir == "" {
+		cfg.DataDir = os.Getenv("DATA_DIR")
+	}
+	if cfg.DataDir == "" {
+		cfg.DataDir = "data"
+	}
+	if cfg.ProxyURL == "" {
+		cfg.ProxyURL = os.Getenv("PROXY_URL")
+	}
 	if cfg.YouTubeCurator.YouTube.TokenFile == "" {
-		cfg.YouTubeCurator.YouTube.TokenFile = "data/youtube_token.json"
+		cfg.YouTubeCurator.YouTube.TokenFile = filepath.Join(cfg.DataDir, "youtube_token.json")
 	}
 	if cfg.YouTubeCurator.YouTube.TokenRefreshMinutes == 0 {
 		cfg.YouTubeCurator.YouTube.TokenRefreshMinutes = 30 // Default to 30 minutes
 	}
+	if cfg.YouTubeCurator.YouTube.SubscriptionsPageSize == 0 {
+		cfg.YouTubeCurator.YouTube.SubscriptionsPageSize = MaxSubscriptionsPageSize
+	}
 	if cfg.YouTubeCurator.AI.GeminiAPIKey == "" {
 		cfg.YouTubeCurator.AI.GeminiAPIKey = os.Getenv("GEMINI_API_KEY")
 	}
@@ -111,18 +513,45 @@ func Load() (*Config, error) {
 	if cfg.Email.Password == "" {
 		cfg.Email.Password = os.Getenv("EMAIL_PASSWORD")
 	}
+	if cfg.Monitoring.AdminToken == "" {
+		cfg.Monitoring.AdminToken = os.Getenv("HEALTH_ADMIN_TOKEN")
+	}
+	if cfg.DroneWeather.OpenWeatherMapAPIKey == "" {
+		cfg.DroneWeather.OpenWeatherMapAPIKey = os.Getenv("OPENWEATHERMAP_API_KEY")
+	}
 
 	// No external monitoring services - self-contained only
 
 	if cfg.YouTubeCurator.AI.Model == "" {
 		cfg.YouTubeCurator.AI.Model = "gemini-2.5-flash"
 	}
+	if cfg.YouTubeCurator.AI.RequestsPerMinute == 0 {
+		cfg.YouTubeCurator.AI.RequestsPerMinute = 30 // Matches the historical 2s pacing between videos
+	}
+	if cfg.YouTubeCurator.AI.MediaMIMEType == "" {
+		cfg.YouTubeCurator.AI.MediaMIMEType = "video/mp4"
+	}
 	if cfg.YouTubeCurator.Video.LongMinutes == 0 {
 		cfg.YouTubeCurator.Video.LongMinutes = 60
 	}
 	if cfg.YouTubeCurator.Video.ShortMinutes == 0 {
 		cfg.YouTubeCurator.Video.ShortMinutes = 1
 	}
+	if cfg.YouTubeCurator.AnalysisOrder == "" {
+		cfg.YouTubeCurator.AnalysisOrder = AnalysisOrderNewest
+	}
+	if cfg.YouTubeCurator.MinScore == 0 {
+		cfg.YouTubeCurator.MinScore = 6
+	}
+	if cfg.YouTubeCurator.TrackerRetentionDays == 0 {
+		cfg.YouTubeCurator.TrackerRetentionDays = 7
+	}
+	if cfg.YouTubeCurator.TrackerBackend == "" {
+		cfg.YouTubeCurator.TrackerBackend = TrackerBackendJSON
+	}
+	if cfg.YouTubeCurator.DigestDay == "" {
+		cfg.YouTubeCurator.DigestDay = "sunday"
+	}
 	if cfg.YouTubeCurator.Schedule == "" {
 		// 6-field cron with seconds: daily at 09:00:00
 		cfg.YouTubeCurator.Schedule = "0 0 9 * * *"
@@ -132,9 +561,15 @@ func Load() (*Config, error) {
 		cfg.DroneWeather.Schedule = "0 0 9 * * *"
 	}
 
+	if cfg.Monitoring.ScheduleSkewSeconds == 0 {
+		cfg.Monitoring.ScheduleSkewSeconds = 60
+	}
 	if cfg.Monitoring.HealthPort == 0 {
 		cfg.Monitoring.HealthPort = 8080
 	}
+	if cfg.Monitoring.StatsDPrefix == "" {
+		cfg.Monitoring.StatsDPrefix = "agent_stack"
+	}
 
 	// Optional override via environment variable to align Docker healthchecks.
 	// Use a single variable name to avoid confusion.
@@ -145,8 +580,15 @@ func Load() (*Config, error) {
 	}
 
 	// Set defaults for drone weather configuration
+	if cfg.DroneWeather.WeatherProvider == "" {
+		cfg.DroneWeather.WeatherProvider = WeatherProviderOpenMeteo
+	}
 	if cfg.DroneWeather.WeatherURL == "" {
-		cfg.DroneWeather.WeatherURL = "https://api.open-meteo.com/v1/forecast"
+		cfg.DroneWeather.WeatherURL = DefaultWeatherURL(cfg.DroneWeather.WeatherProvider)
+	}
+	if cfg.DroneWeather.TFRURL == "" {
+		// FAA GeoServer WFS endpoint returning TFR polygons in Web Mercator
+		cfg.DroneWeather.TFRURL = "https://tfr.faa.gov/geoserver/TFR/ows?service=WFS&version=1.1.0&request=GetFeature&typeName=TFR:V_TFR_LOC&maxFeatures=300&outputFormat=application/json&srsname=EPSG:3857"
 	}
 	if cfg.DroneWeather.MaxWindSpeedKmh == 0 {
 		cfg.DroneWeather.MaxWindSpeedKmh = 25 // ~15 mph converted to km/h
@@ -163,9 +605,39 @@ func Load() (*Config, error) {
 	if cfg.DroneWeather.MaxTempC == 0 {
 		cfg.DroneWeather.MaxTempC = 35.0 // 95°F in Celsius
 	}
-	if cfg.DroneWeather.SearchRadiusMiles == 0 {
+	if cfg.DroneWeather.SearchRadiusMiles == 0 && cfg.DroneWeather.SearchRadiusKm == 0 {
 		cfg.DroneWeather.SearchRadiusMiles = 25
 	}
+	if cfg.DroneWeather.FlightCeilingFt == 0 {
+		cfg.DroneWeather.FlightCeilingFt = 400 // Part 107 altitude limit
+	}
+	if cfg.DroneWeather.TempComparison == "" {
+		cfg.DroneWeather.TempComparison = TempComparisonActual
+	}
+	if cfg.DroneWeather.Units == "" {
+		cfg.DroneWeather.Units = UnitsMetric
+	}
+	if cfg.DroneWeather.CautionMarginPct == 0 {
+		cfg.DroneWeather.CautionMarginPct = 10
+	}
+	if cfg.DroneWeather.MinWindowMinutes == 0 {
+		cfg.DroneWeather.MinWindowMinutes = 60
+	}
+	if cfg.DroneWeather.NotifyResetNoFlyRuns == 0 {
+		cfg.DroneWeather.NotifyResetNoFlyRuns = 1
+	}
+	if cfg.DroneWeather.HTTPTimeoutSeconds == 0 {
+		cfg.DroneWeather.HTTPTimeoutSeconds = 30
+	}
+	if cfg.DroneWeather.WeatherFetchAttempts == 0 {
+		cfg.DroneWeather.WeatherFetchAttempts = 3
+	}
+	if cfg.DroneWeather.ForecastHours == 0 {
+		cfg.DroneWeather.ForecastHours = 24
+	}
+	if cfg.DroneWeather.UnparseableTFRPolicy == "" {
+		cfg.DroneWeather.UnparseableTFRPolicy = UnparseableTFRActive
+	}
 
 	if err := cfg.validate(); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
@@ -174,6 +646,74 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// ParseClientSecretFile reads the OAuth client ID and secret from a Google Cloud
+// Console client_secret.json, which nests them under "installed" for desktop
+// apps or "web" for web applications
+func ParseClientSecretFile(path string) (clientID, clientSecret string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read client secret file %s: %w", path, err)
+	}
+
+	type credentials struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	var file struct {
+		Installed *credentials `json:"installed"`
+		Web       *credentials `json:"web"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return "", "", fmt.Errorf("failed to parse client secret file %s: %w", path, err)
+	}
+
+	creds := file.Installed
+	if creds == nil {
+		creds = file.Web
+	}
+	if creds == nil || creds.ClientID == "" {
+		return "", "", fmt.Errorf("client secret file %s has no installed or web client credentials", path)
+	}
+	return creds.ClientID, creds.ClientSecret, nil
+}
+
+// redactedValue replaces secrets in Redacted output
+const redactedValue = "REDACTED"
+
+// Redacted returns a copy of the config with passwords, API keys and client
+// secrets masked so the effective configuration can be printed safely.
+// Unset secrets stay empty to show they are missing.
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	for _, secret := range []*string{
+		&redacted.Email.Password,
+		&redacted.YouTubeCurator.AI.GeminiAPIKey,
+		&redacted.YouTubeCurator.YouTube.ClientSecret,
+		&redacted.Monitoring.AdminToken,
+		&redacted.DroneWeather.OpenWeatherMapAPIKey,
+	} {
+		if *secret != "" {
+			*secret = redactedValue
+		}
+	}
+	// Proxy credentials may be embedded in the URL
+	if u, err := url.Parse(redacted.ProxyURL); err == nil && u.User != nil {
+		redacted.ProxyURL = u.Redacted()
+	}
+	return &redacted
+}
+
+// WriteEffective writes the redacted config as YAML, including all defaults
+// and environment overrides applied by Load
+func (c *Config) WriteEffective(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	return encoder.Close()
+}
+
 func (c *Config) validate() error {
 	if c.Email.Username == "" {
 		return fmt.Errorf("Email username is required (set EMAIL_USERNAME or email.username)")
@@ -181,9 +721,105 @@ func (c *Config) validate() error {
 	if c.Email.Password == "" {
 		return fmt.Errorf("Email password is required (set EMAIL_PASSWORD or email.password)")
 	}
+	// A slice rather than a map so the first invalid field is always the one reported
+	for _, address := range []struct{ field, value string }{
+		{"email.from_email", c.Email.FromEmail},
+		{"email.to_email", c.Email.ToEmail},
+	} {
+		if err := validateEmailAddress(address.value); err != nil {
+			return fmt.Errorf("invalid %s: %w", address.field, err)
+		}
+	}
+	if err := validateHost(c.Email.HeloHost); err != nil {
+		return fmt.Errorf("invalid email.helo_host: %w", err)
+	}
+	switch c.Email.TLSMode {
+	case "", TLSModeImplicit, TLSModeStartTLS:
+	default:
+		return fmt.Errorf("invalid email.tls_mode %q (expected %s or %s)", c.Email.TLSMode, TLSModeImplicit, TLSModeStartTLS)
+	}
+	if c.Email.SMTPTimeoutSeconds < 0 {
+		return fmt.Errorf("email.smtp_timeout_seconds must not be negative, got %d", c.Email.SMTPTimeoutSeconds)
+	}
+	if err := validateHost(c.Monitoring.HealthBindAddr); err != nil {
+		return fmt.Errorf("invalid monitoring.health_bind_addr: %w", err)
+	}
+	if err := validateProxyURL(c.ProxyURL); err != nil {
+		return fmt.Errorf("invalid proxy_url: %w", err)
+	}
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone: %w", err)
+	}
+	return nil
+}
+
+// Location returns the configured Timezone, or UTC when it is unset or invalid
+func (c *Config) Location() *time.Location {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// validateProxyURL checks that raw is an absolute http, https or socks5 URL
+// with a host. An empty value is accepted and means no explicit proxy.
+func validateProxyURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("unsupported scheme %q (expected http, https or socks5)", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", raw)
+	}
+	return nil
+}
+
+// validateHost checks that addr is usable as the host part of a listen address.
+// An empty value is accepted and means all interfaces.
+func validateHost(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	if net.ParseIP(addr) != nil {
+		return nil
+	}
+	if !hostnamePattern.MatchString(addr) {
+		return fmt.Errorf("%q is not a valid IP address or hostname", addr)
+	}
+	return nil
+}
+
+// validateEmailAddress checks that addr is a single RFC 5322 address such as
+// "user@example.com" or "Name <user@example.com>". An empty value is accepted.
+func validateEmailAddress(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	if _, err := mail.ParseAddress(addr); err != nil {
+		return fmt.Errorf("%q is not a valid email address: %w", addr, err)
+	}
 	return nil
 }
 
+// ParseWeekday parses a case-insensitive English weekday name such as "Sunday"
+func ParseWeekday(name string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(name, d.String()) {
+			return d, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("%q is not a weekday", name)
+}
+
 // ValidateYouTubeCurator validates YouTube Curator specific configuration
 func (c *Config) ValidateYouTubeCurator() error {
 	if c.YouTubeCurator.YouTube.ClientID == "" {
@@ -192,11 +828,134 @@ func (c *Config) ValidateYouTubeCurator() error {
 	if c.YouTubeCurator.AI.GeminiAPIKey == "" {
 		return fmt.Errorf("Gemini API key is required (set GEMINI_API_KEY or youtube_curator.ai.gemini_api_key)")
 	}
+	if n := c.YouTubeCurator.YouTube.SubscriptionsPageSize; n < 1 || n > MaxSubscriptionsPageSize {
+		return fmt.Errorf("youtube_curator.youtube.subscriptions_page_size must be between 1 and %d, got %d", MaxSubscriptionsPageSize, n)
+	}
+	if _, err := template.New("subject").Parse(c.YouTubeCurator.SubjectTemplate); err != nil {
+		return fmt.Errorf("invalid youtube_curator.subject_template: %w", err)
+	}
+	for i, criterion := range c.YouTubeCurator.Guidelines.Criteria {
+		if criterion.Text == "" {
+			return fmt.Errorf("youtube_curator.guidelines.criteria[%d] has no text", i)
+		}
+		switch criterion.Priority {
+		case "", PriorityHigh, PriorityMedium, PriorityLow:
+		default:
+			return fmt.Errorf("invalid priority %q for youtube_curator.guidelines.criteria[%d] (expected %s, %s or %s)",
+				criterion.Priority, i, PriorityHigh, PriorityMedium, PriorityLow)
+		}
+	}
+	if c.YouTubeCurator.Video.MinDurationSeconds < 0 {
+		return fmt.Errorf("youtube_curator.video.min_duration_seconds cannot be negative, got %d", c.YouTubeCurator.Video.MinDurationSeconds)
+	}
+	if c.YouTubeCurator.DigestMode {
+		if _, err := ParseWeekday(c.YouTubeCurator.DigestDay); err != nil {
+			return fmt.Errorf("invalid youtube_curator.digest_day: %w", err)
+		}
+	}
+	if c.YouTubeCurator.MaxAnalysisPerRun < 0 {
+		return fmt.Errorf("youtube_curator.max_analysis_per_run cannot be negative, got %d", c.YouTubeCurator.MaxAnalysisPerRun)
+	}
+	switch c.YouTubeCurator.AnalysisOrder {
+	case "", AnalysisOrderNewest, AnalysisOrderOldest:
+	default:
+		return fmt.Errorf("invalid youtube_curator.analysis_order %q (expected %s or %s)",
+			c.YouTubeCurator.AnalysisOrder, AnalysisOrderNewest, AnalysisOrderOldest)
+	}
+	if c.YouTubeCurator.MinScore < 1 || c.YouTubeCurator.MinScore > 10 {
+		return fmt.Errorf("youtube_curator.min_score must be between 1 and 10, got %d", c.YouTubeCurator.MinScore)
+	}
+	if m := c.YouTubeCurator.MentionMinScore; m < 0 || (m > 0 && m >= c.YouTubeCurator.MinScore) {
+		return fmt.Errorf("youtube_curator.mention_min_score must be 0 (disabled) or below min_score (%d), got %d", c.YouTubeCurator.MinScore, m)
+	}
+	if s := c.YouTubeCurator.DedupTitleSimilarity; s < 0 || s > 1 {
+		return fmt.Errorf("youtube_curator.dedup_title_similarity must be between 0 and 1, got %g", s)
+	}
+	if c.YouTubeCurator.TrackerRetentionDays <= 0 {
+		return fmt.Errorf("youtube_curator.tracker_retention_days must be positive, got %d", c.YouTubeCurator.TrackerRetentionDays)
+	}
+	if c.YouTubeCurator.RunLockMinutes < 0 {
+		return fmt.Errorf("youtube_curator.run_lock_minutes must not be negative, got %d", c.YouTubeCurator.RunLockMinutes)
+	}
+	switch c.YouTubeCurator.TrackerBackend {
+	case TrackerBackendJSON, TrackerBackendSQLite:
+	default:
+		return fmt.Errorf("invalid youtube_curator.tracker_backend %q (expected %s or %s)",
+			c.YouTubeCurator.TrackerBackend, TrackerBackendJSON, TrackerBackendSQLite)
+	}
 	return nil
 }
 
 // ValidateDroneWeather validates Drone Weather specific configuration
 func (c *Config) ValidateDroneWeather() error {
-	// No specific validation required for drone weather currently
+	switch c.DroneWeather.UnparseableTFRPolicy {
+	case UnparseableTFRActive, UnparseableTFRSkip, UnparseableTFRFlag:
+	default:
+		return fmt.Errorf("invalid drone_weather.unparseable_tfr_policy %q (expected %s, %s or %s)",
+			c.DroneWeather.UnparseableTFRPolicy, UnparseableTFRActive, UnparseableTFRSkip, UnparseableTFRFlag)
+	}
+	switch c.DroneWeather.TempComparison {
+	case TempComparisonActual, TempComparisonApparent:
+	default:
+		return fmt.Errorf("invalid drone_weather.temp_comparison %q (expected %s or %s)",
+			c.DroneWeather.TempComparison, TempComparisonActual, TempComparisonApparent)
+	}
+	switch c.DroneWeather.Units {
+	case UnitsMetric, UnitsImperial:
+	default:
+		return fmt.Errorf("invalid drone_weather.units %q (expected %s or %s)",
+			c.DroneWeather.Units, UnitsMetric, UnitsImperial)
+	}
+	if p := c.DroneWeather.MaxPrecipProbabilityPct; p < 0 || p > 100 {
+		return fmt.Errorf("drone_weather.max_precip_probability_pct must be between 0 and 100, got %d", p)
+	}
+	if c.DroneWeather.CautionMarginPct >= 100 {
+		return fmt.Errorf("drone_weather.caution_margin_pct must be below 100, got %d", c.DroneWeather.CautionMarginPct)
+	}
+	if d := c.DroneWeather.ForecastDays; d < 0 || d > MaxForecastDays {
+		return fmt.Errorf("drone_weather.forecast_days must be between 0 and %d, got %d", MaxForecastDays, d)
+	}
+	if c.DroneWeather.SearchRadiusKm < 0 {
+		return fmt.Errorf("drone_weather.search_radius_km must not be negative, got %d", c.DroneWeather.SearchRadiusKm)
+	}
+	if c.DroneWeather.HTTPTimeoutSeconds <= 0 {
+		return fmt.Errorf("drone_weather.http_timeout_seconds must be positive, got %d", c.DroneWeather.HTTPTimeoutSeconds)
+	}
+	for _, provider := range []string{c.DroneWeather.WeatherProvider, c.DroneWeather.FallbackWeatherProvider} {
+		switch provider {
+		case WeatherProviderOpenMeteo, "":
+		case WeatherProviderOpenWeatherMap:
+			if c.DroneWeather.OpenWeatherMapAPIKey == "" {
+				return fmt.Errorf("drone_weather.openweathermap_api_key is required for the %s provider (or set OPENWEATHERMAP_API_KEY)", WeatherProviderOpenWeatherMap)
+			}
+		default:
+			return fmt.Errorf("invalid drone_weather weather provider %q (expected %s or %s)", provider, WeatherProviderOpenMeteo, WeatherProviderOpenWeatherMap)
+		}
+	}
+	if c.DroneWeather.FallbackWeatherProvider != "" && c.DroneWeather.FallbackWeatherProvider == c.DroneWeather.WeatherProvider &&
+		(c.DroneWeather.FallbackWeatherURL == "" || c.DroneWeather.FallbackWeatherURL == c.DroneWeather.WeatherURL) {
+		return fmt.Errorf("drone_weather.fallback_weather_provider %q needs a fallback_weather_url different from weather_url", c.DroneWeather.FallbackWeatherProvider)
+	}
+	if c.DroneWeather.WeatherFetchAttempts < 1 {
+		return fmt.Errorf("drone_weather.weather_fetch_attempts must be at least 1, got %d", c.DroneWeather.WeatherFetchAttempts)
+	}
+	if c.DroneWeather.NotifyCooldownHours < 0 {
+		return fmt.Errorf("drone_weather.notify_cooldown_hours cannot be negative, got %d", c.DroneWeather.NotifyCooldownHours)
+	}
+	if c.DroneWeather.NotifyResetNoFlyRuns < 1 {
+		return fmt.Errorf("drone_weather.notify_reset_no_fly_runs must be at least 1, got %d", c.DroneWeather.NotifyResetNoFlyRuns)
+	}
+	if c.DroneWeather.NoFlyHeadsUpRuns < 0 {
+		return fmt.Errorf("drone_weather.no_fly_heads_up_runs cannot be negative, got %d", c.DroneWeather.NoFlyHeadsUpRuns)
+	}
+	if c.DroneWeather.MinWindowMinutes < 0 {
+		return fmt.Errorf("drone_weather.min_window_minutes cannot be negative, got %d", c.DroneWeather.MinWindowMinutes)
+	}
+	if c.DroneWeather.ForecastHours < 1 || c.DroneWeather.ForecastHours > MaxForecastHours {
+		return fmt.Errorf("drone_weather.forecast_hours must be between 1 and %d, got %d", MaxForecastHours, c.DroneWeather.ForecastHours)
+	}
+	if _, err := template.New("subject").Parse(c.DroneWeather.SubjectTemplate); err != nil {
+		return fmt.Errorf("invalid drone_weather.subject_template: %w", err)
+	}
 	return nil
 }
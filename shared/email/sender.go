@@ -2,26 +2,60 @@ package email
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"html/template"
+	"log"
+	"net"
 	"net/smtp"
 	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
 
 	"agent-stack/internal/models"
 	"agent-stack/shared/config"
 )
 
+// DefaultReportSubject is the subject template used for YouTube digest emails when none is configured
+const DefaultReportSubject = `YouTube Video Digest - {{.Selected}} Videos Worth Watching ({{.Date.Format "Jan 2, 2006"}})`
+
+// EmailSender delivers agent emails. *Sender sends over SMTP; tests can substitute a fake.
+type EmailSender interface {
+	SendReport(report *models.EmailReport, subjectTemplate string) error
+	SendHTML(subject, htmlBody string) error
+}
+
+var _ EmailSender = (*Sender)(nil)
+
 type Sender struct {
-	config *config.EmailConfig
+	config   *config.EmailConfig
+	rootCAs  *x509.CertPool // nil trusts the system roots
+	observer SendObserver
+	location *time.Location // report dates are shown in this zone; nil means UTC
 }
 
+// SendObserver is told how long each SMTP delivery took and whether it failed
+type SendObserver func(latency time.Duration, err error)
+
+// implicitTLSPort is the SMTPS port, where TLS starts before any SMTP traffic
+const implicitTLSPort = 465
+
+// authHint is appended to authentication failures, which are usually caused by
+// using the account password where the provider requires an app password
+const authHint = "check email.username and email.password; Gmail, iCloud and Outlook accounts with 2FA need an app password, not the account password"
+
 func NewSender(cfg *config.EmailConfig) *Sender {
 	return &Sender{
 		config: cfg,
 	}
 }
 
-func (s *Sender) SendReport(report *models.EmailReport) error {
+// SendReport sends the YouTube digest. subjectTemplate is rendered against the
+// report; an empty value uses DefaultReportSubject.
+func (s *Sender) SendReport(report *models.EmailReport, subjectTemplate string) error {
 	if report == nil {
 		return fmt.Errorf("report cannot be nil")
 	}
@@ -30,10 +64,15 @@ func (s *Sender) SendReport(report *models.EmailReport) error {
 		return nil // No videos to report
 	}
 
-	subject := fmt.Sprintf("YouTube Video Digest - %d Videos Worth Watching (%s)",
-		report.Selected, report.Date.Format("Jan 2, 2006"))
+	// The subject template formats .Date itself, so hand it a localized copy
+	localized := *report
+	localized.Date = report.Date.In(displayLocation(s.location))
+	subject, err := RenderSubject(subjectTemplate, DefaultReportSubject, &localized)
+	if err != nil {
+		return fmt.Errorf("failed to render email subject: %w", err)
+	}
 
-	body, err := s.generateEmailBody(report)
+	body, err := RenderReport(report, s.location)
 	if err != nil {
 		return fmt.Errorf("failed to generate email body: %w", err)
 	}
@@ -41,15 +80,65 @@ func (s *Sender) SendReport(report *models.EmailReport) error {
 	return s.SendHTML(subject, body)
 }
 
+// RenderSubject renders a Go text/template subject line against data,
+// falling back to defaultTemplate when tmpl is empty
+func RenderSubject(tmpl, defaultTemplate string, data interface{}) (string, error) {
+	if tmpl == "" {
+		tmpl = defaultTemplate
+	}
+
+	t, err := texttemplate.New("subject").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse subject template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute subject template: %w", err)
+	}
+
+	// Header values must stay on a single line
+	return strings.Join(strings.Fields(buf.String()), " "), nil
+}
+
+// SetObserver reports the latency and outcome of every SMTP delivery to observer;
+// nil stops reporting
+func (s *Sender) SetObserver(observer SendObserver) {
+	s.observer = observer
+}
+
+// SetLocation sets the timezone report dates are rendered in; nil means UTC
+func (s *Sender) SetLocation(loc *time.Location) {
+	s.location = loc
+}
+
 // SendHTML sends an email with custom HTML content
 func (s *Sender) SendHTML(subject, htmlBody string) error {
 	return s.sendViaSMTP(subject, htmlBody)
 }
 
+// sendViaSMTP delivers the message, timing the whole SMTP exchange for the observer
 func (s *Sender) sendViaSMTP(subject, body string) error {
-	auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.SMTPServer)
+	if s.config.SaveReportsDir != "" {
+		// Archiving is best effort and must never block delivery
+		if path, err := saveReport(s.config.SaveReportsDir, subject, body, time.Now()); err != nil {
+			log.Printf("Warning: Failed to save email HTML: %v", err)
+		} else {
+			log.Printf("Saved email HTML to %s", path)
+		}
+	}
 
-	to := []string{s.config.ToEmail}
+	start := time.Now()
+	err := s.deliver(subject, body)
+	if s.observer != nil {
+		s.observer(time.Since(start), err)
+	}
+	return err
+}
+
+// deliver mirrors smtp.SendMail but drives the client directly so the
+// EHLO hostname can be configured
+func (s *Sender) deliver(subject, body string) error {
 	msg := []byte(fmt.Sprintf(`To: %s
 From: %s
 Subject: %s
@@ -58,11 +147,114 @@ Content-Type: text/html; charset=UTF-8
 
 %s`, s.config.ToEmail, s.config.FromEmail, subject, body))
 
-	addr := fmt.Sprintf("%s:%d", s.config.SMTPServer, s.config.SMTPPort)
-	return smtp.SendMail(addr, auth, s.config.FromEmail, to, msg)
+	client, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if s.config.HeloHost != "" {
+		if err := client.Hello(s.config.HeloHost); err != nil {
+			return fmt.Errorf("SMTP HELO failed: %w", err)
+		}
+	}
+	if !s.implicitTLS() {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(s.tlsConfig()); err != nil {
+				return fmt.Errorf("SMTP STARTTLS failed: %w", err)
+			}
+		}
+	}
+	if s.config.Username != "" || s.config.Password != "" {
+		// Sending unauthenticated would silently ignore the configured credentials
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("SMTP server does not advertise AUTH but credentials are configured (check email.smtp_server, email.smtp_port and email.tls_mode)")
+		}
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.SMTPServer)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed (%s): %w", authHint, err)
+		}
+	}
+
+	if err := client.Mail(s.config.FromEmail); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	if err := client.Rcpt(s.config.ToEmail); err != nil {
+		return fmt.Errorf("SMTP RCPT TO failed: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write email body: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finish email body: %w", err)
+	}
+	return client.Quit()
+}
+
+// dial connects to the SMTP server within the configured timeout, negotiating
+// TLS up front when implicitTLS applies. The timeout also becomes a deadline for
+// the rest of the exchange so an unresponsive server cannot hang a run.
+func (s *Sender) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.config.SMTPServer, fmt.Sprintf("%d", s.config.SMTPPort))
+	timeout := s.timeout()
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set SMTP deadline: %w", err)
+	}
+
+	if s.implicitTLS() {
+		tlsConn := tls.Client(conn, s.tlsConfig())
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to connect to SMTP server over TLS: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	client, err := smtp.NewClient(conn, s.config.SMTPServer)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	return client, nil
+}
+
+// timeout returns the configured SMTP timeout, falling back to the default
+func (s *Sender) timeout() time.Duration {
+	if s.config.SMTPTimeoutSeconds > 0 {
+		return time.Duration(s.config.SMTPTimeoutSeconds) * time.Second
+	}
+	return config.DefaultSMTPTimeoutSeconds * time.Second
+}
+
+// implicitTLS reports whether the connection must be TLS from the start, as on
+// port 465, rather than upgraded with STARTTLS
+func (s *Sender) implicitTLS() bool {
+	switch s.config.TLSMode {
+	case config.TLSModeImplicit:
+		return true
+	case config.TLSModeStartTLS:
+		return false
+	}
+	return s.config.SMTPPort == implicitTLSPort
+}
+
+func (s *Sender) tlsConfig() *tls.Config {
+	return &tls.Config{ServerName: s.config.SMTPServer, RootCAs: s.rootCAs}
 }
 
-func (s *Sender) generateEmailBody(report *models.EmailReport) (string, error) {
+// RenderReport renders the YouTube digest HTML for report, showing dates in loc
+// (UTC when nil). The template path is relative to the repository root, which is
+// the working directory in all deployments.
+func RenderReport(report *models.EmailReport, loc *time.Location) (string, error) {
 	// Read template from external file
 	templatePath := "agents/youtube-curator/email_template.html"
 	tmplBytes, err := os.ReadFile(templatePath)
@@ -77,8 +269,10 @@ func (s *Sender) generateEmailBody(report *models.EmailReport) (string, error) {
 			}
 			return a / b
 		},
-		"mul":     func(a, b float64) float64 { return a * b },
-		"float64": func(i int) float64 { return float64(i) },
+		"mul":       func(a, b float64) float64 { return a * b },
+		"float64":   func(i int) float64 { return float64(i) },
+		"local":     func(t time.Time) time.Time { return t.In(displayLocation(loc)) },
+		"byChannel": groupByChannel,
 	})
 
 	tmpl, err = tmpl.Parse(string(tmplBytes))
@@ -93,3 +287,75 @@ func (s *Sender) generateEmailBody(report *models.EmailReport) (string, error) {
 
 	return buf.String(), nil
 }
+
+// channelGroup is one channel's videos in a report grouped by channel
+type channelGroup struct {
+	Channel string
+	Videos  []*models.Analysis
+}
+
+// groupByChannel groups analyses by channel without reordering them: channels
+// appear in the order of their first (best-ranked) video and each group keeps
+// the overall ordering
+func groupByChannel(analyses []*models.Analysis) []*channelGroup {
+	var groups []*channelGroup
+	byName := make(map[string]*channelGroup)
+	for _, analysis := range analyses {
+		channel := analysis.Video.ChannelTitle
+		group, ok := byName[channel]
+		if !ok {
+			group = &channelGroup{Channel: channel}
+			byName[channel] = group
+			groups = append(groups, group)
+		}
+		group.Videos = append(group.Videos, analysis)
+	}
+	return groups
+}
+
+// saveReport writes body to dir under a timestamped name derived from subject,
+// returning the file path
+func saveReport(dir, subject, body string, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create reports directory: %w", err)
+	}
+
+	name := now.Format("20060102-150405.000")
+	if slug := reportSlug(subject); slug != "" {
+		name += "-" + slug
+	}
+	path := filepath.Join(dir, name+".html")
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// reportSlug turns a subject into a short lowercase file name fragment
+func reportSlug(subject string) string {
+	const maxLen = 60
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(subject) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= maxLen {
+			break
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// displayLocation defaults an unset display timezone to UTC so rendered dates
+// never depend on the server's zone
+func displayLocation(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,640 @@
+package email
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agent-stack/internal/models"
+	"agent-stack/shared/config"
+)
+
+func TestRenderSubject(t *testing.T) {
+	report := &models.EmailReport{
+		Date:     time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC),
+		Total:    12,
+		Selected: 3,
+	}
+
+	tests := []struct {
+		name     string
+		tmpl     string
+		expected string
+	}{
+		{
+			name:     "Default template",
+			tmpl:     "",
+			expected: "YouTube Video Digest - 3 Videos Worth Watching (Mar 14, 2025)",
+		},
+		{
+			name:     "Custom template with counts and date",
+			tmpl:     `[{{.Date.Format "2006-01-02"}}] {{.Selected}}/{{.Total}} picks`,
+			expected: "[2025-03-14] 3/12 picks",
+		},
+		{
+			name:     "Newlines collapsed to keep header on one line",
+			tmpl:     "Digest\n{{.Selected}} videos",
+			expected: "Digest 3 videos",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, err := RenderSubject(tt.tmpl, DefaultReportSubject, report)
+			if err != nil {
+				t.Fatalf("RenderSubject() error: %v", err)
+			}
+			if subject != tt.expected {
+				t.Errorf("RenderSubject() = %q, want %q", subject, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRenderSubjectInvalidTemplate(t *testing.T) {
+	if _, err := RenderSubject("{{.Selected", DefaultReportSubject, &models.EmailReport{}); err == nil {
+		t.Error("Expected error for malformed template")
+	}
+	if _, err := RenderSubject("{{.Missing}}", DefaultReportSubject, &models.EmailReport{}); err == nil {
+		t.Error("Expected error for unknown field")
+	}
+}
+
+func TestRenderReportIncludesAnalysisFields(t *testing.T) {
+	t.Chdir("../..")
+
+	report := &models.EmailReport{
+		Date:     time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC),
+		Total:    4,
+		Selected: 1,
+		Videos: []*models.Analysis{
+			{
+				Video: &models.Video{
+					Title:        "Profiling Go Services",
+					ChannelTitle: "Go Channel",
+					URL:          "https://www.youtube.com/watch?v=abc123",
+				},
+				IsRelevant: true,
+				Summary:    "A walkthrough of pprof on a production service.",
+				Reasoning:  "Hands-on performance content matching the criteria.",
+				ValueProp:  "Learn to find CPU and allocation hot spots.",
+				Score:      8,
+			},
+		},
+	}
+
+	body, err := RenderReport(report, nil)
+	if err != nil {
+		t.Fatalf("RenderReport() error: %v", err)
+	}
+
+	for _, want := range []string{
+		"Summary:",
+		"A walkthrough of pprof on a production service.",
+		"Reasoning:",
+		"Hands-on performance content matching the criteria.",
+		"Why Watch:",
+		"Learn to find CPU and allocation hot spots.",
+		"Score: 8/10",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected rendered report to contain %q", want)
+		}
+	}
+}
+
+func TestRenderReportMentionsSection(t *testing.T) {
+	t.Chdir("../..")
+
+	report := &models.EmailReport{
+		Date:     time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC),
+		Total:    2,
+		Selected: 1,
+		Videos: []*models.Analysis{
+			{Video: &models.Video{Title: "Profiling Go Services"}, Summary: "pprof walkthrough", Score: 8},
+		},
+	}
+
+	body, err := RenderReport(report, nil)
+	if err != nil {
+		t.Fatalf("RenderReport() error: %v", err)
+	}
+	if strings.Contains(body, "Honorable Mentions") {
+		t.Error("Expected no mentions section without mentions")
+	}
+
+	report.Mentions = []*models.Analysis{
+		{Video: &models.Video{Title: "Go 1.25 release notes", ChannelTitle: "Go News"}, Summary: "Changelog skim", Score: 5},
+	}
+	body, err = RenderReport(report, nil)
+	if err != nil {
+		t.Fatalf("RenderReport() error: %v", err)
+	}
+	for _, want := range []string{"Honorable Mentions", "Go 1.25 release notes", "Score: 5/10", "Changelog skim"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected rendered report to contain %q", want)
+		}
+	}
+}
+
+func TestRenderReportUsesDisplayTimezone(t *testing.T) {
+	t.Chdir("../..")
+
+	// 02:30 UTC on the 15th is still the evening of the 14th in New York
+	published := time.Date(2025, 3, 15, 2, 30, 0, 0, time.UTC)
+	report := &models.EmailReport{
+		Date:     published,
+		Total:    1,
+		Selected: 1,
+		Videos: []*models.Analysis{
+			{Video: &models.Video{Title: "Profiling Go Services", PublishedAt: published}, Summary: "pprof walkthrough", Score: 8},
+		},
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("Failed to load timezone: %v", err)
+	}
+	body, err := RenderReport(report, newYork)
+	if err != nil {
+		t.Fatalf("RenderReport() error: %v", err)
+	}
+	for _, want := range []string{"Friday, March 14, 2025", "Mar 14, 22:30 EDT"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected rendered report to contain %q", want)
+		}
+	}
+
+	body, err = RenderReport(report, nil)
+	if err != nil {
+		t.Fatalf("RenderReport() error: %v", err)
+	}
+	for _, want := range []string{"Saturday, March 15, 2025", "Mar 15, 02:30 UTC"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected unset timezone to render UTC %q", want)
+		}
+	}
+}
+
+func TestRenderReportGroupsByChannel(t *testing.T) {
+	t.Chdir("../..")
+
+	analysis := func(title, channel string, score int) *models.Analysis {
+		return &models.Analysis{Video: &models.Video{Title: title, ChannelTitle: channel}, Score: score}
+	}
+	// Score order interleaves the two channels
+	report := &models.EmailReport{
+		Date:     time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC),
+		Total:    4,
+		Selected: 4,
+		Videos: []*models.Analysis{
+			analysis("Alpha One", "Alpha Channel", 9),
+			analysis("Beta One", "Beta Channel", 8),
+			analysis("Alpha Two", "Alpha Channel", 7),
+			analysis("Beta Two", "Beta Channel", 6),
+		},
+	}
+
+	order := func(body string, markers ...string) []int {
+		var positions []int
+		for _, marker := range markers {
+			positions = append(positions, strings.Index(body, marker))
+		}
+		return positions
+	}
+
+	flat, err := RenderReport(report, nil)
+	if err != nil {
+		t.Fatalf("RenderReport() error: %v", err)
+	}
+	if strings.Contains(flat, `class="channel"`) {
+		t.Error("Expected no channel headers without grouping")
+	}
+
+	report.GroupByChannel = true
+	grouped, err := RenderReport(report, nil)
+	if err != nil {
+		t.Fatalf("RenderReport() error: %v", err)
+	}
+	if n := strings.Count(grouped, `<h2 class="channel">`); n != 2 {
+		t.Fatalf("Expected 2 channel headers, got %d", n)
+	}
+	positions := order(grouped, `<h2 class="channel">Alpha Channel</h2>`, "Alpha One", "Alpha Two", `<h2 class="channel">Beta Channel</h2>`, "Beta One", "Beta Two")
+	for i := 1; i < len(positions); i++ {
+		if positions[i-1] < 0 || positions[i-1] > positions[i] {
+			t.Fatalf("Expected each channel's videos under its header in score order, got positions %v", positions)
+		}
+	}
+}
+
+func TestRenderReportThumbnails(t *testing.T) {
+	t.Chdir("../..")
+
+	tests := []struct {
+		name      string
+		thumbnail string
+	}{
+		{"With thumbnail", "https://i.ytimg.com/vi/abc123/mqdefault.jpg"},
+		{"Without thumbnail", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &models.EmailReport{
+				Date:     time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC),
+				Total:    1,
+				Selected: 1,
+				Videos: []*models.Analysis{{
+					Video:   &models.Video{Title: "Profiling Go Services", URL: "https://www.youtube.com/watch?v=abc123", ThumbnailURL: tt.thumbnail},
+					Summary: "pprof walkthrough",
+					Score:   8,
+				}},
+			}
+
+			body, err := RenderReport(report, nil)
+			if err != nil {
+				t.Fatalf("RenderReport() error: %v", err)
+			}
+			if hasImage := strings.Contains(body, "<img"); hasImage != (tt.thumbnail != "") {
+				t.Errorf("Expected image rendered=%v, got body:\n%s", tt.thumbnail != "", body)
+			}
+			if tt.thumbnail != "" && !strings.Contains(body, `src="`+tt.thumbnail+`"`) {
+				t.Errorf("Expected thumbnail URL %q in rendered report", tt.thumbnail)
+			}
+		})
+	}
+}
+
+// heloRecorder is a minimal SMTP server that records the EHLO/HELO name of each connection
+type heloRecorder struct {
+	listener   net.Listener
+	names      chan string
+	rejectAuth bool          // answer AUTH with 535 like a provider refusing the account password
+	noAuth     bool          // don't advertise AUTH, like a relay or a server expecting TLS first
+	delay      time.Duration // pause before accepting the message, like a slow server
+}
+
+func newHeloRecorder(t *testing.T) *heloRecorder {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start fake SMTP server: %v", err)
+	}
+	return startHeloRecorder(t, listener)
+}
+
+// newTLSHeloRecorder starts a heloRecorder that speaks TLS from the first byte, as
+// SMTP servers on port 465 do, returning a pool that trusts its certificate
+func newTLSHeloRecorder(t *testing.T) (*heloRecorder, *x509.CertPool) {
+	t.Helper()
+
+	// Borrow httptest's self-signed certificate for 127.0.0.1
+	certServer := httptest.NewUnstartedServer(nil)
+	certServer.StartTLS()
+	certificate := certServer.TLS.Certificates[0]
+	roots := x509.NewCertPool()
+	roots.AddCert(certServer.Certificate())
+	certServer.Close()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{certificate}})
+	if err != nil {
+		t.Fatalf("Failed to start fake SMTPS server: %v", err)
+	}
+	return startHeloRecorder(t, listener), roots
+}
+
+func startHeloRecorder(t *testing.T, listener net.Listener) *heloRecorder {
+	t.Helper()
+
+	r := &heloRecorder{listener: listener, names: make(chan string, 10)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go r.handle(conn)
+		}
+	}()
+	return r
+}
+
+func (r *heloRecorder) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+	reply("220 localhost ESMTP")
+	inData := false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if inData {
+			if line == ".\r\n" {
+				inData = false
+				reply("250 OK")
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "EHLO", "HELO":
+			if len(fields) > 1 {
+				r.names <- fields[1]
+			}
+			if r.noAuth {
+				reply("250 localhost")
+				continue
+			}
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			if r.rejectAuth {
+				reply("535 5.7.8 Username and Password not accepted")
+				continue
+			}
+			reply("235 Authentication successful")
+		case "DATA":
+			time.Sleep(r.delay)
+			inData = true
+			reply("354 End data with <CR><LF>.<CR><LF>")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSendHTMLUsesConfiguredHeloHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		heloHost string
+		expected string
+	}{
+		{"Default", "", "localhost"},
+		{"Configured", "mail.example.com", "mail.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newHeloRecorder(t)
+			sender := NewSender(&config.EmailConfig{
+				SMTPServer: "127.0.0.1",
+				SMTPPort:   server.listener.Addr().(*net.TCPAddr).Port,
+				Username:   "user",
+				Password:   "pass",
+				FromEmail:  "from@test.com",
+				ToEmail:    "to@test.com",
+				HeloHost:   tt.heloHost,
+			})
+
+			if err := sender.SendHTML("Subject", "<p>Hello</p>"); err != nil {
+				t.Fatalf("SendHTML() error: %v", err)
+			}
+
+			select {
+			case name := <-server.names:
+				if name != tt.expected {
+					t.Errorf("Expected HELO name %q, got %q", tt.expected, name)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for HELO")
+			}
+		})
+	}
+}
+
+func TestSendHTMLImplicitTLS(t *testing.T) {
+	server, roots := newTLSHeloRecorder(t)
+	sender := NewSender(&config.EmailConfig{
+		SMTPServer: "127.0.0.1",
+		SMTPPort:   server.listener.Addr().(*net.TCPAddr).Port,
+		Username:   "user",
+		Password:   "pass",
+		FromEmail:  "from@test.com",
+		ToEmail:    "to@test.com",
+		TLSMode:    config.TLSModeImplicit, // the test port stands in for 465
+	})
+	sender.rootCAs = roots
+
+	if err := sender.SendHTML("Subject", "<p>Hello</p>"); err != nil {
+		t.Fatalf("SendHTML() over implicit TLS error: %v", err)
+	}
+	select {
+	case <-server.names:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for HELO over TLS")
+	}
+}
+
+func TestImplicitTLSSelection(t *testing.T) {
+	tests := []struct {
+		name     string
+		port     int
+		mode     string
+		expected bool
+	}{
+		{"Port 465 defaults to implicit TLS", 465, "", true},
+		{"Port 587 defaults to STARTTLS", 587, "", false},
+		{"Explicit tls on another port", 2465, config.TLSModeImplicit, true},
+		{"Explicit starttls on 465", 465, config.TLSModeStartTLS, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := NewSender(&config.EmailConfig{SMTPPort: tt.port, TLSMode: tt.mode})
+			if got := sender.implicitTLS(); got != tt.expected {
+				t.Errorf("Expected implicitTLS %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSendHTMLRequiresAuthWhenCredentialsSet(t *testing.T) {
+	server := newHeloRecorder(t)
+	server.noAuth = true
+	sender := NewSender(&config.EmailConfig{
+		SMTPServer: "127.0.0.1",
+		SMTPPort:   server.listener.Addr().(*net.TCPAddr).Port,
+		Username:   "user",
+		Password:   "pass",
+		FromEmail:  "from@test.com",
+		ToEmail:    "to@test.com",
+	})
+
+	err := sender.SendHTML("Subject", "<p>Hello</p>")
+	if err == nil || !strings.Contains(err.Error(), "does not advertise AUTH") {
+		t.Errorf("Expected an error when the server does not offer AUTH, got %v", err)
+	}
+}
+
+func TestSendHTMLAuthFailureHintsAppPassword(t *testing.T) {
+	server := newHeloRecorder(t)
+	server.rejectAuth = true
+	sender := NewSender(&config.EmailConfig{
+		SMTPServer: "127.0.0.1",
+		SMTPPort:   server.listener.Addr().(*net.TCPAddr).Port,
+		Username:   "user@gmail.com",
+		Password:   "account-password",
+		FromEmail:  "from@test.com",
+		ToEmail:    "to@test.com",
+	})
+
+	err := sender.SendHTML("Subject", "<p>Hello</p>")
+	if err == nil {
+		t.Fatal("Expected authentication to fail")
+	}
+	if !strings.Contains(err.Error(), "app password") || !strings.Contains(err.Error(), "535") {
+		t.Errorf("Expected an app password hint alongside the server error, got %v", err)
+	}
+}
+
+func TestSendHTMLReportsLatency(t *testing.T) {
+	tests := []struct {
+		name       string
+		rejectAuth bool
+	}{
+		{"Delivered", false},
+		{"Rejected", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newHeloRecorder(t)
+			server.delay = 50 * time.Millisecond
+			server.rejectAuth = tt.rejectAuth
+			sender := NewSender(&config.EmailConfig{
+				SMTPServer: "127.0.0.1",
+				SMTPPort:   server.listener.Addr().(*net.TCPAddr).Port,
+				Username:   "user",
+				Password:   "pass",
+				FromEmail:  "from@test.com",
+				ToEmail:    "to@test.com",
+			})
+
+			var observed []time.Duration
+			var observedErr error
+			sender.SetObserver(func(latency time.Duration, err error) {
+				observed = append(observed, latency)
+				observedErr = err
+			})
+
+			sendErr := sender.SendHTML("Subject", "<p>Hello</p>")
+			if len(observed) != 1 {
+				t.Fatalf("Expected one observation, got %d", len(observed))
+			}
+			if (observedErr != nil) != tt.rejectAuth || observedErr != sendErr {
+				t.Errorf("Expected observed error to match send error %v, got %v", sendErr, observedErr)
+			}
+			if !tt.rejectAuth && observed[0] < server.delay {
+				t.Errorf("Expected latency of at least %v, got %v", server.delay, observed[0])
+			}
+		})
+	}
+}
+
+func TestSendHTMLSavesReportEvenWhenDeliveryFails(t *testing.T) {
+	server := newHeloRecorder(t)
+	server.rejectAuth = true
+	dir := filepath.Join(t.TempDir(), "reports")
+	sender := NewSender(&config.EmailConfig{
+		SMTPServer:     "127.0.0.1",
+		SMTPPort:       server.listener.Addr().(*net.TCPAddr).Port,
+		Username:       "user",
+		Password:       "pass",
+		FromEmail:      "from@test.com",
+		ToEmail:        "to@test.com",
+		SaveReportsDir: dir,
+	})
+
+	if err := sender.SendHTML("Drone Weather: Good to fly!", "<p>Hello</p>"); err == nil {
+		t.Fatal("Expected delivery to fail")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected one saved report, got %v (%v)", files, err)
+	}
+	if !strings.HasSuffix(files[0], "-drone-weather-good-to-fly.html") {
+		t.Errorf("Expected a timestamped name derived from the subject, got %s", filepath.Base(files[0]))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to read saved report: %v", err)
+	}
+	if string(data) != "<p>Hello</p>" {
+		t.Errorf("Expected saved HTML to match the email body, got %q", data)
+	}
+}
+
+func TestSendHTMLFailsWithinTimeout(t *testing.T) {
+	// Reserve a port, then free it so nothing is listening there
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve a port: %v", err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	// A server that accepts connections but never sends its greeting
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer silent.Close()
+	go func() {
+		for {
+			conn, err := silent.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		name string
+		port int
+	}{
+		{"Not listening", closedPort},
+		{"Never responds", silent.Addr().(*net.TCPAddr).Port},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := NewSender(&config.EmailConfig{
+				SMTPServer:         "127.0.0.1",
+				SMTPPort:           tt.port,
+				FromEmail:          "from@test.com",
+				ToEmail:            "to@test.com",
+				SMTPTimeoutSeconds: 1,
+			})
+
+			start := time.Now()
+			if err := sender.SendHTML("Subject", "<p>Hello</p>"); err == nil {
+				t.Fatal("Expected send to fail")
+			}
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("Expected send to give up within the 1s timeout, took %v", elapsed)
+			}
+		})
+	}
+}
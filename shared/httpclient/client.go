@@ -0,0 +1,40 @@
+package httpclient
+
+import (
+	"net/http"
+	"time"
+
+	"agent-stack/shared/version"
+)
+
+// DefaultUserAgent identifies agent-stack and its version to the APIs it calls,
+// with a link operators can use to reach us
+var DefaultUserAgent = "agent-stack/" + version.Version + " (+https://github.com/ETeissonniere/agent-stack)"
+
+// New returns an HTTP client with the given timeout that sets userAgent on
+// every outgoing request. An empty userAgent uses DefaultUserAgent.
+func New(timeout time.Duration, userAgent string) *http.Client {
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &userAgentTransport{
+			base:      http.DefaultTransport,
+			userAgent: userAgent,
+		},
+	}
+}
+
+// userAgentTransport sets the User-Agent header before delegating to base
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,48 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewSetsUserAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		expected  string
+	}{
+		{"Default", "", DefaultUserAgent},
+		{"Configured", "my-drone-bot/2.0 (me@example.com)", "my-drone-bot/2.0 (me@example.com)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.Header.Get("User-Agent")
+			}))
+			defer server.Close()
+
+			req, err := http.NewRequest("GET", server.URL, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("User-Agent", "Go-http-client/1.1")
+
+			resp, err := New(5*time.Second, tt.userAgent).Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+
+			if received != tt.expected {
+				t.Errorf("Expected User-Agent %q, got %q", tt.expected, received)
+			}
+			if req.Header.Get("User-Agent") != "Go-http-client/1.1" {
+				t.Error("Transport must not modify the caller's request")
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DebugBodyLimit caps how much of each response body is written to disk
+const DebugBodyLimit = 1 << 20
+
+// DebugMaxFiles caps how many captured bodies are kept; the oldest are deleted first
+const DebugMaxFiles = 100
+
+// WithDebugCapture returns a copy of client that logs every outbound URL and
+// response status, and saves raw response bodies (up to DebugBodyLimit bytes)
+// as files in dir, keeping the newest DebugMaxFiles. Meant for diagnosing
+// changes in public API responses.
+func WithDebugCapture(client *http.Client, dir string) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	debugClient := *client
+	debugClient.Transport = &debugTransport{base: base, dir: dir, maxFiles: DebugMaxFiles}
+	return &debugClient
+}
+
+// debugTransport logs requests and captures response bodies before delegating to base
+type debugTransport struct {
+	base     http.RoundTripper
+	dir      string
+	maxFiles int
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	log.Printf("HTTP debug: %s %s", req.Method, req.URL)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		log.Printf("HTTP debug: %s %s failed: %v", req.Method, req.URL, err)
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	// Hand the caller an untouched copy of the full body
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	path, err := t.capture(req, body)
+	if err != nil {
+		log.Printf("HTTP debug: failed to capture response body: %v", err)
+	} else {
+		log.Printf("HTTP debug: %s %s -> %d (%d bytes, saved to %s)", req.Method, req.URL, resp.StatusCode, len(body), path)
+	}
+	return resp, nil
+}
+
+// capture writes body, truncated to DebugBodyLimit, to a timestamped file named after the host
+func (t *debugTransport) capture(req *http.Request, body []byte) (string, error) {
+	if err := os.MkdirAll(t.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create debug directory: %w", err)
+	}
+
+	if len(body) > DebugBodyLimit {
+		body = body[:DebugBodyLimit]
+	}
+
+	host := strings.NewReplacer(":", "_", "/", "_").Replace(req.URL.Host)
+	path := filepath.Join(t.dir, fmt.Sprintf("%s-%s.body", time.Now().Format("20060102-150405.000000"), host))
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := t.prune(); err != nil {
+		log.Printf("HTTP debug: failed to remove old captures: %v", err)
+	}
+	return path, nil
+}
+
+// prune deletes the oldest captured bodies beyond maxFiles. File names start with
+// their capture time, so name order is capture order.
+func (t *debugTransport) prune() error {
+	files, err := filepath.Glob(filepath.Join(t.dir, "*.body"))
+	if err != nil || len(files) <= t.maxFiles {
+		return err
+	}
+	sort.Strings(files)
+	for _, file := range files[:len(files)-t.maxFiles] {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
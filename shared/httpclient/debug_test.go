@@ -0,0 +1,117 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugCaptureWritesBody(t *testing.T) {
+	const payload = `{"type": "FeatureCollection", "features": []}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "http_debug")
+	client := WithDebugCapture(New(5*time.Second, ""), dir)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	if string(body) != payload {
+		t.Errorf("Expected caller to receive the full body, got %q", body)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.body"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected one captured body file, got %v (err %v)", files, err)
+	}
+	captured, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to read captured body: %v", err)
+	}
+	if string(captured) != payload {
+		t.Errorf("Expected captured body %q, got %q", payload, captured)
+	}
+}
+
+func TestDebugCaptureCapsBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", DebugBodyLimit+100)))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	resp, err := WithDebugCapture(New(5*time.Second, ""), dir).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if len(body) != DebugBodyLimit+100 {
+		t.Errorf("Expected caller to receive %d bytes, got %d", DebugBodyLimit+100, len(body))
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.body"))
+	if len(files) != 1 {
+		t.Fatalf("Expected one captured body file, got %v", files)
+	}
+	info, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatalf("Failed to stat captured body: %v", err)
+	}
+	if info.Size() != DebugBodyLimit {
+		t.Errorf("Expected captured body capped at %d bytes, got %d", DebugBodyLimit, info.Size())
+	}
+}
+
+func TestDebugCaptureKeepsNewestFiles(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, "response %d", requests)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	client := WithDebugCapture(New(5*time.Second, ""), dir)
+	client.Transport.(*debugTransport).maxFiles = 3
+
+	for i := 0; i < 5; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.body"))
+	if err != nil {
+		t.Fatalf("Failed to list captures: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 captured bodies, got %d", len(files))
+	}
+	sort.Strings(files)
+	oldest, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("Failed to read capture: %v", err)
+	}
+	if string(oldest) != "response 3" {
+		t.Errorf("Expected the oldest captures to be removed first, oldest kept is %q", oldest)
+	}
+}
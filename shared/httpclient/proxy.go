@@ -0,0 +1,28 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// SetProxy routes every request sent through http.DefaultTransport via
+// proxyURL, which may use the http, https or socks5 scheme. That transport
+// backs the weather and TFR clients as well as the YouTube OAuth and Gemini
+// clients, so one call covers all outbound API traffic. An empty proxyURL
+// leaves the standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables in effect.
+func SetProxy(proxyURL string) error {
+	if proxyURL == "" {
+		return nil
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("cannot configure proxy on default transport of type %T", http.DefaultTransport)
+	}
+	transport.Proxy = http.ProxyURL(u)
+	return nil
+}
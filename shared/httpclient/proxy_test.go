@@ -0,0 +1,42 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSetProxyRoutesRequestsThroughProxy(t *testing.T) {
+	transport := http.DefaultTransport.(*http.Transport)
+	originalProxy := transport.Proxy
+	t.Cleanup(func() { transport.Proxy = originalProxy })
+
+	requested := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Proxied requests carry the absolute target URL
+		requested <- r.URL.String()
+		fmt.Fprint(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	if err := SetProxy(proxy.URL); err != nil {
+		t.Fatalf("SetProxy failed: %v", err)
+	}
+
+	resp, err := New(5*time.Second, "").Get("http://api.example.invalid/v1/forecast")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	select {
+	case target := <-requested:
+		if target != "http://api.example.invalid/v1/forecast" {
+			t.Errorf("Expected proxy to receive the upstream URL, got %s", target)
+		}
+	default:
+		t.Fatal("Expected request to be routed through the proxy")
+	}
+}
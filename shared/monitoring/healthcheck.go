@@ -1,38 +1,117 @@
 package monitoring
 
 import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strings"
+
+	"agent-stack/shared/version"
 )
 
 type HealthServer struct {
-	monitor *Monitor
-	port    string
+	monitor  *Monitor
+	bindAddr string
+	port     string
+	listener net.Listener
+	server   *http.Server
+	// tracker and adminToken back /tracker/mark, which is disabled until both are set
+	tracker    TrackerEditor
+	adminToken string
+}
+
+// TrackerEditor is the part of an analyzed-video tracker that /tracker/mark edits
+type TrackerEditor interface {
+	MarkAnalyzed(videoID string) error
+	Unmark(videoID string) error
 }
 
-func NewHealthServer(monitor *Monitor, port string) *HealthServer {
+// NewHealthServer creates a health server listening on bindAddr:port.
+// An empty bindAddr binds all interfaces.
+func NewHealthServer(monitor *Monitor, bindAddr, port string) *HealthServer {
 	if port == "" {
 		port = "8080"
 	}
-	return &HealthServer{
-		monitor: monitor,
-		port:    port,
+	h := &HealthServer{
+		monitor:  monitor,
+		bindAddr: bindAddr,
+		port:     port,
 	}
+
+	// Use a dedicated mux so multiple servers can coexist in one process
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", h.healthHandler)
+	mux.HandleFunc("/livez", h.livenessHandler)
+	mux.HandleFunc("/readyz", h.healthHandler)
+	mux.HandleFunc("/status", h.statusHandler)
+	mux.HandleFunc("/last-check", h.lastCheckHandler)
+	mux.HandleFunc("/version", h.versionHandler)
+	mux.HandleFunc("/tfrs", h.snapshotHandler(SnapshotTFRs))
+	mux.HandleFunc("/tracker/mark", h.trackerMarkHandler)
+	h.server = &http.Server{Handler: mux}
+
+	return h
 }
 
-func (h *HealthServer) Start() {
-	http.HandleFunc("/health", h.healthHandler)
-	http.HandleFunc("/status", h.statusHandler)
+// SetTracker enables /tracker/mark for requests bearing token. Call before Start;
+// an empty token keeps the endpoint disabled.
+func (h *HealthServer) SetTracker(tracker TrackerEditor, token string) {
+	h.tracker = tracker
+	h.adminToken = token
+}
 
-	log.Printf("Health check server starting on port %s", h.port)
+// Start binds the listen address and serves health endpoints in the background
+func (h *HealthServer) Start() error {
+	listener, err := net.Listen("tcp", net.JoinHostPort(h.bindAddr, h.port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", net.JoinHostPort(h.bindAddr, h.port), err)
+	}
+	h.listener = listener
+
+	log.Printf("Health check server starting on %s", listener.Addr())
 	go func() {
-		if err := http.ListenAndServe(":"+h.port, nil); err != nil {
+		if err := h.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Printf("Health server error: %v", err)
 		}
 	}()
+	return nil
+}
+
+// Shutdown gracefully stops the server and releases its port, waiting for
+// in-flight requests until ctx is done
+func (h *HealthServer) Shutdown(ctx context.Context) error {
+	err := h.server.Shutdown(ctx)
+	// Serve may not have picked up the listener yet, so close it explicitly
+	if h.listener != nil {
+		_ = h.listener.Close()
+	}
+	return err
 }
 
+// Addr returns the address the server is listening on, or empty if not started
+func (h *HealthServer) Addr() string {
+	if h.listener == nil {
+		return ""
+	}
+	return h.listener.Addr().String()
+}
+
+// livenessHandler serves /livez. It answers 200 whenever the process can serve
+// HTTP at all, regardless of run outcomes, so orchestrators only restart a
+// process that is actually wedged rather than one whose last run failed.
+func (h *HealthServer) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "OK")
+}
+
+// healthHandler serves /health and /readyz. It reflects whether the agent's
+// last run succeeded (503 after a critical failure) and is meant for readiness
+// checks and alerting, not liveness.
 func (h *HealthServer) healthHandler(w http.ResponseWriter, r *http.Request) {
 	if h.monitor.IsHealthy() {
 		w.WriteHeader(http.StatusOK)
@@ -43,8 +122,101 @@ func (h *HealthServer) healthHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// statusHandler serves /status as plain text by default, or as JSON (including
+// staleness_seconds) when requested via ?format=json or an Accept header
 func (h *HealthServer) statusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(h.monitor.GetStatus()); err != nil {
+			log.Printf("Failed to encode status: %v", err)
+		}
+		return
+	}
+
 	w.Header().Set("Content-Type", "text/plain")
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, "%s", h.monitor.GetStatusSummary())
+	fmt.Fprintf(w, "%s\nVersion: %s", h.monitor.GetStatusSummary(), version.Get())
+}
+
+// versionHandler serves the running build's version information as JSON
+func (h *HealthServer) versionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(version.Get()); err != nil {
+		log.Printf("Failed to encode version: %v", err)
+	}
+}
+
+func (h *HealthServer) lastCheckHandler(w http.ResponseWriter, r *http.Request) {
+	snapshot := h.monitor.GetLastCheck()
+	if snapshot == nil {
+		http.Error(w, "No check recorded yet", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snapshot); err != nil {
+		log.Printf("Failed to encode last check snapshot: %v", err)
+	}
+}
+
+// snapshotHandler serves the named snapshot as JSON, or 404 until one is recorded
+func (h *HealthServer) snapshotHandler(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snapshot := h.monitor.GetSnapshot(name)
+		if snapshot == nil {
+			http.Error(w, "No "+name+" recorded yet", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(snapshot); err != nil {
+			log.Printf("Failed to encode %s snapshot: %v", name, err)
+		}
+	}
+}
+
+// trackerMarkHandler serves /tracker/mark?id=..., marking the video analyzed on POST
+// and forgetting it on DELETE. Requests must send "Authorization: Bearer <token>".
+func (h *HealthServer) trackerMarkHandler(w http.ResponseWriter, r *http.Request) {
+	if h.tracker == nil || h.adminToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	videoID := r.URL.Query().Get("id")
+	if videoID == "" {
+		http.Error(w, "Missing id parameter", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	switch r.Method {
+	case http.MethodPost:
+		err = h.tracker.MarkAnalyzed(videoID)
+	case http.MethodDelete:
+		err = h.tracker.Unmark(videoID)
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to update tracker for %s: %v", videoID, err)
+		http.Error(w, "Failed to update tracker", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Tracker updated via %s /tracker/mark for video %s", r.Method, videoID)
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,302 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"agent-stack/shared/version"
+)
+
+func TestHealthServerBindsConfiguredAddress(t *testing.T) {
+	server := NewHealthServer(NewMonitor(), "127.0.0.1", "0")
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start health server: %v", err)
+	}
+	defer server.Shutdown(context.Background())
+
+	host, _, err := net.SplitHostPort(server.Addr())
+	if err != nil {
+		t.Fatalf("Failed to parse listen address %q: %v", server.Addr(), err)
+	}
+	if host != "127.0.0.1" {
+		t.Errorf("Expected server bound to 127.0.0.1, got %s", host)
+	}
+}
+
+func TestMultipleHealthServers(t *testing.T) {
+	first := NewHealthServer(NewMonitor(), "127.0.0.1", "0")
+	second := NewHealthServer(NewMonitor(), "127.0.0.1", "0")
+
+	for _, server := range []*HealthServer{first, second} {
+		if err := server.Start(); err != nil {
+			t.Fatalf("Failed to start health server: %v", err)
+		}
+		defer server.Shutdown(context.Background())
+	}
+
+	for _, server := range []*HealthServer{first, second} {
+		resp, err := http.Get("http://" + server.Addr() + "/health")
+		if err != nil {
+			t.Fatalf("Failed to query %s: %v", server.Addr(), err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected 200 from %s, got %d", server.Addr(), resp.StatusCode)
+		}
+	}
+}
+
+func TestHealthServerShutdownReleasesPort(t *testing.T) {
+	server := NewHealthServer(NewMonitor(), "127.0.0.1", "0")
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start health server: %v", err)
+	}
+	addr := server.Addr()
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to shut down health server: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Port %s still in use after shutdown: %v", addr, err)
+	}
+	listener.Close()
+}
+
+func TestLivenessVersusReadiness(t *testing.T) {
+	tests := []struct {
+		name          string
+		record        func(m *Monitor)
+		expectLive    int
+		expectHealthy int
+	}{
+		{
+			name:          "Last run succeeded",
+			record:        func(m *Monitor) { m.RecordSuccess("ok", time.Second) },
+			expectLive:    http.StatusOK,
+			expectHealthy: http.StatusOK,
+		},
+		{
+			name:          "Last run failed",
+			record:        func(m *Monitor) { m.RecordCriticalFailure(errors.New("boom"), time.Second) },
+			expectLive:    http.StatusOK,
+			expectHealthy: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := NewMonitor()
+			tt.record(monitor)
+
+			server := NewHealthServer(monitor, "127.0.0.1", "0")
+			if err := server.Start(); err != nil {
+				t.Fatalf("Failed to start health server: %v", err)
+			}
+			defer server.Shutdown(context.Background())
+
+			for path, expected := range map[string]int{
+				"/livez":  tt.expectLive,
+				"/readyz": tt.expectHealthy,
+				"/health": tt.expectHealthy,
+			} {
+				resp, err := http.Get("http://" + server.Addr() + path)
+				if err != nil {
+					t.Fatalf("Failed to query %s: %v", path, err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != expected {
+					t.Errorf("Expected %d from %s, got %d", expected, path, resp.StatusCode)
+				}
+			}
+		})
+	}
+}
+
+func TestStatusJSON(t *testing.T) {
+	monitor := NewMonitor()
+	monitor.RecordSuccess("ok", time.Second)
+
+	server := NewHealthServer(monitor, "127.0.0.1", "0")
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start health server: %v", err)
+	}
+	defer server.Shutdown(context.Background())
+
+	resp, err := http.Get("http://" + server.Addr() + "/status?format=json")
+	if err != nil {
+		t.Fatalf("Failed to query /status: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode status JSON: %v", err)
+	}
+	if !status.Healthy || !status.LastRunSuccess || status.LastSuccessTime == nil {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+	if status.StalenessSeconds > 5 {
+		t.Errorf("Expected near-zero staleness right after success, got %d", status.StalenessSeconds)
+	}
+}
+
+func TestVersionEndpoint(t *testing.T) {
+	original := version.Get()
+	version.Version, version.Commit, version.BuildTime = "v1.2.3", "abc1234", "2025-01-02T03:04:05Z"
+	defer func() {
+		version.Version, version.Commit, version.BuildTime = original.Version, original.Commit, original.BuildTime
+	}()
+
+	server := NewHealthServer(NewMonitor(), "127.0.0.1", "0")
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start health server: %v", err)
+	}
+	defer server.Shutdown(context.Background())
+
+	resp, err := http.Get("http://" + server.Addr() + "/version")
+	if err != nil {
+		t.Fatalf("Failed to query /version: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var info version.Info
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatalf("Failed to decode version JSON: %v", err)
+	}
+	expected := version.Info{Version: "v1.2.3", Commit: "abc1234", BuildTime: "2025-01-02T03:04:05Z"}
+	if info != expected {
+		t.Errorf("Expected %+v, got %+v", expected, info)
+	}
+
+	if status := NewMonitor().GetStatus(); status.Build != expected {
+		t.Errorf("Expected /status build info %+v, got %+v", expected, status.Build)
+	}
+}
+
+func TestTFRsEndpoint(t *testing.T) {
+	monitor := NewMonitor()
+	server := NewHealthServer(monitor, "127.0.0.1", "0")
+	if err := server.Start(); err != nil {
+		t.Fatalf("Failed to start health server: %v", err)
+	}
+	defer server.Shutdown(context.Background())
+
+	resp, err := http.Get("http://" + server.Addr() + "/tfrs")
+	if err != nil {
+		t.Fatalf("Failed to query /tfrs: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 before any TFR check, got %d", resp.StatusCode)
+	}
+
+	monitor.RecordSnapshot(SnapshotTFRs, map[string]interface{}{
+		"has_active_tfrs": true,
+		"active_tfrs":     []map[string]interface{}{{"name": "STADIUM", "type": "SECURITY", "latitude": 40.1, "longitude": -74.1, "radius": 3}},
+	})
+
+	resp, err = http.Get("http://" + server.Addr() + "/tfrs")
+	if err != nil {
+		t.Fatalf("Failed to query /tfrs: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var check struct {
+		HasActiveTFRs bool `json:"has_active_tfrs"`
+		ActiveTFRs    []struct {
+			Name   string  `json:"name"`
+			Radius float64 `json:"radius"`
+		} `json:"active_tfrs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&check); err != nil {
+		t.Fatalf("Failed to decode /tfrs: %v", err)
+	}
+	if !check.HasActiveTFRs || len(check.ActiveTFRs) != 1 || check.ActiveTFRs[0].Name != "STADIUM" || check.ActiveTFRs[0].Radius != 3 {
+		t.Errorf("Expected stored TFR check, got %+v", check)
+	}
+}
+
+// fakeTracker records the IDs edited through /tracker/mark
+type fakeTracker struct {
+	marked map[string]bool
+}
+
+func (f *fakeTracker) MarkAnalyzed(videoID string) error {
+	f.marked[videoID] = true
+	return nil
+}
+
+func (f *fakeTracker) Unmark(videoID string) error {
+	delete(f.marked, videoID)
+	return nil
+}
+
+func TestTrackerMarkEndpoint(t *testing.T) {
+	tracker := &fakeTracker{marked: map[string]bool{"old": true}}
+	server := NewHealthServer(NewMonitor(), "127.0.0.1", "0")
+	server.SetTracker(tracker, "secret")
+	ts := httptest.NewServer(server.server.Handler)
+	defer ts.Close()
+
+	request := func(method, query, token string) int {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+"/tracker/mark"+query, nil)
+		if err != nil {
+			t.Fatalf("Failed to build request: %v", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to query /tracker/mark: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		query    string
+		token    string
+		expected int
+	}{
+		{"Missing token", http.MethodPost, "?id=abc", "", http.StatusUnauthorized},
+		{"Wrong token", http.MethodPost, "?id=abc", "guess", http.StatusUnauthorized},
+		{"Missing id", http.MethodPost, "", "secret", http.StatusBadRequest},
+		{"Unsupported method", http.MethodGet, "?id=abc", "secret", http.StatusMethodNotAllowed},
+		{"Mark", http.MethodPost, "?id=abc", "secret", http.StatusNoContent},
+		{"Unmark", http.MethodDelete, "?id=old", "secret", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if status := request(tt.method, tt.query, tt.token); status != tt.expected {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.expected, status)
+		}
+	}
+
+	if !tracker.marked["abc"] || tracker.marked["old"] || len(tracker.marked) != 1 {
+		t.Errorf("Expected only abc to be marked, got %v", tracker.marked)
+	}
+
+	disabled := httptest.NewServer(NewHealthServer(NewMonitor(), "127.0.0.1", "0").server.Handler)
+	defer disabled.Close()
+	req, _ := http.NewRequest(http.MethodPost, disabled.URL+"/tracker/mark?id=abc", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to query /tracker/mark: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 without a configured tracker and token, got %d", resp.StatusCode)
+	}
+}
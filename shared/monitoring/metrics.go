@@ -0,0 +1,74 @@
+package monitoring
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// MetricsSink receives run telemetry emitted by the Monitor
+type MetricsSink interface {
+	IncrCounter(name string, value int64)
+	Timing(name string, duration time.Duration)
+}
+
+// Metric names emitted by the Monitor
+const (
+	MetricRunSuccess         = "runs.success"
+	MetricRunIdle            = "runs.idle"
+	MetricRunSkipped         = "runs.skipped"
+	MetricRunPartialFailure  = "runs.partial_failure"
+	MetricRunCriticalFailure = "runs.critical_failure"
+	MetricRunDuration        = "runs.duration"
+	MetricEmailSent          = "email.sent"
+	MetricEmailFailed        = "email.failed"
+	MetricEmailLatency       = "email.latency"
+)
+
+// noopSink discards all metrics; used when no sink is configured
+type noopSink struct{}
+
+func (noopSink) IncrCounter(string, int64)    {}
+func (noopSink) Timing(string, time.Duration) {}
+
+// StatsDSink sends metrics to a StatsD server over UDP.
+// Sends are fire-and-forget; delivery errors are ignored so telemetry never affects runs.
+type StatsDSink struct {
+	conn   net.Conn
+	prefix string
+}
+
+// NewStatsDSink creates a sink sending to addr (host:port). A non-empty prefix
+// is prepended to every metric name with a dot separator.
+func NewStatsDSink(addr, prefix string) (*StatsDSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to StatsD at %s: %w", addr, err)
+	}
+	return &StatsDSink{conn: conn, prefix: strings.TrimSuffix(prefix, ".")}, nil
+}
+
+func (s *StatsDSink) IncrCounter(name string, value int64) {
+	s.send(fmt.Sprintf("%s:%d|c", s.metricName(name), value))
+}
+
+func (s *StatsDSink) Timing(name string, duration time.Duration) {
+	s.send(fmt.Sprintf("%s:%d|ms", s.metricName(name), duration.Milliseconds()))
+}
+
+// Close releases the underlying UDP socket
+func (s *StatsDSink) Close() error {
+	return s.conn.Close()
+}
+
+func (s *StatsDSink) metricName(name string) string {
+	if s.prefix == "" {
+		return name
+	}
+	return s.prefix + "." + name
+}
+
+func (s *StatsDSink) send(line string) {
+	_, _ = s.conn.Write([]byte(line))
+}
@@ -1,41 +1,198 @@
 package monitoring
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"os"
+	"sync"
 	"time"
+
+	"agent-stack/shared/storage"
+	"agent-stack/shared/version"
 )
 
 type Monitor struct {
-	lastRunSuccess bool
-	lastRunTime    time.Time
+	lastRunSuccess      bool
+	lastRunIdle         bool // the last successful run found nothing new to do
+	lastRunTime         time.Time
+	lastSuccessTime     time.Time
+	consecutiveFailures int
+	startedAt           time.Time
+	maxStaleness        time.Duration // zero disables the staleness check
+	sink                MetricsSink
+	statePath           string // empty keeps run outcomes in memory only
+
+	// lastCheck holds the most recent agent-specific decision snapshot for debugging
+	lastCheck   interface{}
+	lastCheckMu sync.RWMutex
+
+	// snapshots holds named agent data served on dedicated endpoints, e.g. SnapshotTFRs
+	snapshots   map[string]interface{}
+	snapshotsMu sync.RWMutex
+
+	// lastEmail is the outcome of the most recent SMTP delivery, nil until one is made
+	lastEmail   *EmailStatus
+	lastEmailMu sync.RWMutex
+
+	// lastSkip records the most recent run that did not start, e.g. because another
+	// instance held the run lock
+	lastSkipTime   time.Time
+	lastSkipReason string
+	lastSkipMu     sync.RWMutex
+}
+
+// EmailStatus describes the most recent email delivery attempt
+type EmailStatus struct {
+	Time      time.Time `json:"time"`
+	Success   bool      `json:"success"`
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// SnapshotTFRs names the drone agent's latest TFR check, served at /tfrs
+const SnapshotTFRs = "tfrs"
+
+// Status is the machine-readable health summary served at /status
+type Status struct {
+	Healthy             bool         `json:"healthy"`
+	Summary             string       `json:"summary"`
+	LastRunTime         *time.Time   `json:"last_run_time,omitempty"`
+	LastRunSuccess      bool         `json:"last_run_success"`
+	LastRunIdle         bool         `json:"last_run_idle"`
+	LastSuccessTime     *time.Time   `json:"last_success_time,omitempty"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	StalenessSeconds    int64        `json:"staleness_seconds"` // since last success, or since start if none
+	LastEmail           *EmailStatus `json:"last_email,omitempty"`
+	LastSkipTime        *time.Time   `json:"last_skip_time,omitempty"`
+	LastSkipReason      string       `json:"last_skip_reason,omitempty"`
+	Build               version.Info `json:"build"`
+}
+
+// persistedState is the run outcome saved to disk so health survives restarts
+type persistedState struct {
+	LastRunSuccess      bool      `json:"last_run_success"`
+	LastRunTime         time.Time `json:"last_run_time"`
+	LastSuccessTime     time.Time `json:"last_success_time"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
 }
 
 func NewMonitor() *Monitor {
-	return &Monitor{}
+	return NewMonitorWithState("")
+}
+
+// NewMonitorWithState creates a monitor that persists the last run outcome to
+// statePath and restores it on creation, so /health stays accurate across
+// restarts. An empty statePath keeps state in memory only.
+func NewMonitorWithState(statePath string) *Monitor {
+	m := &Monitor{sink: noopSink{}, startedAt: time.Now(), statePath: statePath}
+	if statePath != "" {
+		if err := m.loadState(); err != nil {
+			log.Printf("Warning: Failed to restore monitor state from %s: %v", statePath, err)
+		}
+	}
+	return m
+}
+
+// SetMaxStaleness makes IsHealthy fail when no run has succeeded within d,
+// regardless of the last run's outcome. Zero disables the check.
+func (m *Monitor) SetMaxStaleness(d time.Duration) {
+	m.maxStaleness = d
+}
+
+// SetMetricsSink routes run telemetry to sink; nil disables emission
+func (m *Monitor) SetMetricsSink(sink MetricsSink) {
+	if sink == nil {
+		sink = noopSink{}
+	}
+	m.sink = sink
 }
 
 func (m *Monitor) RecordSuccess(summary string, duration time.Duration) {
-	m.lastRunSuccess = true
-	m.lastRunTime = time.Now()
+	m.recordHealthyRun(false)
+	m.sink.IncrCounter(MetricRunSuccess, 1)
+	m.sink.Timing(MetricRunDuration, duration)
 
 	log.Printf("✅ Run completed successfully - %s (took %v)", summary, duration)
 }
 
+// RecordIdle records a healthy run that found nothing new to do. It keeps the
+// agent healthy like RecordSuccess but is counted as runs.idle rather than
+// runs.success, so success notifications and dashboards can ignore quiet runs.
+func (m *Monitor) RecordIdle(summary string, duration time.Duration) {
+	m.recordHealthyRun(true)
+	m.sink.IncrCounter(MetricRunIdle, 1)
+	m.sink.Timing(MetricRunDuration, duration)
+
+	log.Printf("💤 Run completed with nothing new - %s (took %v)", summary, duration)
+}
+
+// RecordSkipped records a run that did not start, such as one skipped because
+// another instance held the run lock. Health is unaffected; the skip is shown in
+// /status and counted as runs.skipped.
+func (m *Monitor) RecordSkipped(reason string) {
+	m.lastSkipMu.Lock()
+	m.lastSkipTime = time.Now()
+	m.lastSkipReason = reason
+	m.lastSkipMu.Unlock()
+	m.sink.IncrCounter(MetricRunSkipped, 1)
+
+	log.Printf("⏭️  Run skipped: %s", reason)
+}
+
+func (m *Monitor) recordHealthyRun(idle bool) {
+	m.lastRunSuccess = true
+	m.lastRunIdle = idle
+	m.lastRunTime = time.Now()
+	m.lastSuccessTime = m.lastRunTime
+	m.consecutiveFailures = 0
+	m.saveState()
+}
+
 func (m *Monitor) RecordPartialFailure(err error, duration time.Duration) {
 	// Don't change health status for partial failures
+	m.sink.IncrCounter(MetricRunPartialFailure, 1)
 	log.Printf("⚠️  PARTIAL FAILURE: %s (Duration: %v)", err.Error(), duration)
 }
 
 func (m *Monitor) RecordCriticalFailure(err error, duration time.Duration) {
 	m.lastRunSuccess = false
+	m.lastRunIdle = false
 	m.lastRunTime = time.Now()
+	m.consecutiveFailures++
+	m.saveState()
+	m.sink.IncrCounter(MetricRunCriticalFailure, 1)
+	m.sink.Timing(MetricRunDuration, duration)
 
 	log.Printf("🚨 CRITICAL FAILURE: %s (Duration: %v)", err.Error(), duration)
 	log.Printf("Failure occurred at: %s", time.Now().Format("2006-01-02 15:04:05"))
 }
 
+// RecordEmailSend records the latency and outcome of an SMTP delivery, so slow runs
+// can be attributed to the mail server. Failures are also reported by the agent.
+func (m *Monitor) RecordEmailSend(latency time.Duration, err error) {
+	status := &EmailStatus{Time: time.Now(), Success: err == nil, LatencyMs: latency.Milliseconds()}
+	if err != nil {
+		status.Error = err.Error()
+		m.sink.IncrCounter(MetricEmailFailed, 1)
+	} else {
+		m.sink.IncrCounter(MetricEmailSent, 1)
+	}
+	m.sink.Timing(MetricEmailLatency, latency)
+
+	m.lastEmailMu.Lock()
+	m.lastEmail = status
+	m.lastEmailMu.Unlock()
+
+	log.Printf("Email delivery took %v (success=%t)", latency, err == nil)
+}
+
 func (m *Monitor) IsHealthy() bool {
+	// Catches silently stopped runs (e.g. cron misfires) that never record a failure
+	if m.maxStaleness > 0 && m.Staleness() > m.maxStaleness {
+		return false
+	}
+
 	if m.lastRunTime.IsZero() {
 		return true // No runs yet, assume healthy
 	}
@@ -45,6 +202,13 @@ func (m *Monitor) IsHealthy() bool {
 }
 
 func (m *Monitor) GetStatusSummary() string {
+	m.lastSkipMu.RLock()
+	skipTime, skipReason := m.lastSkipTime, m.lastSkipReason
+	m.lastSkipMu.RUnlock()
+	if !skipTime.IsZero() && skipTime.After(m.lastRunTime) {
+		return fmt.Sprintf("⏭️ Last run skipped (%s): %s", skipReason, skipTime.Format("Jan 2 15:04"))
+	}
+
 	if m.lastRunTime.IsZero() {
 		return "No runs yet"
 	}
@@ -55,3 +219,119 @@ func (m *Monitor) GetStatusSummary() string {
 		return fmt.Sprintf("❌ Last run failed: %s", m.lastRunTime.Format("Jan 2 15:04"))
 	}
 }
+
+// Staleness returns the time since the last successful run, or since the
+// monitor started if no run has succeeded yet
+func (m *Monitor) Staleness() time.Duration {
+	if m.lastSuccessTime.IsZero() {
+		return time.Since(m.startedAt)
+	}
+	return time.Since(m.lastSuccessTime)
+}
+
+// GetStatus returns a machine-readable health summary
+func (m *Monitor) GetStatus() Status {
+	status := Status{
+		Healthy:             m.IsHealthy(),
+		Summary:             m.GetStatusSummary(),
+		LastRunSuccess:      m.lastRunSuccess,
+		LastRunIdle:         m.lastRunIdle,
+		ConsecutiveFailures: m.consecutiveFailures,
+		StalenessSeconds:    int64(m.Staleness().Seconds()),
+		Build:               version.Get(),
+	}
+	if !m.lastRunTime.IsZero() {
+		lastRun := m.lastRunTime
+		status.LastRunTime = &lastRun
+	}
+	if !m.lastSuccessTime.IsZero() {
+		lastSuccess := m.lastSuccessTime
+		status.LastSuccessTime = &lastSuccess
+	}
+	m.lastEmailMu.RLock()
+	if m.lastEmail != nil {
+		lastEmail := *m.lastEmail
+		status.LastEmail = &lastEmail
+	}
+	m.lastEmailMu.RUnlock()
+	m.lastSkipMu.RLock()
+	if !m.lastSkipTime.IsZero() {
+		lastSkip := m.lastSkipTime
+		status.LastSkipTime = &lastSkip
+		status.LastSkipReason = "skipped: " + m.lastSkipReason
+	}
+	m.lastSkipMu.RUnlock()
+	return status
+}
+
+// RecordLastCheck stores a JSON-serializable snapshot of the agent's most recent decision
+func (m *Monitor) RecordLastCheck(snapshot interface{}) {
+	m.lastCheckMu.Lock()
+	defer m.lastCheckMu.Unlock()
+	m.lastCheck = snapshot
+}
+
+// GetLastCheck returns the most recently recorded snapshot, or nil if none
+func (m *Monitor) GetLastCheck() interface{} {
+	m.lastCheckMu.RLock()
+	defer m.lastCheckMu.RUnlock()
+	return m.lastCheck
+}
+
+// loadState restores the last run outcome from statePath; a missing file is not an error
+func (m *Monitor) loadState() error {
+	data, err := os.ReadFile(m.statePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read monitor state: %w", err)
+	}
+
+	var state persistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to decode monitor state: %w", err)
+	}
+
+	m.lastRunSuccess = state.LastRunSuccess
+	m.lastRunTime = state.LastRunTime
+	m.lastSuccessTime = state.LastSuccessTime
+	m.consecutiveFailures = state.ConsecutiveFailures
+	return nil
+}
+
+// saveState atomically writes the last run outcome to statePath, so a crash mid-write
+// can't leave a truncated file behind. Failures are logged, not returned, since
+// losing health state must never fail a run.
+func (m *Monitor) saveState() {
+	if m.statePath == "" {
+		return
+	}
+
+	err := storage.WriteJSONFile(m.statePath, persistedState{
+		LastRunSuccess:      m.lastRunSuccess,
+		LastRunTime:         m.lastRunTime,
+		LastSuccessTime:     m.lastSuccessTime,
+		ConsecutiveFailures: m.consecutiveFailures,
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to persist monitor state: %v", err)
+	}
+}
+
+// RecordSnapshot stores named JSON-serializable agent data, replacing any previous value
+func (m *Monitor) RecordSnapshot(name string, snapshot interface{}) {
+	m.snapshotsMu.Lock()
+	defer m.snapshotsMu.Unlock()
+	if m.snapshots == nil {
+		m.snapshots = make(map[string]interface{})
+	}
+	m.snapshots[name] = snapshot
+}
+
+// GetSnapshot returns the most recent snapshot recorded under name, or nil if none
+func (m *Monitor) GetSnapshot(name string) interface{} {
+	m.snapshotsMu.RLock()
+	defer m.snapshotsMu.RUnlock()
+	return m.snapshots[name]
+}
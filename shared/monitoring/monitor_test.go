@@ -0,0 +1,245 @@
+package monitoring
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeSink struct {
+	counters map[string]int64
+	timings  map[string][]time.Duration
+}
+
+func newFakeSink() *fakeSink {
+	return &fakeSink{counters: map[string]int64{}, timings: map[string][]time.Duration{}}
+}
+
+func (f *fakeSink) IncrCounter(name string, value int64) {
+	f.counters[name] += value
+}
+
+func (f *fakeSink) Timing(name string, duration time.Duration) {
+	f.timings[name] = append(f.timings[name], duration)
+}
+
+func TestMonitorEmitsMetrics(t *testing.T) {
+	sink := newFakeSink()
+	monitor := NewMonitor()
+	monitor.SetMetricsSink(sink)
+
+	monitor.RecordSuccess("ok", 2*time.Second)
+	monitor.RecordPartialFailure(errors.New("minor"), 3*time.Second)
+	monitor.RecordCriticalFailure(errors.New("major"), 4*time.Second)
+
+	expectedCounters := map[string]int64{
+		MetricRunSuccess:         1,
+		MetricRunPartialFailure:  1,
+		MetricRunCriticalFailure: 1,
+	}
+	if !reflect.DeepEqual(sink.counters, expectedCounters) {
+		t.Errorf("Counters = %v, want %v", sink.counters, expectedCounters)
+	}
+
+	expectedTimings := map[string][]time.Duration{
+		MetricRunDuration: {2 * time.Second, 4 * time.Second},
+	}
+	if !reflect.DeepEqual(sink.timings, expectedTimings) {
+		t.Errorf("Timings = %v, want %v", sink.timings, expectedTimings)
+	}
+}
+
+func TestMonitorWithoutSink(t *testing.T) {
+	// Unconfigured monitors must not panic when recording
+	monitor := NewMonitor()
+	monitor.RecordSuccess("ok", time.Second)
+	monitor.SetMetricsSink(nil)
+	monitor.RecordCriticalFailure(errors.New("boom"), time.Second)
+}
+
+func TestRecordIdleIsHealthyButNotASuccess(t *testing.T) {
+	sink := newFakeSink()
+	monitor := NewMonitor()
+	monitor.SetMetricsSink(sink)
+
+	monitor.RecordIdle("nothing new", time.Second)
+
+	if sink.counters[MetricRunIdle] != 1 || sink.counters[MetricRunSuccess] != 0 {
+		t.Errorf("Expected an idle run counted apart from successes, got %v", sink.counters)
+	}
+	status := monitor.GetStatus()
+	if !status.Healthy || !status.LastRunSuccess || !status.LastRunIdle || status.LastSuccessTime == nil {
+		t.Errorf("Expected a healthy idle run, got %+v", status)
+	}
+
+	monitor.RecordSuccess("ok", time.Second)
+	if monitor.GetStatus().LastRunIdle {
+		t.Error("Expected a real success to clear the idle flag")
+	}
+}
+
+func TestStatsDSinkFormat(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	sink, err := NewStatsDSink(conn.LocalAddr().String(), "agents")
+	if err != nil {
+		t.Fatalf("NewStatsDSink() error: %v", err)
+	}
+	defer sink.Close()
+
+	sink.IncrCounter(MetricRunSuccess, 1)
+	sink.Timing(MetricRunDuration, 1500*time.Millisecond)
+
+	var received []string
+	buf := make([]byte, 512)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for len(received) < 2 {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Failed to read metric: %v", err)
+		}
+		received = append(received, string(buf[:n]))
+	}
+
+	expected := "agents.runs.success:1|c\nagents.runs.duration:1500|ms"
+	if got := strings.Join(received, "\n"); got != expected {
+		t.Errorf("Received %q, want %q", got, expected)
+	}
+}
+
+func TestStaleButNotFailedIsUnhealthy(t *testing.T) {
+	m := NewMonitor()
+	m.SetMaxStaleness(time.Hour)
+	m.RecordSuccess("ok", time.Second)
+
+	if !m.IsHealthy() {
+		t.Fatal("Expected healthy right after a successful run")
+	}
+
+	// Last run succeeded, but nothing has succeeded for longer than the window
+	m.lastSuccessTime = time.Now().Add(-2 * time.Hour)
+	m.lastRunTime = m.lastSuccessTime
+	if m.IsHealthy() {
+		t.Error("Expected unhealthy when the last success is older than max staleness")
+	}
+
+	status := m.GetStatus()
+	if !status.LastRunSuccess {
+		t.Error("Expected last run to still be reported as successful")
+	}
+	if status.StalenessSeconds < 7200 {
+		t.Errorf("Expected staleness of at least 7200s, got %d", status.StalenessSeconds)
+	}
+}
+
+func TestStalenessWithoutAnySuccess(t *testing.T) {
+	m := NewMonitor()
+	m.SetMaxStaleness(time.Hour)
+
+	if !m.IsHealthy() {
+		t.Fatal("Expected healthy within the window after start")
+	}
+
+	m.startedAt = time.Now().Add(-2 * time.Hour)
+	if m.IsHealthy() {
+		t.Error("Expected unhealthy when no run succeeded within the window since start")
+	}
+}
+
+func TestStalenessDisabledByDefault(t *testing.T) {
+	m := NewMonitor()
+	m.RecordSuccess("ok", time.Second)
+	m.lastSuccessTime = time.Now().Add(-30 * 24 * time.Hour)
+
+	if !m.IsHealthy() {
+		t.Error("Staleness should not affect health when max staleness is unset")
+	}
+}
+
+func TestMonitorRestoresPersistedFailure(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "monitor_state.json")
+
+	monitor := NewMonitorWithState(statePath)
+	monitor.RecordSuccess("ok", time.Second)
+	monitor.RecordCriticalFailure(errors.New("boom"), time.Second)
+	monitor.RecordCriticalFailure(errors.New("boom again"), time.Second)
+
+	restored := NewMonitorWithState(statePath)
+	if restored.IsHealthy() {
+		t.Error("Expected restored monitor to be unhealthy after a persisted failure")
+	}
+
+	status := restored.GetStatus()
+	if status.ConsecutiveFailures != 2 {
+		t.Errorf("Expected 2 consecutive failures, got %d", status.ConsecutiveFailures)
+	}
+	if status.LastRunTime == nil || status.LastSuccessTime == nil {
+		t.Fatalf("Expected run and success times to be restored, got %+v", status)
+	}
+
+	restored.RecordSuccess("ok", time.Second)
+	if reloaded := NewMonitorWithState(statePath); !reloaded.IsHealthy() || reloaded.GetStatus().ConsecutiveFailures != 0 {
+		t.Error("Expected a success to reset the persisted failure state")
+	}
+	if entries, err := os.ReadDir(filepath.Dir(statePath)); err != nil || len(entries) != 1 {
+		t.Errorf("Expected only the state file after atomic writes, got %d entries (err: %v)", len(entries), err)
+	}
+}
+
+func TestRecordEmailSend(t *testing.T) {
+	sink := newFakeSink()
+	monitor := NewMonitor()
+	monitor.SetMetricsSink(sink)
+
+	if status := monitor.GetStatus(); status.LastEmail != nil {
+		t.Errorf("Expected no email status before any send, got %+v", status.LastEmail)
+	}
+
+	monitor.RecordEmailSend(1200*time.Millisecond, nil)
+	monitor.RecordEmailSend(300*time.Millisecond, errors.New("SMTP DATA failed"))
+
+	expectedCounters := map[string]int64{MetricEmailSent: 1, MetricEmailFailed: 1}
+	if !reflect.DeepEqual(sink.counters, expectedCounters) {
+		t.Errorf("Counters = %v, want %v", sink.counters, expectedCounters)
+	}
+	expectedTimings := map[string][]time.Duration{MetricEmailLatency: {1200 * time.Millisecond, 300 * time.Millisecond}}
+	if !reflect.DeepEqual(sink.timings, expectedTimings) {
+		t.Errorf("Timings = %v, want %v", sink.timings, expectedTimings)
+	}
+
+	last := monitor.GetStatus().LastEmail
+	if last == nil || last.Success || last.LatencyMs != 300 || last.Error != "SMTP DATA failed" {
+		t.Errorf("Expected the failed send as last email status, got %+v", last)
+	}
+}
+
+func TestRecordSkippedKeepsHealthAndShowsInStatus(t *testing.T) {
+	monitor := NewMonitor()
+	monitor.RecordSuccess("ok", time.Second)
+	monitor.RecordSkipped("lock held by other-host:42")
+
+	if !monitor.IsHealthy() {
+		t.Error("A skipped run should not affect health")
+	}
+	status := monitor.GetStatus()
+	if status.LastSkipTime == nil || status.LastSkipReason != "skipped: lock held by other-host:42" {
+		t.Errorf("Expected the skip in status, got %+v", status)
+	}
+	if !strings.Contains(monitor.GetStatusSummary(), "skipped") {
+		t.Errorf("Expected summary to mention the skipped run, got %q", monitor.GetStatusSummary())
+	}
+
+	monitor.RecordSuccess("ok", time.Second)
+	if strings.Contains(monitor.GetStatusSummary(), "skipped") {
+		t.Errorf("Expected a later run to replace the skip in the summary, got %q", monitor.GetStatusSummary())
+	}
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"path/filepath"
+	"strings"
 	"time"
+	"unicode"
 
 	"agent-stack/shared/config"
 	"agent-stack/shared/monitoring"
+	"agent-stack/shared/storage"
 
 	"github.com/robfig/cron/v3"
 )
@@ -23,6 +27,16 @@ type AgentEvents struct {
 	OnSuccess         func(metrics Metrics, duration time.Duration)
 	OnPartialFailure  func(err error, duration time.Duration)
 	OnCriticalFailure func(err error, duration time.Duration)
+	// OnIdle reports a successful run that found nothing new to do, in place of
+	// OnSuccess, so downstream notifications can ignore it
+	OnIdle func(metrics Metrics, duration time.Duration)
+	// OnSkipped reports a run that did not start, e.g. "lock held by host:123"
+	OnSkipped func(reason string)
+	// OnLastCheck records a JSON-serializable snapshot of the agent's latest decision
+	OnLastCheck func(snapshot interface{})
+	// OnSnapshot records named JSON-serializable data served on its own health
+	// endpoint, e.g. monitoring.SnapshotTFRs at /tfrs
+	OnSnapshot func(name string, snapshot interface{})
 }
 
 // Agent defines the interface that all agents must implement
@@ -33,6 +47,20 @@ type Agent interface {
 	GetSchedule() string
 }
 
+// EmailObserver is implemented by agents that send email over SMTP, letting the
+// scheduler report delivery latency and outcomes to the monitor. ObserveEmail is
+// called once by New, before Initialize and any run, so agents hand the observer to
+// the sender they create without synchronization.
+type EmailObserver interface {
+	ObserveEmail(observer func(latency time.Duration, err error))
+}
+
+// TrackerProvider is implemented by agents with an analyzed-video tracker, letting
+// the health server's /tracker/mark endpoint edit it
+type TrackerProvider interface {
+	VideoTracker() storage.Tracker
+}
+
 // Scheduler manages the execution of agents on a schedule
 type Scheduler struct {
 	config  *config.Config
@@ -42,15 +70,59 @@ type Scheduler struct {
 }
 
 func New(cfg *config.Config, agent Agent) *Scheduler {
-	m := monitoring.NewMonitor()
+	// Persist run outcomes so health stays accurate across restarts. Agents may share
+	// a data dir (docker-compose mounts one volume into both), so each gets its own file.
+	var statePath string
+	if cfg.DataDir != "" {
+		statePath = filepath.Join(cfg.DataDir, "monitor_state_"+stateFileName(agent.Name())+".json")
+	}
+	m := monitoring.NewMonitorWithState(statePath)
+	if cfg.Monitoring.MaxStalenessSeconds > 0 {
+		m.SetMaxStaleness(time.Duration(cfg.Monitoring.MaxStalenessSeconds) * time.Second)
+	}
+	if cfg.Monitoring.StatsDAddr != "" {
+		sink, err := monitoring.NewStatsDSink(cfg.Monitoring.StatsDAddr, cfg.Monitoring.StatsDPrefix)
+		if err != nil {
+			log.Printf("Warning: Metrics disabled: %v", err)
+		} else {
+			m.SetMetricsSink(sink)
+			log.Printf("Emitting run metrics to StatsD at %s", cfg.Monitoring.StatsDAddr)
+		}
+	}
+
+	// Prevent overlapping runs
+	options := []cron.Option{cron.WithSeconds(), cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger))}
+	if cfg.Timezone != "" {
+		options = append(options, cron.WithLocation(cfg.Location()))
+	}
+
+	if observed, ok := agent.(EmailObserver); ok {
+		observed.ObserveEmail(m.RecordEmailSend)
+	}
 
 	return &Scheduler{
 		config:  cfg,
 		monitor: m,
 		agent:   agent,
-		// Prevent overlapping runs
-		cron: cron.New(cron.WithSeconds(), cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger))),
+		cron:    cron.New(options...),
+	}
+}
+
+// stateFileName turns an agent name into a file name part, e.g. "YouTube Curator"
+// becomes "youtube_curator"
+func stateFileName(agentName string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(agentName) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore && b.Len() > 0 {
+			b.WriteByte('_')
+			underscore = true
+		}
 	}
+	return strings.TrimSuffix(b.String(), "_")
 }
 
 func (s *Scheduler) Start(ctx context.Context) error {
@@ -58,12 +130,23 @@ func (s *Scheduler) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to initialize agent: %w", err)
 	}
 
-	// Start health check server (configurable via config, defaults to 8080)
-	healthServer := monitoring.NewHealthServer(s.monitor, fmt.Sprintf("%d", s.config.Monitoring.HealthPort))
-	healthServer.Start()
+	healthServer, err := s.StartHealthServer()
+	if err != nil {
+		return err
+	}
+	defer shutdownHealthServer(healthServer)
 
 	schedule := s.agent.GetSchedule()
-	_, err := s.cron.AddFunc(schedule, func() {
+	var watchdog *scheduleWatchdog
+	if tolerance := s.config.Monitoring.ScheduleSkewSeconds; tolerance > 0 {
+		watchdog, err = newScheduleWatchdog(schedule, time.Duration(tolerance)*time.Second, s.cron.Location())
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = s.cron.AddFunc(schedule, func() {
+		s.checkScheduleSkew(watchdog, time.Now())
 		if err := s.RunOnce(ctx); err != nil {
 			log.Printf("Error running scheduled job for %s: %v", s.agent.Name(), err)
 		}
@@ -82,12 +165,66 @@ func (s *Scheduler) Start(ctx context.Context) error {
 	return ctx.Err()
 }
 
+// StartHealthServer starts the health check server for this scheduler's monitor
+// (configurable via config, defaults to 8080)
+func (s *Scheduler) StartHealthServer() (*monitoring.HealthServer, error) {
+	healthServer := monitoring.NewHealthServer(s.monitor, s.config.Monitoring.HealthBindAddr, fmt.Sprintf("%d", s.config.Monitoring.HealthPort))
+	// Agents open their tracker during Initialize, which runs before this
+	if provider, ok := s.agent.(TrackerProvider); ok && s.config.Monitoring.AdminToken != "" {
+		if tracker := provider.VideoTracker(); tracker != nil {
+			healthServer.SetTracker(tracker, s.config.Monitoring.AdminToken)
+		}
+	}
+	if err := healthServer.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start health server: %w", err)
+	}
+	return healthServer, nil
+}
+
+// ServeHealth serves the health endpoints until ctx is cancelled without
+// scheduling any runs. Used after a single run so probes can see its outcome.
+func (s *Scheduler) ServeHealth(ctx context.Context) error {
+	healthServer, err := s.StartHealthServer()
+	if err != nil {
+		return err
+	}
+	defer shutdownHealthServer(healthServer)
+
+	log.Printf("Serving health status for %s until stopped", s.agent.Name())
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func shutdownHealthServer(healthServer *monitoring.HealthServer) {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := healthServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Health server shutdown error: %v", err)
+	}
+}
+
+// checkScheduleSkew records a partial failure when a scheduled invocation arrives
+// far from its expected time, surfacing missed runs that would otherwise be silent
+func (s *Scheduler) checkScheduleSkew(watchdog *scheduleWatchdog, now time.Time) {
+	if watchdog == nil {
+		return
+	}
+	if err := watchdog.observe(now); err != nil {
+		log.Printf("Warning: Schedule skew detected for %s: %v", s.agent.Name(), err)
+		s.monitor.RecordPartialFailure(fmt.Errorf("%s schedule skew: %w", s.agent.Name(), err), 0)
+	}
+}
+
 func (s *Scheduler) RunOnce(ctx context.Context) error {
 	startTime := time.Now()
 	agentName := s.agent.Name()
 
 	log.Printf("Starting %s run...", agentName)
 
+	// Agents usually signal OnCriticalFailure and then return the same error;
+	// track it so the failure is only recorded once
+	criticalReported := false
+
 	// Create event handlers for monitoring
 	events := &AgentEvents{
 		OnSuccess: func(metrics Metrics, duration time.Duration) {
@@ -97,13 +234,22 @@ func (s *Scheduler) RunOnce(ctx context.Context) error {
 			s.monitor.RecordPartialFailure(fmt.Errorf("%s partial failure: %w", agentName, err), duration)
 		},
 		OnCriticalFailure: func(err error, duration time.Duration) {
+			criticalReported = true
 			s.monitor.RecordCriticalFailure(fmt.Errorf("%s critical failure: %w", agentName, err), duration)
 		},
+		OnIdle: func(metrics Metrics, duration time.Duration) {
+			s.monitor.RecordIdle(metrics.GetSummary(), duration)
+		},
+		OnSkipped:   s.monitor.RecordSkipped,
+		OnLastCheck: s.monitor.RecordLastCheck,
+		OnSnapshot:  s.monitor.RecordSnapshot,
 	}
 
 	if err := s.agent.RunOnce(ctx, events); err != nil {
-		duration := time.Since(startTime)
-		s.monitor.RecordCriticalFailure(fmt.Errorf("%s failed: %w", agentName, err), duration)
+		if !criticalReported {
+			duration := time.Since(startTime)
+			s.monitor.RecordCriticalFailure(fmt.Errorf("%s failed: %w", agentName, err), duration)
+		}
 		return fmt.Errorf("%s run failed: %w", agentName, err)
 	}
 
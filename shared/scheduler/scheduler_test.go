@@ -0,0 +1,280 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"agent-stack/shared/config"
+	"agent-stack/shared/monitoring"
+)
+
+// fakeAgent returns err from RunOnce, optionally signaling it as a critical failure first
+type fakeAgent struct {
+	name           string // defaults to "Fake Agent"
+	err            error
+	signalCritical bool
+}
+
+func (a *fakeAgent) Name() string {
+	if a.name != "" {
+		return a.name
+	}
+	return "Fake Agent"
+}
+
+func (a *fakeAgent) Initialize() error   { return nil }
+func (a *fakeAgent) GetSchedule() string { return "0 0 9 * * *" }
+
+func (a *fakeAgent) RunOnce(ctx context.Context, events *AgentEvents) error {
+	if a.err != nil && a.signalCritical && events != nil && events.OnCriticalFailure != nil {
+		events.OnCriticalFailure(a.err, time.Millisecond)
+	}
+	return a.err
+}
+
+// emailingAgent reports a fixed SMTP latency through its email observer on each run
+type emailingAgent struct {
+	fakeAgent
+	observer     func(latency time.Duration, err error)
+	observeCalls int
+}
+
+func (a *emailingAgent) ObserveEmail(observer func(latency time.Duration, err error)) {
+	a.observer = observer
+	a.observeCalls++
+}
+
+func (a *emailingAgent) RunOnce(ctx context.Context, events *AgentEvents) error {
+	if a.observer != nil {
+		a.observer(250*time.Millisecond, nil)
+	}
+	return nil
+}
+
+// countingSink records how many times each counter was incremented
+type countingSink struct {
+	mu       sync.Mutex
+	counters map[string]int64
+}
+
+func (s *countingSink) IncrCounter(name string, value int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counters[name] += value
+}
+
+func (s *countingSink) Timing(string, time.Duration) {}
+
+func TestRunOnceRecordsCriticalFailureOnce(t *testing.T) {
+	tests := []struct {
+		name           string
+		agent          *fakeAgent
+		expectFailures int64
+	}{
+		{"Returned and already signaled", &fakeAgent{err: errors.New("boom"), signalCritical: true}, 1},
+		{"Returned without signaling", &fakeAgent{err: errors.New("boom")}, 1},
+		{"Successful run", &fakeAgent{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(&config.Config{}, tt.agent)
+			sink := &countingSink{counters: make(map[string]int64)}
+			s.monitor.SetMetricsSink(sink)
+
+			err := s.RunOnce(context.Background())
+			if (err != nil) != (tt.agent.err != nil) {
+				t.Errorf("Expected RunOnce error %v, got %v", tt.agent.err, err)
+			}
+
+			if got := sink.counters[monitoring.MetricRunCriticalFailure]; got != tt.expectFailures {
+				t.Errorf("Expected %d critical failures recorded, got %d", tt.expectFailures, got)
+			}
+		})
+	}
+}
+
+func TestRunOnceWiresEmailObserver(t *testing.T) {
+	agent := &emailingAgent{}
+	s := New(&config.Config{}, agent)
+	sink := &countingSink{counters: make(map[string]int64)}
+	s.monitor.SetMetricsSink(sink)
+
+	for i := 0; i < 2; i++ {
+		if err := s.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce() error: %v", err)
+		}
+	}
+
+	// Set once up front rather than on every run, which raced with in-flight sends
+	if agent.observeCalls != 1 {
+		t.Errorf("Expected the observer to be set once, got %d calls", agent.observeCalls)
+	}
+	if got := sink.counters[monitoring.MetricEmailSent]; got != 2 {
+		t.Errorf("Expected 2 email sends recorded, got %d", got)
+	}
+	if last := s.monitor.GetStatus().LastEmail; last == nil || last.LatencyMs != 250 {
+		t.Errorf("Expected email latency in status, got %+v", last)
+	}
+}
+
+func TestSchedulersSharingDataDirKeepSeparateState(t *testing.T) {
+	cfg := &config.Config{DataDir: t.TempDir()}
+	curator := &fakeAgent{name: "YouTube Curator"}
+	drone := &fakeAgent{name: "Drone Weather", err: errors.New("boom"), signalCritical: true}
+
+	_ = New(cfg, curator).RunOnce(context.Background())
+	_ = New(cfg, drone).RunOnce(context.Background())
+
+	// Restarted schedulers restore their own agent's last run, not the other's
+	if !New(cfg, curator).monitor.IsHealthy() {
+		t.Error("Expected the curator's successful run to survive the drone agent's failure")
+	}
+	if New(cfg, drone).monitor.IsHealthy() {
+		t.Error("Expected the drone agent's failed run to be restored")
+	}
+}
+
+func TestStateFileName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"YouTube Curator": "youtube_curator",
+		"Drone Weather":   "drone_weather",
+		" Odd--Name! ":    "odd_name",
+	} {
+		if got := stateFileName(name); got != expected {
+			t.Errorf("stateFileName(%q) = %q, want %q", name, got, expected)
+		}
+	}
+}
+
+func TestHealthServerReflectsRunOnceOutcome(t *testing.T) {
+	tests := []struct {
+		name         string
+		agent        *fakeAgent
+		expectStatus int
+	}{
+		{"Successful run", &fakeAgent{}, http.StatusOK},
+		{"Failed run", &fakeAgent{err: errors.New("boom"), signalCritical: true}, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Monitoring: config.MonitoringConfig{HealthBindAddr: "127.0.0.1"}}
+			s := New(cfg, tt.agent)
+			_ = s.RunOnce(context.Background())
+
+			healthServer, err := s.StartHealthServer()
+			if err != nil {
+				t.Fatalf("StartHealthServer() error: %v", err)
+			}
+			defer shutdownHealthServer(healthServer)
+
+			resp, err := http.Get("http://" + healthServer.Addr() + "/health")
+			if err != nil {
+				t.Fatalf("Failed to query /health: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.expectStatus {
+				t.Errorf("Expected %d after run, got %d", tt.expectStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestServeHealthStopsOnCancel(t *testing.T) {
+	cfg := &config.Config{Monitoring: config.MonitoringConfig{HealthBindAddr: "127.0.0.1"}}
+	s := New(cfg, &fakeAgent{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.ServeHealth(ctx) }()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeHealth did not return after cancellation")
+	}
+}
+
+func TestScheduleWatchdogFlagsMissedTick(t *testing.T) {
+	watchdog, err := newScheduleWatchdog("0 0 9 * * *", time.Minute, time.Local)
+	if err != nil {
+		t.Fatalf("newScheduleWatchdog() error: %v", err)
+	}
+
+	day := func(d int, offset time.Duration) time.Time {
+		return time.Date(2025, 6, d, 9, 0, 0, 0, time.Local).Add(offset)
+	}
+
+	tests := []struct {
+		name      string
+		at        time.Time
+		expectErr bool
+	}{
+		{"First invocation has nothing to compare", day(1, 0), false},
+		{"On time", day(2, 2*time.Millisecond), false},
+		{"Within tolerance", day(3, 30*time.Second), false},
+		{"Missed a day", day(5, 0), true},
+		{"Back on schedule", day(6, 0), false},
+	}
+
+	for _, tt := range tests {
+		if err := watchdog.observe(tt.at); (err != nil) != tt.expectErr {
+			t.Errorf("%s: expected error=%v, got %v", tt.name, tt.expectErr, err)
+		}
+	}
+}
+
+func TestScheduleWatchdogUsesConfiguredTimezone(t *testing.T) {
+	cfg := &config.Config{Timezone: "America/New_York"}
+	s := New(cfg, &fakeAgent{})
+	if s.cron.Location().String() != "America/New_York" {
+		t.Fatalf("Expected cron to run in America/New_York, got %s", s.cron.Location())
+	}
+
+	watchdog, err := newScheduleWatchdog("0 0 9 * * *", time.Minute, s.cron.Location())
+	if err != nil {
+		t.Fatalf("newScheduleWatchdog() error: %v", err)
+	}
+
+	// 09:00 in New York is 13:00 UTC in summer; ticks observed from a UTC clock are on time
+	first := time.Date(2025, 6, 1, 13, 0, 0, 0, time.UTC)
+	if err := watchdog.observe(first); err != nil {
+		t.Fatalf("First observation error: %v", err)
+	}
+	if err := watchdog.observe(first.Add(24 * time.Hour)); err != nil {
+		t.Errorf("Expected a daily 09:00 New York tick to be on schedule, got %v", err)
+	}
+	if err := watchdog.observe(first.Add(72 * time.Hour)); err == nil {
+		t.Error("Expected a missed day to still be flagged")
+	}
+}
+
+func TestScheduleSkewRecordsPartialFailure(t *testing.T) {
+	s := New(&config.Config{}, &fakeAgent{})
+	sink := &countingSink{counters: make(map[string]int64)}
+	s.monitor.SetMetricsSink(sink)
+
+	watchdog, err := newScheduleWatchdog(s.agent.GetSchedule(), time.Minute, s.cron.Location())
+	if err != nil {
+		t.Fatalf("newScheduleWatchdog() error: %v", err)
+	}
+	start := time.Date(2025, 6, 1, 9, 0, 0, 0, time.Local)
+	s.checkScheduleSkew(watchdog, start)
+	s.checkScheduleSkew(watchdog, start.Add(72*time.Hour))
+
+	if got := sink.counters[monitoring.MetricRunPartialFailure]; got != 1 {
+		t.Errorf("Expected 1 partial failure for the missed ticks, got %d", got)
+	}
+	if !s.monitor.IsHealthy() {
+		t.Error("Schedule skew should not mark the agent unhealthy")
+	}
+}
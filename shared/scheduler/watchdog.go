@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// scheduleParser matches the seconds-first format the scheduler's cron uses
+var scheduleParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// scheduleWatchdog flags scheduled invocations that arrive far from when the cron
+// expression said they should, e.g. after host clock drift, a suspended
+// container or a run skipped because the previous one was still going
+type scheduleWatchdog struct {
+	schedule  cron.Schedule
+	tolerance time.Duration
+	location  *time.Location
+	last      time.Time
+}
+
+// newScheduleWatchdog parses spec the way the scheduler's cron does. location must
+// match the cron's, since daily schedules land at different instants in each zone.
+func newScheduleWatchdog(spec string, tolerance time.Duration, location *time.Location) (*scheduleWatchdog, error) {
+	schedule, err := scheduleParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schedule %q: %w", spec, err)
+	}
+	return &scheduleWatchdog{schedule: schedule, tolerance: tolerance, location: location}, nil
+}
+
+// observe records an invocation at now and returns an error when it deviates
+// from the time expected after the previous invocation by more than the tolerance
+func (w *scheduleWatchdog) observe(now time.Time) error {
+	now = now.In(w.location)
+	last := w.last
+	w.last = now
+	if last.IsZero() {
+		return nil
+	}
+
+	expected := w.schedule.Next(last)
+	skew := now.Sub(expected)
+	if skew > w.tolerance || skew < -w.tolerance {
+		return fmt.Errorf("scheduled run at %s was expected around %s (off by %v, previous run %s); runs may have been missed",
+			now.Format(time.RFC3339), expected.Format(time.RFC3339), skew.Round(time.Second), last.Format(time.RFC3339))
+	}
+	return nil
+}
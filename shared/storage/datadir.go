@@ -0,0 +1,21 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+)
+
+// EnsureWritableDir creates dir if needed and verifies files can be written to it
+func EnsureWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory %s: %w", dir, err)
+	}
+
+	probe, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("data directory %s is not writable: %w", dir, err)
+	}
+	name := probe.Name()
+	probe.Close()
+	return os.Remove(name)
+}
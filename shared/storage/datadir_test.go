@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureWritableDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested", "data")
+	if err := EnsureWritableDir(dir); err != nil {
+		t.Fatalf("EnsureWritableDir() error: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Expected directory to be created: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected write probe to be cleaned up, found %d entries", len(entries))
+	}
+}
+
+func TestEnsureWritableDirRejectsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	if err := EnsureWritableDir(file); err == nil {
+		t.Error("Expected error when data dir is a regular file")
+	}
+}
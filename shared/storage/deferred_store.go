@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DeferredStore persists the IDs of videos that could not be analyzed yet, such as
+// live streams and upcoming premieres, so they are re-checked on later runs even
+// after they drop out of the subscription crawl window
+type DeferredStore struct {
+	filePath    string
+	deferredIDs map[string]time.Time // video ID -> first deferred at
+	mu          sync.Mutex
+	maxAge      time.Duration
+}
+
+// DeferredVideo represents a video waiting to become analyzable
+type DeferredVideo struct {
+	VideoID    string    `json:"video_id"`
+	DeferredAt time.Time `json:"deferred_at"`
+}
+
+// NewDeferredStore creates a deferred-video store backed by a JSON file in dataDir.
+// Videos deferred for longer than maxAge (e.g. deleted streams) are forgotten.
+func NewDeferredStore(dataDir string, maxAge time.Duration) (*DeferredStore, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	store := &DeferredStore{
+		filePath:    filepath.Join(dataDir, "deferred_videos.json"),
+		deferredIDs: make(map[string]time.Time),
+		maxAge:      maxAge,
+	}
+
+	var deferredVideos []DeferredVideo
+	if err := readJSONFile(store.filePath, &deferredVideos); err != nil {
+		return nil, fmt.Errorf("failed to load deferred video data: %w", err)
+	}
+	for _, dv := range deferredVideos {
+		store.deferredIDs[dv.VideoID] = dv.DeferredAt
+	}
+
+	return store, nil
+}
+
+// IDs returns the deferred video IDs still within maxAge, sorted
+func (ds *DeferredStore) IDs() []string {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	cutoff := time.Now().Add(-ds.maxAge)
+	var ids []string
+	for videoID, deferredAt := range ds.deferredIDs {
+		if deferredAt.After(cutoff) {
+			ids = append(ids, videoID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Update records newly deferred video IDs, keeping their original deferral time if
+// already known, and forgets resolved ones that no longer need re-checking
+func (ds *DeferredStore) Update(deferred, resolved []string) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	now := time.Now()
+	for _, videoID := range deferred {
+		if _, ok := ds.deferredIDs[videoID]; !ok {
+			ds.deferredIDs[videoID] = now
+		}
+	}
+	for _, videoID := range resolved {
+		delete(ds.deferredIDs, videoID)
+	}
+
+	cutoff := now.Add(-ds.maxAge)
+	deferredVideos := make([]DeferredVideo, 0, len(ds.deferredIDs))
+	for videoID, deferredAt := range ds.deferredIDs {
+		if deferredAt.Before(cutoff) {
+			delete(ds.deferredIDs, videoID)
+			continue
+		}
+		deferredVideos = append(deferredVideos, DeferredVideo{VideoID: videoID, DeferredAt: deferredAt})
+	}
+	return WriteJSONFile(ds.filePath, deferredVideos)
+}
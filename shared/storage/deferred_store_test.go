@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeferredStorePersistsUntilResolved(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := NewDeferredStore(dataDir, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create deferred store: %v", err)
+	}
+	if err := store.Update([]string{"stream", "premiere"}, nil); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	reloaded, err := NewDeferredStore(dataDir, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to reload deferred store: %v", err)
+	}
+	if ids := strings.Join(reloaded.IDs(), ","); ids != "premiere,stream" {
+		t.Errorf("Expected deferred IDs to survive a restart, got %s", ids)
+	}
+
+	if err := reloaded.Update(nil, []string{"stream", "unrelated"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if ids := strings.Join(reloaded.IDs(), ","); ids != "premiere" {
+		t.Errorf("Expected resolved video to be forgotten, got %s", ids)
+	}
+}
+
+func TestDeferredStoreForgetsExpiredEntries(t *testing.T) {
+	store, err := NewDeferredStore(t.TempDir(), time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to create deferred store: %v", err)
+	}
+	if err := store.Update([]string{"deleted-stream"}, nil); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if ids := store.IDs(); len(ids) != 0 {
+		t.Errorf("Expected entries older than maxAge to be forgotten, got %v", ids)
+	}
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"agent-stack/internal/models"
+)
+
+// DigestStore persists relevant analyses between runs until an email containing
+// them is delivered, either a weekly digest or a retry after a failed send
+type DigestStore struct {
+	filePath string
+	pending  pendingDigest
+	mu       sync.Mutex
+}
+
+// pendingDigest is the on-disk representation of the accumulated digest
+type pendingDigest struct {
+	Analyses []*models.Analysis `json:"analyses"`
+	Mentions []*models.Analysis `json:"mentions,omitempty"` // honorable mentions below the relevance threshold
+	Analyzed int                `json:"analyzed"`           // total videos analyzed since the last digest
+	// LastSent is when the last digest went out, or was due with nothing to send
+	LastSent time.Time `json:"last_sent,omitempty"`
+}
+
+// NewDigestStore creates a digest store backed by a JSON file in dataDir
+func NewDigestStore(dataDir string) (*DigestStore, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	store := &DigestStore{
+		filePath: filepath.Join(dataDir, "pending_digest.json"),
+	}
+
+	if err := store.load(); err != nil {
+		return nil, fmt.Errorf("failed to load digest data: %w", err)
+	}
+
+	return store, nil
+}
+
+// Add accumulates relevant analyses and the number of videos analyzed in a run
+func (ds *DigestStore) Add(analyses []*models.Analysis, analyzed int) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	ds.pending.Analyses = append(ds.pending.Analyses, analyses...)
+	ds.pending.Analyzed += analyzed
+	return ds.save()
+}
+
+// Pending returns the accumulated analyses and the total analyzed count
+func (ds *DigestStore) Pending() ([]*models.Analysis, int) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	analyses := make([]*models.Analysis, len(ds.pending.Analyses))
+	copy(analyses, ds.pending.Analyses)
+	return analyses, ds.pending.Analyzed
+}
+
+// AddMentions accumulates honorable mentions to include alongside the next email
+func (ds *DigestStore) AddMentions(mentions []*models.Analysis) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	ds.pending.Mentions = append(ds.pending.Mentions, mentions...)
+	return ds.save()
+}
+
+// Mentions returns the accumulated honorable mentions
+func (ds *DigestStore) Mentions() []*models.Analysis {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	mentions := make([]*models.Analysis, len(ds.pending.Mentions))
+	copy(mentions, ds.pending.Mentions)
+	return mentions
+}
+
+// LastSent returns when MarkSent was last called, or the zero time if never
+func (ds *DigestStore) LastSent() time.Time {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.pending.LastSent
+}
+
+// Clear empties the accumulator after a report has been delivered
+func (ds *DigestStore) Clear() error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	ds.pending = pendingDigest{LastSent: ds.pending.LastSent}
+	return ds.save()
+}
+
+// MarkSent empties the accumulator and records when the digest went out
+func (ds *DigestStore) MarkSent(at time.Time) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	ds.pending = pendingDigest{LastSent: at}
+	return ds.save()
+}
+
+// load reads the pending digest from the JSON file
+func (ds *DigestStore) load() error {
+	return readJSONFile(ds.filePath, &ds.pending)
+}
+
+// save writes the pending digest to the JSON file
+func (ds *DigestStore) save() error {
+	return WriteJSONFile(ds.filePath, ds.pending)
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"agent-stack/internal/models"
+)
+
+func TestDigestStoreAccumulatesAcrossRuns(t *testing.T) {
+	dataDir := t.TempDir()
+
+	// Simulate two runs, each with its own store instance like separate processes
+	for i, id := range []string{"first", "second"} {
+		store, err := NewDigestStore(dataDir)
+		if err != nil {
+			t.Fatalf("Run %d: failed to create digest store: %v", i+1, err)
+		}
+		analysis := &models.Analysis{Video: &models.Video{ID: id}, IsRelevant: true, Score: 7}
+		if err := store.Add([]*models.Analysis{analysis}, 5); err != nil {
+			t.Fatalf("Run %d: Add failed: %v", i+1, err)
+		}
+	}
+
+	store, err := NewDigestStore(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to reopen digest store: %v", err)
+	}
+	pending, analyzed := store.Pending()
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 pending analyses, got %d", len(pending))
+	}
+	if pending[0].Video.ID != "first" || pending[1].Video.ID != "second" {
+		t.Errorf("Unexpected pending order: %s, %s", pending[0].Video.ID, pending[1].Video.ID)
+	}
+	if analyzed != 10 {
+		t.Errorf("Expected 10 analyzed videos, got %d", analyzed)
+	}
+
+	if err := store.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	reopened, err := NewDigestStore(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to reopen digest store: %v", err)
+	}
+	if pending, analyzed := reopened.Pending(); len(pending) != 0 || analyzed != 0 {
+		t.Errorf("Expected empty digest after clear, got %d analyses and %d analyzed", len(pending), analyzed)
+	}
+}
+
+func TestDigestStoreRemembersLastSent(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := NewDigestStore(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to create digest store: %v", err)
+	}
+	if !store.LastSent().IsZero() {
+		t.Fatal("New store should have no digest sent")
+	}
+
+	sentAt := time.Date(2025, 3, 9, 9, 0, 0, 0, time.UTC)
+	analysis := &models.Analysis{Video: &models.Video{ID: "first"}, IsRelevant: true, Score: 7}
+	if err := store.Add([]*models.Analysis{analysis}, 1); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := store.MarkSent(sentAt); err != nil {
+		t.Fatalf("MarkSent failed: %v", err)
+	}
+	// Clearing after a non-digest email keeps the last digest time
+	if err := store.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+
+	reopened, err := NewDigestStore(dataDir)
+	if err != nil {
+		t.Fatalf("Failed to reopen digest store: %v", err)
+	}
+	if pending, _ := reopened.Pending(); len(pending) != 0 {
+		t.Errorf("Expected MarkSent to clear pending analyses, got %d", len(pending))
+	}
+	if !reopened.LastSent().Equal(sentAt) {
+		t.Errorf("Expected last sent %v after restart, got %v", sentAt, reopened.LastSent())
+	}
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	var missing map[string]int
+	if err := readJSONFile(path, &missing); err != nil || missing != nil {
+		t.Fatalf("Expected a missing file to leave the value untouched, got %v (err: %v)", missing, err)
+	}
+
+	if err := WriteJSONFile(path, map[string]int{"a": 1}); err != nil {
+		t.Fatalf("WriteJSONFile failed: %v", err)
+	}
+	var got map[string]int
+	if err := readJSONFile(path, &got); err != nil || got["a"] != 1 {
+		t.Errorf("Expected to read back the written value, got %v (err: %v)", got, err)
+	}
+}
+
+func TestWriteJSONFileKeepsPreviousContentsOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	if err := WriteJSONFile(path, map[string]int{"a": 1}); err != nil {
+		t.Fatalf("WriteJSONFile failed: %v", err)
+	}
+
+	// Channels can't be encoded, so this write fails after nothing touched the file
+	if err := WriteJSONFile(path, map[string]interface{}{"bad": make(chan int)}); err == nil {
+		t.Fatal("Expected encoding a channel to fail")
+	}
+
+	var got map[string]int
+	if err := readJSONFile(path, &got); err != nil || got["a"] != 1 {
+		t.Errorf("Expected the previous contents to survive a failed write, got %v (err: %v)", got, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to list dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected no leftover temp files, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// NotifyStore persists when a notification was last sent, and how many checks in a
+// row found conditions unsuitable, so agents can apply a cooldown that survives restarts
+type NotifyStore struct {
+	filePath       string
+	lastNotified   time.Time
+	unsuitableRuns int
+	mu             sync.RWMutex
+}
+
+// notifyState is the on-disk representation of a NotifyStore
+type notifyState struct {
+	LastNotified   time.Time `json:"last_notified"`
+	UnsuitableRuns int       `json:"unsuitable_runs,omitempty"`
+}
+
+// NewNotifyStore creates a notification store backed by fileName in dataDir
+func NewNotifyStore(dataDir, fileName string) (*NotifyStore, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	store := &NotifyStore{filePath: filepath.Join(dataDir, fileName)}
+	if err := store.load(); err != nil {
+		return nil, fmt.Errorf("failed to load notification state: %w", err)
+	}
+	return store, nil
+}
+
+// LastNotified returns when a notification was last recorded, or the zero time if never
+func (ns *NotifyStore) LastNotified() time.Time {
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+	return ns.lastNotified
+}
+
+// RecordNotified records that a notification was sent at the given time
+func (ns *NotifyStore) RecordNotified(at time.Time) error {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.lastNotified = at
+	return ns.save()
+}
+
+// Reset forgets the last notification so the next one is sent immediately
+func (ns *NotifyStore) Reset() error {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if ns.lastNotified.IsZero() {
+		return nil
+	}
+	ns.lastNotified = time.Time{}
+	return ns.save()
+}
+
+// MarkUnsuitable records a check that found conditions unsuitable and returns how
+// many checks in a row have now done so
+func (ns *NotifyStore) MarkUnsuitable() (int, error) {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.unsuitableRuns++
+	return ns.unsuitableRuns, ns.save()
+}
+
+// ClearUnsuitable ends the current run of unsuitable checks
+func (ns *NotifyStore) ClearUnsuitable() error {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	if ns.unsuitableRuns == 0 {
+		return nil
+	}
+	ns.unsuitableRuns = 0
+	return ns.save()
+}
+
+// load reads the notification state from the JSON file
+func (ns *NotifyStore) load() error {
+	var state notifyState
+	if err := readJSONFile(ns.filePath, &state); err != nil {
+		return err
+	}
+	ns.lastNotified = state.LastNotified
+	ns.unsuitableRuns = state.UnsuitableRuns
+	return nil
+}
+
+// save writes the notification state to the JSON file
+func (ns *NotifyStore) save() error {
+	return WriteJSONFile(ns.filePath, notifyState{LastNotified: ns.lastNotified, UnsuitableRuns: ns.unsuitableRuns})
+}
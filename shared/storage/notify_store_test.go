@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotifyStorePersistsAndResets(t *testing.T) {
+	dataDir := t.TempDir()
+
+	store, err := NewNotifyStore(dataDir, "notify.json")
+	if err != nil {
+		t.Fatalf("Failed to create notify store: %v", err)
+	}
+	if !store.LastNotified().IsZero() {
+		t.Fatal("New store should have no notification recorded")
+	}
+
+	sentAt := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	if err := store.RecordNotified(sentAt); err != nil {
+		t.Fatalf("RecordNotified failed: %v", err)
+	}
+
+	reloaded, err := NewNotifyStore(dataDir, "notify.json")
+	if err != nil {
+		t.Fatalf("Failed to reload notify store: %v", err)
+	}
+	if !reloaded.LastNotified().Equal(sentAt) {
+		t.Errorf("Expected last notified %v after restart, got %v", sentAt, reloaded.LastNotified())
+	}
+
+	if err := reloaded.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	afterReset, err := NewNotifyStore(dataDir, "notify.json")
+	if err != nil {
+		t.Fatalf("Failed to reload notify store: %v", err)
+	}
+	if !afterReset.LastNotified().IsZero() {
+		t.Errorf("Expected reset to persist, got %v", afterReset.LastNotified())
+	}
+}
+
+func TestNotifyStoreCountsUnsuitableRuns(t *testing.T) {
+	dataDir := t.TempDir()
+	store, err := NewNotifyStore(dataDir, "notify.json")
+	if err != nil {
+		t.Fatalf("Failed to create notify store: %v", err)
+	}
+
+	if runs, err := store.MarkUnsuitable(); err != nil || runs != 1 {
+		t.Fatalf("Expected first unsuitable check to count 1, got %d (err: %v)", runs, err)
+	}
+	reloaded, err := NewNotifyStore(dataDir, "notify.json")
+	if err != nil {
+		t.Fatalf("Failed to reload notify store: %v", err)
+	}
+	runs, err := reloaded.MarkUnsuitable()
+	if err != nil {
+		t.Fatalf("MarkUnsuitable failed: %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("Expected the count to survive a restart and reach 2, got %d", runs)
+	}
+
+	if err := reloaded.ClearUnsuitable(); err != nil {
+		t.Fatalf("ClearUnsuitable failed: %v", err)
+	}
+	if runs, _ := reloaded.MarkUnsuitable(); runs != 1 {
+		t.Errorf("Expected the count to restart at 1 after clearing, got %d", runs)
+	}
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ReportedStore persists the IDs of videos that have been emailed, separately from
+// the analyzed set, so a video is never included in two emails even if the process
+// restarts between analysis and delivery or two instances analyze the same video
+type ReportedStore struct {
+	filePath    string
+	reportedIDs map[string]time.Time
+	mu          sync.RWMutex
+	maxAge      time.Duration
+}
+
+// ReportedVideo represents a video that has been included in a delivered email
+type ReportedVideo struct {
+	VideoID    string    `json:"video_id"`
+	ReportedAt time.Time `json:"reported_at"`
+}
+
+// NewReportedStore creates a reported-video store backed by a JSON file in dataDir.
+// Entries older than maxAge are forgotten.
+func NewReportedStore(dataDir string, maxAge time.Duration) (*ReportedStore, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	store := &ReportedStore{
+		filePath:    filepath.Join(dataDir, "reported_videos.json"),
+		reportedIDs: make(map[string]time.Time),
+		maxAge:      maxAge,
+	}
+
+	if err := store.load(); err != nil {
+		return nil, fmt.Errorf("failed to load reported video data: %w", err)
+	}
+
+	store.cleanup()
+
+	return store, nil
+}
+
+// IsReported checks if a video ID has already been emailed
+func (rs *ReportedStore) IsReported(videoID string) bool {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	reportedAt, exists := rs.reportedIDs[videoID]
+	return exists && time.Since(reportedAt) < rs.maxAge
+}
+
+// MarkReported records video IDs as emailed. Call only after a successful send.
+func (rs *ReportedStore) MarkReported(videoIDs []string) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := time.Now()
+	for _, videoID := range videoIDs {
+		rs.reportedIDs[videoID] = now
+	}
+	return rs.save()
+}
+
+// cleanup removes entries older than maxAge
+func (rs *ReportedStore) cleanup() {
+	cutoff := time.Now().Add(-rs.maxAge)
+
+	for videoID, reportedAt := range rs.reportedIDs {
+		if reportedAt.Before(cutoff) {
+			delete(rs.reportedIDs, videoID)
+		}
+	}
+}
+
+// load reads the reported videos from the JSON file
+func (rs *ReportedStore) load() error {
+	var reportedVideos []ReportedVideo
+	if err := readJSONFile(rs.filePath, &reportedVideos); err != nil {
+		return err
+	}
+	for _, rv := range reportedVideos {
+		rs.reportedIDs[rv.VideoID] = rv.ReportedAt
+	}
+	return nil
+}
+
+// save writes the reported videos to the JSON file
+func (rs *ReportedStore) save() error {
+	var reportedVideos []ReportedVideo
+	for videoID, reportedAt := range rs.reportedIDs {
+		reportedVideos = append(reportedVideos, ReportedVideo{VideoID: videoID, ReportedAt: reportedAt})
+	}
+	return WriteJSONFile(rs.filePath, reportedVideos)
+}
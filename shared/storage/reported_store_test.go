@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReportedStorePersistsAcrossRestarts(t *testing.T) {
+	dataDir := t.TempDir()
+
+	store, err := NewReportedStore(dataDir, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create reported store: %v", err)
+	}
+	if store.IsReported("abc123") {
+		t.Fatal("New store should not report any videos")
+	}
+	if err := store.MarkReported([]string{"abc123"}); err != nil {
+		t.Fatalf("MarkReported failed: %v", err)
+	}
+
+	reloaded, err := NewReportedStore(dataDir, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to reload reported store: %v", err)
+	}
+	if !reloaded.IsReported("abc123") {
+		t.Error("Expected reported video to survive a restart")
+	}
+	if reloaded.IsReported("other") {
+		t.Error("Unrelated video should not be reported")
+	}
+}
+
+func TestReportedStoreForgetsExpiredEntries(t *testing.T) {
+	store, err := NewReportedStore(t.TempDir(), time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to create reported store: %v", err)
+	}
+	if err := store.MarkReported([]string{"abc123"}); err != nil {
+		t.Fatalf("MarkReported failed: %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if store.IsReported("abc123") {
+		t.Error("Expected entry older than maxAge to be forgotten")
+	}
+}
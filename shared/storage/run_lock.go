@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RunLock is a lease file in the data dir that lets only one process perform a run
+// at a time, for example when two replicas share a volume. A lease older than
+// staleAfter is assumed to belong to a crashed process and is taken over.
+type RunLock struct {
+	filePath   string
+	staleAfter time.Duration
+	holder     string
+	nonce      string // identifies the lease written by the last TryAcquire
+}
+
+// runLease is the on-disk representation of a held RunLock
+type runLease struct {
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	// Nonce is unique per acquisition, so a takeover can tell its own lease apart
+	// from one written by another process with the same holder name
+	Nonce string `json:"nonce,omitempty"`
+}
+
+// takeoverSettle is how long a stale-lease takeover waits before confirming it
+// still holds the lease, letting concurrent takeovers of the same stale lease land
+var takeoverSettle = 100 * time.Millisecond
+
+// NewRunLock creates a run lock backed by fileName in dataDir, identifying this
+// process by hostname and PID
+func NewRunLock(dataDir, fileName string, staleAfter time.Duration) (*RunLock, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return &RunLock{
+		filePath:   filepath.Join(dataDir, fileName),
+		staleAfter: staleAfter,
+		holder:     fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+	}, nil
+}
+
+// TryAcquire takes the lock if it is free or stale. When another process holds a
+// fresh lease it returns false and that process's identity without waiting.
+func (l *RunLock) TryAcquire() (bool, string, error) {
+	nonceBytes := make([]byte, 8)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return false, "", fmt.Errorf("failed to generate run lock nonce: %w", err)
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+	data, err := json.Marshal(runLease{Holder: l.holder, AcquiredAt: time.Now(), Nonce: nonce})
+	if err != nil {
+		return false, "", fmt.Errorf("failed to encode run lock: %w", err)
+	}
+
+	// O_EXCL makes creation atomic, so two processes can't both take a free lock;
+	// a second attempt follows the lock being released between our checks
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(l.filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, writeErr := file.Write(data)
+			if closeErr := file.Close(); writeErr == nil {
+				writeErr = closeErr
+			}
+			if writeErr != nil {
+				os.Remove(l.filePath)
+				return false, "", fmt.Errorf("failed to write run lock: %w", writeErr)
+			}
+			l.nonce = nonce
+			return true, "", nil
+		}
+		if !os.IsExist(err) {
+			return false, "", fmt.Errorf("failed to create run lock: %w", err)
+		}
+
+		lease, acquiredAt, err := l.read()
+		if os.IsNotExist(err) {
+			continue // released since our attempt
+		}
+		if err != nil {
+			return false, "", err
+		}
+		if time.Since(acquiredAt) < l.staleAfter {
+			return false, lease.Holder, nil
+		}
+		log.Printf("Warning: Taking over stale run lock held by %s since %s", lease.Holder, acquiredAt.Format(time.RFC3339))
+		return l.takeOver(data, nonce)
+	}
+	return false, "", fmt.Errorf("run lock %s was taken by another process", l.filePath)
+}
+
+// takeOver replaces a stale lease with ours. The lease is renamed into place so it
+// is never partially written or absent, then re-read after takeoverSettle: when
+// several processes take over the same stale lease only the last rename survives,
+// and every other process sees a foreign nonce and backs off.
+func (l *RunLock) takeOver(data []byte, nonce string) (bool, string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(l.filePath), filepath.Base(l.filePath)+".*.tmp")
+	if err != nil {
+		return false, "", fmt.Errorf("failed to create run lock: %w", err)
+	}
+	_, writeErr := tmp.Write(data)
+	if closeErr := tmp.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr == nil {
+		writeErr = os.Rename(tmp.Name(), l.filePath)
+	}
+	if writeErr != nil {
+		os.Remove(tmp.Name())
+		return false, "", fmt.Errorf("failed to write run lock: %w", writeErr)
+	}
+
+	time.Sleep(takeoverSettle)
+	lease, _, err := l.read()
+	if os.IsNotExist(err) {
+		return false, "", fmt.Errorf("run lock %s was released during takeover", l.filePath)
+	}
+	if err != nil {
+		return false, "", err
+	}
+	if lease.Nonce != nonce {
+		return false, lease.Holder, nil
+	}
+	l.nonce = nonce
+	return true, "", nil
+}
+
+// Release removes the lease if this process still holds it
+func (l *RunLock) Release() error {
+	lease, _, err := l.read()
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if lease.Holder != l.holder || (lease.Nonce != "" && lease.Nonce != l.nonce) {
+		return fmt.Errorf("run lock is held by %s, not this process", lease.Holder)
+	}
+	if err := os.Remove(l.filePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to release run lock: %w", err)
+	}
+	return nil
+}
+
+// read returns the current lease and when it was acquired. A lease that can't be
+// decoded (e.g. mid-write) is dated by the file's modification time.
+func (l *RunLock) read() (runLease, time.Time, error) {
+	var lease runLease
+	data, err := os.ReadFile(l.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lease, time.Time{}, err
+		}
+		return lease, time.Time{}, fmt.Errorf("failed to read run lock: %w", err)
+	}
+	if err := json.Unmarshal(data, &lease); err == nil && !lease.AcquiredAt.IsZero() {
+		return lease, lease.AcquiredAt, nil
+	}
+
+	info, err := os.Stat(l.filePath)
+	if err != nil {
+		return lease, time.Time{}, fmt.Errorf("failed to stat run lock: %w", err)
+	}
+	lease.Holder = "unknown"
+	return lease, info.ModTime(), nil
+}
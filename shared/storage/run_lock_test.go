@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunLockExcludesSecondHolder(t *testing.T) {
+	dataDir := t.TempDir()
+	first, err := NewRunLock(dataDir, "run.lock", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create run lock: %v", err)
+	}
+	second, err := NewRunLock(dataDir, "run.lock", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create run lock: %v", err)
+	}
+	second.holder = "other-host:42"
+
+	if acquired, _, err := first.TryAcquire(); err != nil || !acquired {
+		t.Fatalf("Expected first lock to be acquired, got %v (err: %v)", acquired, err)
+	}
+	acquired, holder, err := second.TryAcquire()
+	if err != nil {
+		t.Fatalf("TryAcquire failed: %v", err)
+	}
+	if acquired || holder != first.holder {
+		t.Errorf("Expected lock held by %s, got acquired=%v holder=%q", first.holder, acquired, holder)
+	}
+
+	if err := second.Release(); err == nil {
+		t.Error("Expected releasing another process's lock to fail")
+	}
+	if err := first.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if acquired, _, err := second.TryAcquire(); err != nil || !acquired {
+		t.Errorf("Expected lock to be free after release, got %v (err: %v)", acquired, err)
+	}
+}
+
+func TestRunLockTakesOverStaleLease(t *testing.T) {
+	dataDir := t.TempDir()
+	crashed, err := NewRunLock(dataDir, "run.lock", time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create run lock: %v", err)
+	}
+	crashed.holder = "crashed-host:1"
+	if acquired, _, err := crashed.TryAcquire(); err != nil || !acquired {
+		t.Fatalf("Expected lock to be acquired, got %v (err: %v)", acquired, err)
+	}
+
+	// A lease that is never released expires after staleAfter
+	fresh, err := NewRunLock(dataDir, "run.lock", 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to create run lock: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if acquired, _, err := fresh.TryAcquire(); err != nil || !acquired {
+		t.Errorf("Expected stale lease to be taken over, got %v (err: %v)", acquired, err)
+	}
+
+	// Unreadable leases fall back to the file's age
+	path := filepath.Join(dataDir, "run.lock")
+	if err := os.WriteFile(path, []byte("garbage"), 0644); err != nil {
+		t.Fatalf("Failed to write lease: %v", err)
+	}
+	if acquired, _, err := fresh.TryAcquire(); err != nil || acquired {
+		t.Errorf("Expected a freshly written lease to be respected, got %v (err: %v)", acquired, err)
+	}
+}
+
+func TestRunLockConcurrentTakeoverHasOneWinner(t *testing.T) {
+	dataDir := t.TempDir()
+	stale := fmt.Sprintf(`{"holder": "crashed-host:1", "acquired_at": %q}`, time.Now().Add(-2*time.Hour).Format(time.RFC3339))
+	if err := os.WriteFile(filepath.Join(dataDir, "run.lock"), []byte(stale), 0644); err != nil {
+		t.Fatalf("Failed to write lease: %v", err)
+	}
+
+	// Every replica sees the same stale lease and races to take it over
+	const replicas = 5
+	var wg sync.WaitGroup
+	results := make([]bool, replicas)
+	for i := 0; i < replicas; i++ {
+		lock, err := NewRunLock(dataDir, "run.lock", time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to create run lock: %v", err)
+		}
+		lock.holder = fmt.Sprintf("replica:%d", i)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			acquired, _, err := lock.TryAcquire()
+			if err != nil {
+				t.Errorf("TryAcquire failed: %v", err)
+			}
+			results[i] = acquired
+		}(i)
+	}
+	wg.Wait()
+
+	var winners int
+	for _, acquired := range results {
+		if acquired {
+			winners++
+		}
+	}
+	if winners != 1 {
+		t.Errorf("Expected exactly one replica to take over the stale lease, got %d", winners)
+	}
+}
@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"agent-stack/internal/models"
+
+	_ "modernc.org/sqlite" // pure Go driver, no cgo required
+)
+
+// sqliteTrackerSchema stores analysis times as Unix nanoseconds, indexed for
+// content-hash lookups and TTL cleanup
+const sqliteTrackerSchema = `
+CREATE TABLE IF NOT EXISTS analyzed_videos (
+	video_id       TEXT PRIMARY KEY,
+	content_hash   TEXT NOT NULL DEFAULT '',
+	analyzed_at    INTEGER NOT NULL,
+	schema_version TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS analyzed_videos_content_hash ON analyzed_videos (content_hash);
+CREATE INDEX IF NOT EXISTS analyzed_videos_analyzed_at ON analyzed_videos (analyzed_at);
+`
+
+// SQLiteTracker is a Tracker backed by a SQLite database, updating single rows
+// instead of rewriting the whole store on every mark
+type SQLiteTracker struct {
+	db            *sql.DB
+	maxAge        time.Duration
+	schemaVersion string
+}
+
+// NewSQLiteTracker opens (creating if needed) analyzed_videos.db in dataDir and
+// deletes entries older than maxAge
+func NewSQLiteTracker(dataDir string, maxAge time.Duration) (*SQLiteTracker, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	dsn := filepath.Join(dataDir, "analyzed_videos.db") + "?_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tracker database: %w", err)
+	}
+	if _, err := db.Exec(sqliteTrackerSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create tracker schema: %w", err)
+	}
+	if err := migrateSchemaVersionColumn(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	tracker := &SQLiteTracker{db: db, maxAge: maxAge}
+	if err := tracker.cleanup(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return tracker, nil
+}
+
+// migrateSchemaVersionColumn adds schema_version to databases created before it existed
+func migrateSchemaVersionColumn(db *sql.DB) error {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('analyzed_videos') WHERE name = 'schema_version'`).Scan(&count); err != nil {
+		return fmt.Errorf("failed to inspect tracker schema: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	if _, err := db.Exec(`ALTER TABLE analyzed_videos ADD COLUMN schema_version TEXT NOT NULL DEFAULT ''`); err != nil {
+		return fmt.Errorf("failed to add schema_version to tracker: %w", err)
+	}
+	return nil
+}
+
+// cutoff returns the oldest analysis time still considered recent
+func (st *SQLiteTracker) cutoff() int64 {
+	return time.Now().Add(-st.maxAge).UnixNano()
+}
+
+// IsAnalyzed checks if a video ID has been analyzed recently
+func (st *SQLiteTracker) IsAnalyzed(videoID string) bool {
+	return st.exists(`SELECT 1 FROM analyzed_videos WHERE video_id = ? AND analyzed_at >= ?`+currentVersionClause, videoID, st.cutoff(), st.schemaVersion, st.schemaVersion)
+}
+
+// currentVersionClause restricts lookups to entries matching the schema version,
+// mirroring versionMatches; it takes the current version as two trailing arguments
+const currentVersionClause = ` AND (? = '' OR schema_version = '' OR schema_version = ?)`
+
+// IsVideoAnalyzed checks if a video has been analyzed recently, matching either
+// its ID or its content hash
+func (st *SQLiteTracker) IsVideoAnalyzed(video *models.Video) bool {
+	return st.IsAnalyzed(video.ID) ||
+		st.exists(`SELECT 1 FROM analyzed_videos WHERE content_hash = ? AND analyzed_at >= ?`+currentVersionClause, ContentHash(video), st.cutoff(), st.schemaVersion, st.schemaVersion)
+}
+
+// SetSchemaVersion sets the analysis schema version entries must match
+func (st *SQLiteTracker) SetSchemaVersion(version string) {
+	st.schemaVersion = version
+}
+
+// exists runs a lookup query. Errors are logged and treated as not found, so a
+// failing database leads to re-analysis rather than skipped videos.
+func (st *SQLiteTracker) exists(query string, args ...interface{}) bool {
+	var found int
+	err := st.db.QueryRow(query, args...).Scan(&found)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Warning: Tracker lookup failed: %v", err)
+	}
+	return err == nil
+}
+
+// MarkAnalyzed marks a video ID as analyzed
+func (st *SQLiteTracker) MarkAnalyzed(videoID string) error {
+	return st.MarkMultipleAnalyzed([]string{videoID})
+}
+
+// MarkMultipleAnalyzed marks multiple video IDs as analyzed in one transaction
+func (st *SQLiteTracker) MarkMultipleAnalyzed(videoIDs []string) error {
+	tracked := make([]TrackedVideo, 0, len(videoIDs))
+	now := time.Now()
+	for _, videoID := range videoIDs {
+		tracked = append(tracked, TrackedVideo{VideoID: videoID, AnalyzedAt: now, SchemaVersion: st.schemaVersion})
+	}
+	return st.record(tracked, false)
+}
+
+// MarkVideosAnalyzed marks multiple videos as analyzed in one transaction,
+// recording both their IDs and content hashes
+func (st *SQLiteTracker) MarkVideosAnalyzed(videos []*models.Video) error {
+	tracked := make([]TrackedVideo, 0, len(videos))
+	now := time.Now()
+	for _, video := range videos {
+		tracked = append(tracked, TrackedVideo{VideoID: video.ID, AnalyzedAt: now, ContentHash: ContentHash(video), SchemaVersion: st.schemaVersion})
+	}
+	return st.record(tracked, false)
+}
+
+// record upserts tracked entries in a single transaction. Entries without a content
+// hash keep the stored one. With keepNewer, rows analyzed more recently than the
+// incoming entry are left untouched.
+func (st *SQLiteTracker) record(tracked []TrackedVideo, keepNewer bool) error {
+	query := `INSERT INTO analyzed_videos (video_id, content_hash, analyzed_at, schema_version) VALUES (?, ?, ?, ?)
+		ON CONFLICT (video_id) DO UPDATE SET
+			analyzed_at = excluded.analyzed_at,
+			schema_version = excluded.schema_version,
+			content_hash = CASE WHEN excluded.content_hash != '' THEN excluded.content_hash ELSE analyzed_videos.content_hash END`
+	if keepNewer {
+		query += ` WHERE excluded.analyzed_at >= analyzed_videos.analyzed_at`
+	}
+
+	tx, err := st.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tracker transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare tracker update: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, tv := range tracked {
+		if _, err := stmt.Exec(tv.VideoID, tv.ContentHash, tv.AnalyzedAt.UnixNano(), tv.SchemaVersion); err != nil {
+			return fmt.Errorf("failed to record video %s: %w", tv.VideoID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tracker update: %w", err)
+	}
+	return nil
+}
+
+// Unmark forgets a video ID, and the content hash stored with it, so the video
+// is analyzed again on the next run
+func (st *SQLiteTracker) Unmark(videoID string) error {
+	if _, err := st.db.Exec(`DELETE FROM analyzed_videos WHERE video_id = ?`, videoID); err != nil {
+		return fmt.Errorf("failed to unmark video: %w", err)
+	}
+	return nil
+}
+
+// Export writes all tracked videos to w in the same JSON format as VideoTracker
+func (st *SQLiteTracker) Export(w io.Writer) error {
+	rows, err := st.db.Query(`SELECT video_id, content_hash, analyzed_at, schema_version FROM analyzed_videos`)
+	if err != nil {
+		return fmt.Errorf("failed to query tracked videos: %w", err)
+	}
+	defer rows.Close()
+
+	var trackedVideos []TrackedVideo
+	for rows.Next() {
+		var tv TrackedVideo
+		var analyzedAt int64
+		if err := rows.Scan(&tv.VideoID, &tv.ContentHash, &analyzedAt, &tv.SchemaVersion); err != nil {
+			return fmt.Errorf("failed to read tracked video: %w", err)
+		}
+		tv.AnalyzedAt = time.Unix(0, analyzedAt)
+		trackedVideos = append(trackedVideos, tv)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read tracked videos: %w", err)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(trackedVideos)
+}
+
+// Import merges tracked videos previously written by Export. Entries already
+// tracked keep the most recent analysis time.
+func (st *SQLiteTracker) Import(r io.Reader) error {
+	var trackedVideos []TrackedVideo
+	if err := json.NewDecoder(r).Decode(&trackedVideos); err != nil {
+		return fmt.Errorf("failed to decode imported tracker data: %w", err)
+	}
+	if err := st.record(trackedVideos, true); err != nil {
+		return err
+	}
+	return st.cleanup()
+}
+
+// GetAnalyzedCount returns the number of tracked videos
+func (st *SQLiteTracker) GetAnalyzedCount() int {
+	var count int
+	if err := st.db.QueryRow(`SELECT COUNT(*) FROM analyzed_videos`).Scan(&count); err != nil {
+		log.Printf("Warning: Failed to count tracked videos: %v", err)
+	}
+	return count
+}
+
+// Cleanup deletes entries older than maxAge
+func (st *SQLiteTracker) Cleanup() error {
+	return st.cleanup()
+}
+
+// Close closes the underlying database
+func (st *SQLiteTracker) Close() error {
+	return st.db.Close()
+}
+
+// cleanup deletes entries older than maxAge
+func (st *SQLiteTracker) cleanup() error {
+	if _, err := st.db.Exec(`DELETE FROM analyzed_videos WHERE analyzed_at < ?`, st.cutoff()); err != nil {
+		return fmt.Errorf("failed to clean up tracked videos: %w", err)
+	}
+	return nil
+}
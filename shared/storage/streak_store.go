@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StreakStore persists a count of consecutive runs with the same outcome, such as
+// no-fly checks in a row, so the streak survives restarts
+type StreakStore struct {
+	filePath string
+	count    int
+	since    time.Time
+	mu       sync.RWMutex
+}
+
+// streakState is the on-disk representation of a StreakStore
+type streakState struct {
+	Count int       `json:"count"`
+	Since time.Time `json:"since"`
+}
+
+// NewStreakStore creates a streak store backed by fileName in dataDir
+func NewStreakStore(dataDir, fileName string) (*StreakStore, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	store := &StreakStore{filePath: filepath.Join(dataDir, fileName)}
+	if err := store.load(); err != nil {
+		return nil, fmt.Errorf("failed to load streak state: %w", err)
+	}
+	return store, nil
+}
+
+// Count returns the current streak length
+func (ss *StreakStore) Count() int {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return ss.count
+}
+
+// Since returns when the current streak started, or the zero time if there is none
+func (ss *StreakStore) Since() time.Time {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return ss.since
+}
+
+// Increment extends the streak by one run at the given time and returns its new length
+func (ss *StreakStore) Increment(at time.Time) (int, error) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.count == 0 {
+		ss.since = at
+	}
+	ss.count++
+	return ss.count, ss.save()
+}
+
+// Reset ends the streak
+func (ss *StreakStore) Reset() error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.count == 0 {
+		return nil
+	}
+	ss.count = 0
+	ss.since = time.Time{}
+	return ss.save()
+}
+
+// load reads the streak state from the JSON file
+func (ss *StreakStore) load() error {
+	var state streakState
+	if err := readJSONFile(ss.filePath, &state); err != nil {
+		return err
+	}
+	ss.count = state.Count
+	ss.since = state.Since
+	return nil
+}
+
+// save writes the streak state to the JSON file
+func (ss *StreakStore) save() error {
+	return WriteJSONFile(ss.filePath, streakState{Count: ss.count, Since: ss.since})
+}
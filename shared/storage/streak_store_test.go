@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreakStorePersistsAndResets(t *testing.T) {
+	dataDir := t.TempDir()
+
+	store, err := NewStreakStore(dataDir, "streak.json")
+	if err != nil {
+		t.Fatalf("Failed to create streak store: %v", err)
+	}
+
+	start := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if _, err := store.Increment(start.Add(time.Duration(i) * 24 * time.Hour)); err != nil {
+			t.Fatalf("Increment failed: %v", err)
+		}
+	}
+
+	reloaded, err := NewStreakStore(dataDir, "streak.json")
+	if err != nil {
+		t.Fatalf("Failed to reload streak store: %v", err)
+	}
+	if reloaded.Count() != 3 {
+		t.Errorf("Expected streak of 3 after restart, got %d", reloaded.Count())
+	}
+	if !reloaded.Since().Equal(start) {
+		t.Errorf("Expected streak to start at %v, got %v", start, reloaded.Since())
+	}
+
+	if err := reloaded.Reset(); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	afterReset, err := NewStreakStore(dataDir, "streak.json")
+	if err != nil {
+		t.Fatalf("Failed to reload streak store: %v", err)
+	}
+	if afterReset.Count() != 0 || !afterReset.Since().IsZero() {
+		t.Errorf("Expected reset to persist, got count %d since %v", afterReset.Count(), afterReset.Since())
+	}
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"io"
+
+	"agent-stack/internal/models"
+)
+
+// Tracker remembers which videos were analyzed within a retention window so they
+// are not analyzed again. Entries older than the window are forgotten.
+type Tracker interface {
+	// IsAnalyzed checks if a video ID has been analyzed recently
+	IsAnalyzed(videoID string) bool
+	// IsVideoAnalyzed checks a video by ID or by content hash
+	IsVideoAnalyzed(video *models.Video) bool
+	MarkAnalyzed(videoID string) error
+	MarkMultipleAnalyzed(videoIDs []string) error
+	// MarkVideosAnalyzed records both IDs and content hashes
+	MarkVideosAnalyzed(videos []*models.Video) error
+	// Unmark forgets a video ID and its content hash so it is analyzed again
+	Unmark(videoID string) error
+	// Export and Import move tracked videos between trackers in a portable JSON format
+	Export(w io.Writer) error
+	Import(r io.Reader) error
+	// SetSchemaVersion tags new entries with version and makes entries recorded
+	// under a different version count as not analyzed. Entries recorded before
+	// versions were tracked, or while none was set, match any version.
+	SetSchemaVersion(version string)
+	GetAnalyzedCount() int
+	// Cleanup forgets entries older than the retention window
+	Cleanup() error
+	Close() error
+}
+
+// versionMatches reports whether an entry recorded under stored is still current
+func versionMatches(stored, current string) bool {
+	return stored == "" || current == "" || stored == current
+}
@@ -0,0 +1,246 @@
+package storage
+
+import (
+	"bytes"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"agent-stack/internal/models"
+)
+
+// trackerBackends opens each Tracker implementation so the suite below runs against all of them
+var trackerBackends = map[string]func(dataDir string, maxAge time.Duration) (Tracker, error){
+	"json": func(dataDir string, maxAge time.Duration) (Tracker, error) {
+		return NewVideoTracker(dataDir, maxAge)
+	},
+	"sqlite": func(dataDir string, maxAge time.Duration) (Tracker, error) {
+		return NewSQLiteTracker(dataDir, maxAge)
+	},
+}
+
+func openTracker(t *testing.T, open func(string, time.Duration) (Tracker, error), dataDir string, maxAge time.Duration) Tracker {
+	t.Helper()
+	tracker, err := open(dataDir, maxAge)
+	if err != nil {
+		t.Fatalf("Failed to open tracker: %v", err)
+	}
+	t.Cleanup(func() { tracker.Close() })
+	return tracker
+}
+
+func TestTrackerBackends(t *testing.T) {
+	video := &models.Video{
+		ID:           "abc123",
+		Title:        "Understanding Go Interfaces",
+		ChannelTitle: "Go Channel",
+		PublishedAt:  time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC),
+	}
+
+	for name, open := range trackerBackends {
+		t.Run(name, func(t *testing.T) {
+			t.Run("Marks and looks up videos", func(t *testing.T) {
+				tracker := openTracker(t, open, t.TempDir(), 7*24*time.Hour)
+				if tracker.IsAnalyzed("abc123") || tracker.IsVideoAnalyzed(video) {
+					t.Fatal("New tracker should not know any videos")
+				}
+				if err := tracker.MarkVideosAnalyzed([]*models.Video{video}); err != nil {
+					t.Fatalf("MarkVideosAnalyzed failed: %v", err)
+				}
+				if err := tracker.MarkMultipleAnalyzed([]string{"one", "two"}); err != nil {
+					t.Fatalf("MarkMultipleAnalyzed failed: %v", err)
+				}
+				if err := tracker.MarkAnalyzed("three"); err != nil {
+					t.Fatalf("MarkAnalyzed failed: %v", err)
+				}
+
+				for _, id := range []string{"abc123", "one", "two", "three"} {
+					if !tracker.IsAnalyzed(id) {
+						t.Errorf("Expected %s to be analyzed", id)
+					}
+				}
+				if tracker.IsAnalyzed("other") {
+					t.Error("Unrelated video should not be analyzed")
+				}
+				if count := tracker.GetAnalyzedCount(); count != 4 {
+					t.Errorf("Expected 4 tracked videos, got %d", count)
+				}
+
+				reupload := *video
+				reupload.ID = "xyz789"
+				if !tracker.IsVideoAnalyzed(&reupload) {
+					t.Error("Expected re-uploaded video to be recognized by content hash")
+				}
+				different := &models.Video{ID: "new", Title: "Something Else", ChannelTitle: "Go Channel"}
+				if tracker.IsVideoAnalyzed(different) {
+					t.Error("Video with different content should not be analyzed")
+				}
+			})
+
+			t.Run("Persists across restarts", func(t *testing.T) {
+				dataDir := t.TempDir()
+				tracker := openTracker(t, open, dataDir, 7*24*time.Hour)
+				if err := tracker.MarkVideosAnalyzed([]*models.Video{video}); err != nil {
+					t.Fatalf("MarkVideosAnalyzed failed: %v", err)
+				}
+				tracker.Close()
+
+				reopened := openTracker(t, open, dataDir, 7*24*time.Hour)
+				if !reopened.IsAnalyzed(video.ID) {
+					t.Error("Expected analyzed video to survive a restart")
+				}
+			})
+
+			t.Run("Unmark forgets the ID and content hash", func(t *testing.T) {
+				dataDir := t.TempDir()
+				tracker := openTracker(t, open, dataDir, 7*24*time.Hour)
+				if err := tracker.MarkVideosAnalyzed([]*models.Video{video}); err != nil {
+					t.Fatalf("MarkVideosAnalyzed failed: %v", err)
+				}
+				if err := tracker.MarkAnalyzed("other"); err != nil {
+					t.Fatalf("MarkAnalyzed failed: %v", err)
+				}
+				if err := tracker.Unmark(video.ID); err != nil {
+					t.Fatalf("Unmark failed: %v", err)
+				}
+				if err := tracker.Unmark("never-tracked"); err != nil {
+					t.Errorf("Unmarking an unknown video should be a no-op, got %v", err)
+				}
+
+				if tracker.IsVideoAnalyzed(video) {
+					t.Error("Expected unmarked video to be analyzed again")
+				}
+				if !tracker.IsAnalyzed("other") {
+					t.Error("Unmark should leave other videos tracked")
+				}
+				tracker.Close()
+
+				reopened := openTracker(t, open, dataDir, 7*24*time.Hour)
+				if reopened.IsAnalyzed(video.ID) || reopened.GetAnalyzedCount() != 1 {
+					t.Errorf("Expected unmark to persist, got %d tracked", reopened.GetAnalyzedCount())
+				}
+			})
+
+			t.Run("Forgets expired entries", func(t *testing.T) {
+				dataDir := t.TempDir()
+				tracker := openTracker(t, open, dataDir, 50*time.Millisecond)
+				if err := tracker.MarkAnalyzed("abc123"); err != nil {
+					t.Fatalf("MarkAnalyzed failed: %v", err)
+				}
+				time.Sleep(100 * time.Millisecond)
+				if tracker.IsAnalyzed("abc123") {
+					t.Error("Expected entry older than maxAge to be forgotten")
+				}
+				tracker.Close()
+
+				// Expired entries are dropped from storage when the tracker is reopened
+				reopened := openTracker(t, open, dataDir, 50*time.Millisecond)
+				if count := reopened.GetAnalyzedCount(); count != 0 {
+					t.Errorf("Expected expired entries to be cleaned up, got %d", count)
+				}
+			})
+
+			t.Run("Cleanup drops expired entries without a restart", func(t *testing.T) {
+				dataDir := t.TempDir()
+				tracker := openTracker(t, open, dataDir, 50*time.Millisecond)
+				if err := tracker.MarkAnalyzed("abc123"); err != nil {
+					t.Fatalf("MarkAnalyzed failed: %v", err)
+				}
+				time.Sleep(100 * time.Millisecond)
+				if err := tracker.Cleanup(); err != nil {
+					t.Fatalf("Cleanup failed: %v", err)
+				}
+				if count := tracker.GetAnalyzedCount(); count != 0 {
+					t.Errorf("Expected Cleanup to drop expired entries, got %d", count)
+				}
+			})
+
+			t.Run("Schema version changes invalidate entries", func(t *testing.T) {
+				dataDir := t.TempDir()
+				tracker := openTracker(t, open, dataDir, 7*24*time.Hour)
+				if err := tracker.MarkAnalyzed("legacy"); err != nil {
+					t.Fatalf("MarkAnalyzed failed: %v", err)
+				}
+				tracker.SetSchemaVersion("v1")
+				if err := tracker.MarkVideosAnalyzed([]*models.Video{video}); err != nil {
+					t.Fatalf("MarkVideosAnalyzed failed: %v", err)
+				}
+				tracker.Close()
+
+				reopened := openTracker(t, open, dataDir, 7*24*time.Hour)
+				reopened.SetSchemaVersion("v1")
+				if !reopened.IsVideoAnalyzed(video) {
+					t.Error("Expected video to stay analyzed under the same schema version")
+				}
+
+				reopened.SetSchemaVersion("v2")
+				reupload := *video
+				reupload.ID = "xyz789"
+				if reopened.IsAnalyzed(video.ID) || reopened.IsVideoAnalyzed(&reupload) {
+					t.Error("Expected a schema version change to invalidate the entry by ID and content hash")
+				}
+				if !reopened.IsAnalyzed("legacy") {
+					t.Error("Expected entries recorded without a version to match any version")
+				}
+
+				if err := reopened.MarkVideosAnalyzed([]*models.Video{video}); err != nil {
+					t.Fatalf("MarkVideosAnalyzed failed: %v", err)
+				}
+				if !reopened.IsVideoAnalyzed(video) {
+					t.Error("Expected re-analysis to record the new schema version")
+				}
+			})
+
+			t.Run("Imports exports from every backend", func(t *testing.T) {
+				for sourceName, openSource := range trackerBackends {
+					source := openTracker(t, openSource, t.TempDir(), 7*24*time.Hour)
+					if err := source.MarkVideosAnalyzed([]*models.Video{video}); err != nil {
+						t.Fatalf("MarkVideosAnalyzed failed: %v", err)
+					}
+					var buf bytes.Buffer
+					if err := source.Export(&buf); err != nil {
+						t.Fatalf("Export from %s failed: %v", sourceName, err)
+					}
+
+					tracker := openTracker(t, open, t.TempDir(), 7*24*time.Hour)
+					if err := tracker.Import(&buf); err != nil {
+						t.Fatalf("Import from %s failed: %v", sourceName, err)
+					}
+					reupload := *video
+					reupload.ID = "xyz789"
+					if !tracker.IsAnalyzed(video.ID) || !tracker.IsVideoAnalyzed(&reupload) {
+						t.Errorf("Expected video imported from %s to match by ID and content hash", sourceName)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestSQLiteTrackerMigratesSchemaVersionColumn(t *testing.T) {
+	dataDir := t.TempDir()
+	db, err := sql.Open("sqlite", filepath.Join(dataDir, "analyzed_videos.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = db.Exec(`CREATE TABLE analyzed_videos (video_id TEXT PRIMARY KEY, content_hash TEXT NOT NULL DEFAULT '', analyzed_at INTEGER NOT NULL);
+		INSERT INTO analyzed_videos (video_id, analyzed_at) VALUES ('old123', ?)`, time.Now().UnixNano())
+	db.Close()
+	if err != nil {
+		t.Fatalf("Failed to create pre-versioning database: %v", err)
+	}
+
+	tracker, err := NewSQLiteTracker(dataDir, 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to open pre-versioning database: %v", err)
+	}
+	defer tracker.Close()
+	tracker.SetSchemaVersion("v1")
+	if !tracker.IsAnalyzed("old123") {
+		t.Error("Expected entries from before versioning to stay analyzed")
+	}
+	if err := tracker.MarkAnalyzed("new456"); err != nil {
+		t.Fatalf("MarkAnalyzed failed: %v", err)
+	}
+}
@@ -1,26 +1,46 @@
 package storage
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
+
+	"agent-stack/internal/models"
 )
 
 // VideoTracker manages a persistent store of analyzed video IDs to prevent duplicate analysis
 type VideoTracker struct {
-	filePath    string
-	analyzedIDs map[string]time.Time
-	mu          sync.RWMutex
-	maxAge      time.Duration
+	filePath       string
+	analyzedIDs    map[string]time.Time
+	contentHashes  map[string]string    // videoID -> content hash
+	analyzedHashes map[string]time.Time // content hash -> analyzed at
+	versions       map[string]string    // videoID -> schema version
+	hashVersions   map[string]string    // content hash -> schema version
+	schemaVersion  string
+	mu             sync.RWMutex
+	maxAge         time.Duration
 }
 
 // TrackedVideo represents a video that has been analyzed
 type TrackedVideo struct {
-	VideoID    string    `json:"video_id"`
-	AnalyzedAt time.Time `json:"analyzed_at"`
+	VideoID     string    `json:"video_id"`
+	AnalyzedAt  time.Time `json:"analyzed_at"`
+	ContentHash string    `json:"content_hash,omitempty"`
+	// SchemaVersion is the analysis schema the video was analyzed under, if known
+	SchemaVersion string `json:"schema_version,omitempty"`
+}
+
+// ContentHash returns a stable hash of a video's title, channel and publish time.
+// It lets re-uploads or videos whose ID changed be recognized as already seen.
+func ContentHash(video *models.Video) string {
+	sum := sha256.Sum256([]byte(video.Title + "\x00" + video.ChannelTitle + "\x00" + video.PublishedAt.UTC().Format(time.RFC3339)))
+	return hex.EncodeToString(sum[:])
 }
 
 // NewVideoTracker creates a new video tracker with persistent storage
@@ -32,9 +52,13 @@ func NewVideoTracker(dataDir string, maxAge time.Duration) (*VideoTracker, error
 	filePath := filepath.Join(dataDir, "analyzed_videos.json")
 
 	tracker := &VideoTracker{
-		filePath:    filePath,
-		analyzedIDs: make(map[string]time.Time),
-		maxAge:      maxAge,
+		filePath:       filePath,
+		analyzedIDs:    make(map[string]time.Time),
+		contentHashes:  make(map[string]string),
+		analyzedHashes: make(map[string]time.Time),
+		versions:       make(map[string]string),
+		hashVersions:   make(map[string]string),
+		maxAge:         maxAge,
 	}
 
 	// Load existing data
@@ -54,7 +78,7 @@ func (vt *VideoTracker) IsAnalyzed(videoID string) bool {
 	defer vt.mu.RUnlock()
 
 	analyzedAt, exists := vt.analyzedIDs[videoID]
-	if !exists {
+	if !exists || !versionMatches(vt.versions[videoID], vt.schemaVersion) {
 		return false
 	}
 
@@ -62,12 +86,34 @@ func (vt *VideoTracker) IsAnalyzed(videoID string) bool {
 	return time.Since(analyzedAt) < vt.maxAge
 }
 
+// IsVideoAnalyzed checks if a video has been analyzed recently, matching either
+// its ID or its content hash
+func (vt *VideoTracker) IsVideoAnalyzed(video *models.Video) bool {
+	if vt.IsAnalyzed(video.ID) {
+		return true
+	}
+
+	vt.mu.RLock()
+	defer vt.mu.RUnlock()
+
+	hash := ContentHash(video)
+	analyzedAt, exists := vt.analyzedHashes[hash]
+	return exists && versionMatches(vt.hashVersions[hash], vt.schemaVersion) && time.Since(analyzedAt) < vt.maxAge
+}
+
+// SetSchemaVersion sets the analysis schema version entries must match
+func (vt *VideoTracker) SetSchemaVersion(version string) {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	vt.schemaVersion = version
+}
+
 // MarkAnalyzed marks a video ID as analyzed
 func (vt *VideoTracker) MarkAnalyzed(videoID string) error {
 	vt.mu.Lock()
 	defer vt.mu.Unlock()
 
-	vt.analyzedIDs[videoID] = time.Now()
+	vt.record(videoID, "", vt.schemaVersion, time.Now())
 	return vt.save()
 }
 
@@ -78,11 +124,82 @@ func (vt *VideoTracker) MarkMultipleAnalyzed(videoIDs []string) error {
 
 	now := time.Now()
 	for _, videoID := range videoIDs {
-		vt.analyzedIDs[videoID] = now
+		vt.record(videoID, "", vt.schemaVersion, now)
 	}
 	return vt.save()
 }
 
+// MarkVideosAnalyzed marks multiple videos as analyzed in batch, recording
+// both their IDs and content hashes
+func (vt *VideoTracker) MarkVideosAnalyzed(videos []*models.Video) error {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+
+	now := time.Now()
+	for _, video := range videos {
+		vt.record(video.ID, ContentHash(video), vt.schemaVersion, now)
+	}
+	return vt.save()
+}
+
+// Unmark forgets a video ID, along with the content hash recorded for it, so the
+// video is analyzed again on the next run
+func (vt *VideoTracker) Unmark(videoID string) error {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+
+	if hash, ok := vt.contentHashes[videoID]; ok {
+		delete(vt.analyzedHashes, hash)
+		delete(vt.hashVersions, hash)
+	}
+	delete(vt.analyzedIDs, videoID)
+	delete(vt.contentHashes, videoID)
+	delete(vt.versions, videoID)
+	return vt.save()
+}
+
+// Export writes all tracked videos to w in a portable JSON format
+func (vt *VideoTracker) Export(w io.Writer) error {
+	vt.mu.RLock()
+	defer vt.mu.RUnlock()
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(vt.trackedVideos())
+}
+
+// Import merges tracked videos previously written by Export and persists the result.
+// Entries already tracked keep the most recent analysis time.
+func (vt *VideoTracker) Import(r io.Reader) error {
+	var trackedVideos []TrackedVideo
+	if err := json.NewDecoder(r).Decode(&trackedVideos); err != nil {
+		return fmt.Errorf("failed to decode imported tracker data: %w", err)
+	}
+
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+
+	for _, tv := range trackedVideos {
+		if existing, ok := vt.analyzedIDs[tv.VideoID]; ok && existing.After(tv.AnalyzedAt) {
+			continue
+		}
+		vt.record(tv.VideoID, tv.ContentHash, tv.SchemaVersion, tv.AnalyzedAt)
+	}
+	vt.cleanup()
+	return vt.save()
+}
+
+// record stores a tracked entry; callers must hold the write lock
+func (vt *VideoTracker) record(videoID, contentHash, schemaVersion string, analyzedAt time.Time) {
+	vt.analyzedIDs[videoID] = analyzedAt
+	vt.versions[videoID] = schemaVersion
+	if contentHash != "" {
+		vt.contentHashes[videoID] = contentHash
+		vt.analyzedHashes[contentHash] = analyzedAt
+		vt.hashVersions[contentHash] = schemaVersion
+	}
+}
+
 // GetAnalyzedCount returns the number of tracked videos
 func (vt *VideoTracker) GetAnalyzedCount() int {
 	vt.mu.RLock()
@@ -90,60 +207,71 @@ func (vt *VideoTracker) GetAnalyzedCount() int {
 	return len(vt.analyzedIDs)
 }
 
-// Cleanup removes entries older than maxAge
-func (vt *VideoTracker) cleanup() {
+// Cleanup removes entries older than maxAge and saves the file if any were removed
+func (vt *VideoTracker) Cleanup() error {
+	vt.mu.Lock()
+	defer vt.mu.Unlock()
+	if !vt.cleanup() {
+		return nil
+	}
+	return vt.save()
+}
+
+// cleanup removes entries older than maxAge from memory and reports whether any were
+func (vt *VideoTracker) cleanup() bool {
 	cutoff := time.Now().Add(-vt.maxAge)
+	removed := false
 
 	for videoID, analyzedAt := range vt.analyzedIDs {
 		if analyzedAt.Before(cutoff) {
 			delete(vt.analyzedIDs, videoID)
+			delete(vt.contentHashes, videoID)
+			delete(vt.versions, videoID)
+			removed = true
 		}
 	}
+	for hash, analyzedAt := range vt.analyzedHashes {
+		if analyzedAt.Before(cutoff) {
+			delete(vt.analyzedHashes, hash)
+			delete(vt.hashVersions, hash)
+			removed = true
+		}
+	}
+	return removed
 }
 
 // load reads the tracked videos from the JSON file
 func (vt *VideoTracker) load() error {
-	file, err := os.Open(vt.filePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// File doesn't exist yet, start with empty tracker
-			return nil
-		}
-		return fmt.Errorf("failed to open tracker file: %w", err)
-	}
-	defer file.Close()
-
 	var trackedVideos []TrackedVideo
-	if err := json.NewDecoder(file).Decode(&trackedVideos); err != nil {
-		return fmt.Errorf("failed to decode tracker data: %w", err)
+	if err := readJSONFile(vt.filePath, &trackedVideos); err != nil {
+		return err
 	}
-
-	// Convert to map
 	for _, tv := range trackedVideos {
-		vt.analyzedIDs[tv.VideoID] = tv.AnalyzedAt
+		vt.record(tv.VideoID, tv.ContentHash, tv.SchemaVersion, tv.AnalyzedAt)
 	}
-
 	return nil
 }
 
-// save writes the tracked videos to the JSON file
-func (vt *VideoTracker) save() error {
-	// Convert map to slice for JSON serialization
+// trackedVideos converts the tracker maps to a slice for JSON serialization
+func (vt *VideoTracker) trackedVideos() []TrackedVideo {
 	var trackedVideos []TrackedVideo
 	for videoID, analyzedAt := range vt.analyzedIDs {
 		trackedVideos = append(trackedVideos, TrackedVideo{
-			VideoID:    videoID,
-			AnalyzedAt: analyzedAt,
+			VideoID:       videoID,
+			AnalyzedAt:    analyzedAt,
+			ContentHash:   vt.contentHashes[videoID],
+			SchemaVersion: vt.versions[videoID],
 		})
 	}
+	return trackedVideos
+}
 
-	file, err := os.Create(vt.filePath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer file.Close()
+// Close is a no-op; every change is already written to disk
+func (vt *VideoTracker) Close() error {
+	return nil
+}
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(trackedVideos)
+// save writes the tracked videos to the JSON file
+func (vt *VideoTracker) save() error {
+	return WriteJSONFile(vt.filePath, vt.trackedVideos())
 }
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"agent-stack/internal/models"
+)
+
+func TestExportImportPreservesAnalyzed(t *testing.T) {
+	source, err := NewVideoTracker(t.TempDir(), 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create source tracker: %v", err)
+	}
+
+	video := &models.Video{
+		ID:           "abc123",
+		Title:        "Understanding Go Interfaces",
+		ChannelTitle: "Go Channel",
+		PublishedAt:  time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC),
+	}
+	if err := source.MarkVideosAnalyzed([]*models.Video{video}); err != nil {
+		t.Fatalf("Failed to mark video analyzed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := source.Export(&buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	fresh, err := NewVideoTracker(t.TempDir(), 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create fresh tracker: %v", err)
+	}
+	if fresh.IsAnalyzed(video.ID) {
+		t.Fatal("Fresh tracker should not know about the video before import")
+	}
+
+	if err := fresh.Import(&buf); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if !fresh.IsAnalyzed(video.ID) {
+		t.Error("Expected video to be analyzed after import")
+	}
+
+	// A re-upload with a new ID but identical metadata should match by content hash
+	reupload := *video
+	reupload.ID = "xyz789"
+	if !fresh.IsVideoAnalyzed(&reupload) {
+		t.Error("Expected re-uploaded video to be recognized by content hash")
+	}
+}
+
+func TestIsVideoAnalyzedDifferentContent(t *testing.T) {
+	tracker, err := NewVideoTracker(t.TempDir(), 7*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create tracker: %v", err)
+	}
+
+	video := &models.Video{ID: "one", Title: "First", ChannelTitle: "Channel"}
+	if err := tracker.MarkVideosAnalyzed([]*models.Video{video}); err != nil {
+		t.Fatalf("Failed to mark video analyzed: %v", err)
+	}
+
+	other := &models.Video{ID: "two", Title: "Second", ChannelTitle: "Channel"}
+	if tracker.IsVideoAnalyzed(other) {
+		t.Error("Unrelated video should not be reported as analyzed")
+	}
+}
@@ -0,0 +1,29 @@
+package version
+
+import "fmt"
+
+// Build information, injected at build time with -ldflags, e.g.
+//
+//	go build -ldflags "-X agent-stack/shared/version.Version=v1.2.3 -X agent-stack/shared/version.Commit=$(git rev-parse HEAD)"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	return Info{Version: Version, Commit: Commit, BuildTime: BuildTime}
+}
+
+// String formats the build information for logs
+func (i Info) String() string {
+	return fmt.Sprintf("%s (commit %s, built %s)", i.Version, i.Commit, i.BuildTime)
+}